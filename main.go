@@ -5,35 +5,85 @@
 package main
 
 import (
+	"context"
 	"flag"
+	"fmt"
+	v1 "github.com/EdgeNet-project/fed4fire/pkg/apis/fed4fire/v1"
 	"github.com/EdgeNet-project/fed4fire/pkg/constants"
 	"github.com/EdgeNet-project/fed4fire/pkg/gc"
 	versioned "github.com/EdgeNet-project/fed4fire/pkg/generated/clientset/versioned"
 	"github.com/EdgeNet-project/fed4fire/pkg/identifiers"
+	"github.com/EdgeNet-project/fed4fire/pkg/imagepolicy"
+	"github.com/EdgeNet-project/fed4fire/pkg/leader"
+	"github.com/EdgeNet-project/fed4fire/pkg/security"
 	"github.com/EdgeNet-project/fed4fire/pkg/service"
 	"github.com/EdgeNet-project/fed4fire/pkg/utils"
 	"github.com/gorilla/rpc"
 	"github.com/maxmouchet/gorilla-xmlrpc/xml"
 	"io/ioutil"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/tools/clientcmd"
 	"k8s.io/klog/v2"
+	"net"
 	"net/http"
 	"os"
+	"os/signal"
 	"strings"
+	"syscall"
+	"text/template"
 	"time"
 )
 
 var showHelp bool
 var absoluteUrl string
 var authorityName string
+var clusterDomain string
 var containerImages utils.ArrayFlags
 var containerCpuLimit string
 var containerMemoryLimit string
+var exclusiveNodeLabel string
+var imageAllowlist utils.ArrayFlags
+var ingressClass string
+var ingressDomain string
+var ingressIssuer string
+var ingressTLSSecret string
 var kubeconfigFile string
+var installImage string
+var leaderElection bool
+var leaderElectionName string
+var leaderElectionNamespace string
+var linkCniConfigFile string
+var linkShapingImage string
+var linkSubnet string
 var listenAddr string
+var maxCpuRequest string
+var maxLinkCapacity int64
+var maxLinkLatency int64
+var maxMemoryRequest string
+var maxNodeCount int
+var maxRequestSize int64
+var maxRspecSize int
+var maxVolumeSize string
 var namespace string
+var namespaceCpuLimit string
+var namespaceMemoryLimit string
+var namespacePerSlice bool
+var networkIsolation bool
+var placementLabels utils.ArrayFlags
+var portServiceType string
+var readOnlyRootFilesystem bool
+var requireImageDigest bool
+var sharedStorageClass string
+var sliverTypeProfiles utils.ArrayFlags
+var sshdCommand string
+var strictDiskImages bool
+var storageClass string
 var trustedCerts utils.ArrayFlags
+var userMaxRequests utils.ArrayFlags
 
 func beforeFunc(i *rpc.RequestInfo) {
 	escapedCert := i.Request.Header.Get(constants.HttpHeaderCertificate)
@@ -55,13 +105,49 @@ func main() {
 	flag.BoolVar(&showHelp, "help", false, "show this message")
 	flag.StringVar(&absoluteUrl, "absoluteUrl", "https://localhost:9443", "URL used by external clients to reach this server")
 	flag.StringVar(&authorityName, "authorityName", "example.org", "authority name to use in URNs")
-	flag.Var(&containerImages, "containerImage", "name:image of a container image that can be deployed; can be specified multiple times")
+	flag.StringVar(&clusterDomain, "clusterDomain", "cluster.local", "DNS domain of the cluster, under which the slivers of a slice reach each other by client_id; slice-internal names are disabled if empty")
+	flag.Var(&containerImages, "containerImage", "name:image of a container image that can be deployed, in addition to the ContainerImage resources; the first one is the default; can be specified multiple times")
 	flag.StringVar(&containerCpuLimit, "containerCpuLimit", "2", "maximum amount of CPU that can be used by a container")
 	flag.StringVar(&containerMemoryLimit, "containerMemoryLimit", "2Gi", "maximum amount of memory that can be used by a container")
+	flag.StringVar(&exclusiveNodeLabel, "exclusiveNodeLabel", "", "key of the node label, set to true, marking the nodes that can be allocated exclusively to a sliver; exclusive nodes are not supported if empty")
+	flag.Var(&imageAllowlist, "imageAllowlist", "pattern of the registry/repository of the custom images that can be deployed, e.g. docker.io/library/*; can be specified multiple times")
+	flag.StringVar(&ingressClass, "ingressClass", "", "class of the ingresses serving the ports requested with edgenet:port ingress=\"true\"; defaults to the default ingress class")
+	flag.StringVar(&ingressDomain, "ingressDomain", "", "domain under which the ingresses get one hostname per sliver port, e.g. slivers.example.org; ingresses are not supported if empty")
+	flag.StringVar(&ingressIssuer, "ingressIssuer", "", "cert-manager cluster issuer of the TLS certificates of the ingresses, when -ingressTLSSecret is empty")
+	flag.StringVar(&ingressTLSSecret, "ingressTLSSecret", "", "secret holding a wildcard TLS certificate for -ingressDomain, in -namespace, copied to the slice namespaces with -namespacePerSlice; the ingresses serve plain HTTP if empty, unless -ingressIssuer is set")
+	flag.StringVar(&installImage, "installImage", "docker.io/library/busybox:1.34", "image of the init container downloading the install services, which must provide sh, wget, tar and unzip")
 	flag.StringVar(&kubeconfigFile, "kubeconfig", "", "path to the kubeconfig file used to communicate with the Kubernetes API")
+	flag.BoolVar(&leaderElection, "leaderElection", false, "run the background workers only on the replica holding the leader lease")
+	flag.StringVar(&leaderElectionName, "leaderElectionName", "fed4fire-am", "name of the lease used for leader election")
+	flag.StringVar(&leaderElectionNamespace, "leaderElectionNamespace", "", "namespace of the lease used for leader election; defaults to -namespace")
+	flag.StringVar(&linkCniConfigFile, "linkCniConfig", "", "path to the template of the CNI configuration of the Multus networks created for the RSpec links; links are not supported if empty")
+	flag.StringVar(&linkShapingImage, "linkShapingImage", "", "image of the init container applying the link latency and packet loss with tc and netem; latency and packet loss are not supported if empty")
+	flag.StringVar(&linkSubnet, "linkSubnet", "10.128.0.0/16", "IPv4 subnet from which the addresses of the link interfaces are assigned, one /24 per link")
 	flag.StringVar(&listenAddr, "listenAddr", "localhost:9443", "host:port on which to listen")
+	flag.StringVar(&maxCpuRequest, "maxCpuRequest", "4", "maximum amount of CPU that a user can request for a container, unless set for the user with -userMaxRequest")
+	flag.Int64Var(&maxLinkCapacity, "maxLinkCapacity", 1000000, "maximum capacity in kbps that a user can request for a link")
+	flag.Int64Var(&maxLinkLatency, "maxLinkLatency", 1000, "maximum latency in ms that a user can request for a link")
+	flag.StringVar(&maxMemoryRequest, "maxMemoryRequest", "4Gi", "maximum amount of memory that a user can request for a container, unless set for the user with -userMaxRequest")
+	flag.IntVar(&maxNodeCount, "maxNodeCount", 100, "maximum number of nodes of a request, including the copies requested with edgenet:count")
+	flag.Int64Var(&maxRequestSize, "maxRequestSize", 16777216, "maximum size in bytes of the XML-RPC requests, once decompressed")
+	flag.IntVar(&maxRspecSize, "maxRspecSize", 4194304, "maximum size in bytes of the request RSpecs")
+	flag.StringVar(&maxVolumeSize, "maxVolumeSize", "10Gi", "maximum size that a user can request for a volume with edgenet:volume")
 	flag.StringVar(&namespace, "namespace", "", "kubernetes namespaces in which to create resources")
+	flag.StringVar(&namespaceCpuLimit, "namespaceCpuLimit", "8", "maximum amount of CPU that can be used by a slice when using -namespacePerSlice")
+	flag.StringVar(&namespaceMemoryLimit, "namespaceMemoryLimit", "8Gi", "maximum amount of memory that can be used by a slice when using -namespacePerSlice")
+	flag.BoolVar(&namespacePerSlice, "namespacePerSlice", false, "create one namespace per slice instead of using -namespace for all the slivers")
+	flag.BoolVar(&networkIsolation, "networkIsolation", true, "only accept SSH traffic and traffic from the same slice in the slivers")
+	flag.Var(&placementLabels, "placementLabel", "key of a node label that users can require in the placement of their nodes; can be specified multiple times")
+	flag.StringVar(&portServiceType, "portServiceType", "NodePort", "type of the services exposing the ports requested with edgenet:port, NodePort or LoadBalancer")
+	flag.BoolVar(&readOnlyRootFilesystem, "readOnlyRootFilesystem", false, "mount the root filesystem of the containers as read-only")
+	flag.BoolVar(&requireImageDigest, "requireImageDigest", false, "only allow custom images pinned by digest")
+	flag.StringVar(&sharedStorageClass, "sharedStorageClass", "", "storage class, supporting ReadWriteMany, of the volumes shared by the slivers of a slice; shared volumes are not supported if empty")
+	flag.Var(&sliverTypeProfiles, "sliverTypeProfile", "type:profile security profile (privileged, baseline, or restricted only for custom-container) of a sliver type; can be specified multiple times")
+	flag.StringVar(&sshdCommand, "sshdCommand", "/usr/sbin/sshd -D -e", "command of the container images, started after the execute services")
+	flag.BoolVar(&strictDiskImages, "strictDiskImages", false, "reject the requests for unknown disk images instead of using the default image")
+	flag.StringVar(&storageClass, "storageClass", "", "storage class of the persistent volumes of the slivers; volumes are not supported if empty")
 	flag.Var(&trustedCerts, "trustedCert", "path to a trusted certificate for authenticating users; can be specified multiple times")
+	flag.Var(&userMaxRequests, "userMaxRequest", "key=cpu,memory maximum amounts of CPU and memory that a user can request for a container, where key is a user URN or an authority, e.g. example.org; can be specified multiple times")
 	flag.Parse()
 
 	if showHelp {
@@ -78,19 +164,83 @@ func main() {
 	kubeclient, err := kubernetes.NewForConfig(config)
 	utils.Check(err)
 
+	dynamicclient, err := dynamic.NewForConfig(config)
+	utils.Check(err)
+
 	authorityIdentifier := identifiers.Identifier{
 		Authorities:  []string{authorityName},
 		ResourceType: identifiers.ResourceTypeAuthority,
 		ResourceName: "am",
 	}
 
-	containerImages_ := make(map[string]string)
-	for _, s := range containerImages {
+	containerImages_ := make([]v1.ContainerImage, 0)
+	for i, s := range containerImages {
 		arr := strings.SplitN(s, ":", 2)
-		containerImages_[arr[0]] = arr[1]
+		containerImages_ = append(containerImages_, v1.ContainerImage{
+			ObjectMeta: metav1.ObjectMeta{Name: arr[0]},
+			Spec: v1.ContainerImageSpec{
+				Image:   arr[1],
+				Default: i == 0,
+			},
+		})
 		klog.InfoS("Parsed container image name", "name", arr[0], "image", arr[1])
 	}
 
+	imagePolicy, err := imagepolicy.NewPolicy(imageAllowlist, requireImageDigest)
+	utils.Check(err)
+
+	var linkCniConfig *template.Template
+	if linkCniConfigFile != "" {
+		linkCniConfig, err = template.ParseFiles(linkCniConfigFile)
+		utils.Check(err)
+	}
+	_, _, err = net.ParseCIDR(linkSubnet)
+	utils.Check(err)
+
+	if portServiceType != string(corev1.ServiceTypeNodePort) && portServiceType != string(corev1.ServiceTypeLoadBalancer) {
+		utils.Check(fmt.Errorf("-portServiceType must be %s or %s", corev1.ServiceTypeNodePort, corev1.ServiceTypeLoadBalancer))
+	}
+
+	containerCpuLimit_, err := resource.ParseQuantity(containerCpuLimit)
+	utils.Check(err)
+	containerMemoryLimit_, err := resource.ParseQuantity(containerMemoryLimit)
+	utils.Check(err)
+	maxCpuRequest_, err := resource.ParseQuantity(maxCpuRequest)
+	utils.Check(err)
+	maxMemoryRequest_, err := resource.ParseQuantity(maxMemoryRequest)
+	utils.Check(err)
+	maxVolumeSize_, err := resource.ParseQuantity(maxVolumeSize)
+	utils.Check(err)
+	namespaceCpuLimit_, err := resource.ParseQuantity(namespaceCpuLimit)
+	utils.Check(err)
+	namespaceMemoryLimit_, err := resource.ParseQuantity(namespaceMemoryLimit)
+	utils.Check(err)
+
+	userMaxRequests_ := make(map[string]service.MaxRequest)
+	for _, s := range userMaxRequests {
+		arr := strings.SplitN(s, "=", 2)
+		quantities := strings.SplitN(arr[1], ",", 2)
+		cpu, err := resource.ParseQuantity(quantities[0])
+		utils.Check(err)
+		memory, err := resource.ParseQuantity(quantities[1])
+		utils.Check(err)
+		userMaxRequests_[arr[0]] = service.MaxRequest{Cpu: cpu, Memory: memory}
+		klog.InfoS("Parsed user maximum request", "key", arr[0], "cpu", cpu.String(), "memory", memory.String())
+	}
+
+	sliverTypeProfiles_ := make(map[string]security.Profile)
+	for _, s := range sliverTypeProfiles {
+		arr := strings.SplitN(s, ":", 2)
+		profile, err := security.Parse(arr[1])
+		utils.Check(err)
+		// Only custom images run without sshd, which cannot run under all the profiles.
+		if arr[0] != constants.SliverTypeCustomContainer && !profile.SupportsSshd() {
+			utils.Check(fmt.Errorf("-sliverTypeProfile %s cannot run sshd from the image of sliver type %s", profile, arr[0]))
+		}
+		sliverTypeProfiles_[arr[0]] = profile
+		klog.InfoS("Parsed sliver type security profile", "type", arr[0], "profile", profile)
+	}
+
 	trustedCerts_ := make([][]byte, 0)
 	for _, s := range trustedCerts {
 		b, err := ioutil.ReadFile(s)
@@ -99,15 +249,47 @@ func main() {
 	}
 
 	s := &service.Service{
-		AbsoluteURL:          absoluteUrl,
-		AuthorityIdentifier:  authorityIdentifier,
-		ContainerImages:      containerImages_,
-		ContainerCpuLimit:    containerCpuLimit,
-		ContainerMemoryLimit: containerMemoryLimit,
-		Namespace:            namespace,
-		TrustedCertificates:  trustedCerts_,
-		Fed4FireClient:       f4fclient,
-		KubernetesClient:     kubeclient,
+		AbsoluteURL:            absoluteUrl,
+		AuthorityIdentifier:    authorityIdentifier,
+		ClusterDomain:          clusterDomain,
+		ContainerImages:        containerImages_,
+		ContainerCpuLimit:      containerCpuLimit_,
+		ContainerMemoryLimit:   containerMemoryLimit_,
+		ExclusiveNodeLabel:     exclusiveNodeLabel,
+		ImagePolicy:            imagePolicy,
+		IngressClass:           ingressClass,
+		IngressDomain:          ingressDomain,
+		IngressIssuer:          ingressIssuer,
+		IngressTLSSecret:       ingressTLSSecret,
+		InstallImage:           installImage,
+		LinkCniConfig:          linkCniConfig,
+		LinkShapingImage:       linkShapingImage,
+		LinkSubnet:             linkSubnet,
+		MaxCpuRequest:          maxCpuRequest_,
+		MaxLinkCapacity:        maxLinkCapacity,
+		MaxLinkLatency:         maxLinkLatency,
+		MaxMemoryRequest:       maxMemoryRequest_,
+		MaxNodeCount:           maxNodeCount,
+		MaxRspecSize:           maxRspecSize,
+		MaxVolumeSize:          maxVolumeSize_,
+		NamespaceCpuLimit:      namespaceCpuLimit_,
+		NamespaceMemoryLimit:   namespaceMemoryLimit_,
+		Namespace:              namespace,
+		NamespacePerSlice:      namespacePerSlice,
+		NetworkIsolation:       networkIsolation,
+		PlacementLabels:        placementLabels,
+		PortServiceType:        portServiceType,
+		ReadOnlyRootFilesystem: readOnlyRootFilesystem,
+		SecurityProfiles:       sliverTypeProfiles_,
+		SharedStorageClass:     sharedStorageClass,
+		SshdCommand:            sshdCommand,
+		StrictDiskImages:       strictDiskImages,
+		TrustedCertificates:    trustedCerts_,
+		StorageClass:           storageClass,
+		UserMaxRequests:        userMaxRequests_,
+		DynamicClient:          dynamicclient,
+		Fed4FireClient:         f4fclient,
+		KubernetesClient:       kubeclient,
 	}
 
 	xmlrpcCodec := xml.NewCodec()
@@ -118,14 +300,58 @@ func main() {
 	RPC.RegisterCodec(xmlrpcCodec, "text/xml")
 	utils.Check(RPC.RegisterService(s, ""))
 
-	gc.GC{
-		Fed4FireClient:   f4fclient,
-		KubernetesClient: kubeclient,
-		Interval:         5 * time.Second,
-		Timeout:          30 * time.Second,
-		Namespace:        namespace,
-	}.Start()
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	workers := []leader.Worker{
+		gc.GC{
+			Fed4FireClient:    f4fclient,
+			KubernetesClient:  kubeclient,
+			Interval:          5 * time.Second,
+			Timeout:           30 * time.Second,
+			Namespace:         s.SliversNamespace(),
+			NamespacePerSlice: namespacePerSlice,
+		},
+	}
+
+	workersDone := make(chan struct{})
+	if leaderElection {
+		identity, err := os.Hostname()
+		utils.Check(err)
+		if leaderElectionNamespace == "" {
+			leaderElectionNamespace = namespace
+		}
+		go func() {
+			leader.Elector{
+				KubernetesClient: kubeclient,
+				Identity:         identity,
+				LockName:         leaderElectionName,
+				LockNamespace:    leaderElectionNamespace,
+				LeaseDuration:    15 * time.Second,
+				RenewDeadline:    10 * time.Second,
+				RetryPeriod:      2 * time.Second,
+				Workers:          workers,
+			}.Run(ctx)
+			close(workersDone)
+		}()
+	} else {
+		leader.StartWorkers(ctx, workers)
+		close(workersDone)
+	}
+
+	// Responses smaller than a few packets are not worth compressing.
+	server := &http.Server{Addr: listenAddr, Handler: utils.GzipHandler(RPC, maxRequestSize, 4096)}
+	go func() {
+		<-ctx.Done()
+		klog.InfoS("Shutting down")
+		utils.Check(server.Shutdown(context.Background()))
+	}()
 
 	klog.InfoS("Listening", "address", listenAddr)
-	utils.Check(http.ListenAndServe(listenAddr, RPC))
+	err = server.ListenAndServe()
+	if err != http.ErrServerClosed {
+		utils.Check(err)
+	}
+	// Wait for the leader lease to be released before exiting.
+	<-workersDone
 }
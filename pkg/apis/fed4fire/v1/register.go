@@ -30,6 +30,8 @@ var (
 // Adds the list of known types to Scheme.
 func addKnownTypes(scheme *runtime.Scheme) error {
 	scheme.AddKnownTypes(SchemeGroupVersion,
+		&ContainerImage{},
+		&ContainerImageList{},
 		&Sliver{},
 		&SliverList{},
 	)
@@ -26,14 +26,156 @@ type SliverSpec struct {
 	UserURN string `json:"userUrn"`
 	// +kubebuilder:validation:Required
 	Expires metav1.Time `json:"expires"`
+	// Expiration of the slice, from the slice credential, until which the retained volumes of the sliver are kept.
+	// +optional
+	SliceExpires *metav1.Time `json:"sliceExpires,omitempty"`
 	// +kubebuilder:validation:Required
 	ClientID string `json:"clientId"`
 	// +kubebuilder:validation:Required
 	Image string `json:"image"`
+	// Name of the disk image in the manifests: the URN of a catalog image, or the reference of a custom image.
+	// +optional
+	DiskImageName string `json:"diskImageName,omitempty"`
+	// +optional
+	SliverType string `json:"sliverType"`
 	// +optional
 	RequestedArch *string `json:"requestedArch"`
+	// Architectures supported by the image, when no architecture is requested.
+	// +optional
+	Architectures []string `json:"architectures,omitempty"`
 	// +optional
 	RequestedNode *string `json:"requestedNode"`
+	// The sliver must be the only one on its node.
+	// +optional
+	Exclusive bool `json:"exclusive,omitempty"`
+	// +optional
+	Placement *SliverPlacement `json:"placement,omitempty"`
+	// Groups of slivers of the same slice that the sliver belongs to.
+	// +optional
+	Groups []SliverGroup `json:"groups,omitempty"`
+	// +optional
+	CpuRequest *string `json:"cpuRequest"`
+	// +optional
+	MemoryRequest *string `json:"memoryRequest"`
+	// +optional
+	Interfaces []SliverInterface `json:"interfaces,omitempty"`
+	// +optional
+	Installs []SliverInstall `json:"installs,omitempty"`
+	// +optional
+	Executes []SliverExecute `json:"executes,omitempty"`
+	// Container ports exposed outside of the cluster.
+	// +optional
+	Ports []SliverPort `json:"ports,omitempty"`
+	// Persistent volumes mounted in the container.
+	// +optional
+	Volumes []SliverVolume `json:"volumes,omitempty"`
+	// Unknown attributes and elements of the request RSpec node, as XML, echoed in the manifests.
+	// +optional
+	RspecExtensions string `json:"rspecExtensions,omitempty"`
+	// Namespace declarations and unknown elements of the request RSpec, and unknown attributes and elements
+	// of the links of the sliver, as XML, echoed in the manifests.
+	// +optional
+	RspecSliceExtensions string `json:"rspecSliceExtensions,omitempty"`
+	// URNs of the users whose SSH keys were installed when the sliver was provisioned.
+	// +optional
+	Users []string `json:"users,omitempty"`
+}
+
+// SliverPlacement restricts the nodes on which a sliver can run, from the EdgeNet placement extension.
+type SliverPlacement struct {
+	// ISO codes of the countries of the nodes.
+	// +optional
+	Countries []string `json:"countries,omitempty"`
+	// Names of the nodes within the requested radius, resolved when the sliver is allocated.
+	// +optional
+	Nodes []string `json:"nodes,omitempty"`
+	// Labels that the nodes must have.
+	// +optional
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+// SliverGroup is a group of slivers spread across or colocated in topology domains, from the EdgeNet group extension.
+type SliverGroup struct {
+	// +kubebuilder:validation:Required
+	Name string `json:"name"`
+	// One of spread, balance or colocate.
+	// +kubebuilder:validation:Required
+	Policy string `json:"policy"`
+	// One of hostname or country.
+	// +kubebuilder:validation:Required
+	Topology string `json:"topology"`
+}
+
+// SliverInstall is a file downloaded in the sliver before it starts, from an RSpec install service.
+type SliverInstall struct {
+	// +kubebuilder:validation:Required
+	URL string `json:"url"`
+	// +kubebuilder:validation:Required
+	InstallPath string `json:"installPath"`
+}
+
+// SliverExecute is a command run when the sliver starts, from an RSpec execute service.
+type SliverExecute struct {
+	// +kubebuilder:validation:Required
+	Shell string `json:"shell"`
+	// +kubebuilder:validation:Required
+	Command string `json:"command"`
+}
+
+// SliverPort is a container port exposed outside of the cluster, from the EdgeNet port extension.
+type SliverPort struct {
+	// +kubebuilder:validation:Required
+	Port int32 `json:"port"`
+	// TCP or UDP.
+	// +kubebuilder:validation:Required
+	Protocol string `json:"protocol"`
+	// Whether the port is also served over HTTP(S) by an ingress.
+	Ingress bool `json:"ingress,omitempty"`
+}
+
+// SliverVolume is a persistent volume of a sliver, from the EdgeNet volume extension.
+type SliverVolume struct {
+	// +kubebuilder:validation:Required
+	Name string `json:"name"`
+	// Kubernetes quantity, e.g. 1Gi.
+	// +kubebuilder:validation:Required
+	Size string `json:"size"`
+	// Absolute path at which the volume is mounted.
+	// +kubebuilder:validation:Required
+	Path string `json:"path"`
+	// Whether the volume is shared by the slivers of the slice mounting a volume of the same name.
+	// +optional
+	Shared bool `json:"shared,omitempty"`
+	// Whether the volume is kept until the expiration of the slice, instead of being deleted with the sliver.
+	// +optional
+	Retain bool `json:"retain,omitempty"`
+}
+
+// SliverInterface is a network interface of a sliver, attached to an RSpec link.
+type SliverInterface struct {
+	// +kubebuilder:validation:Required
+	ClientID string `json:"clientId"`
+	// +kubebuilder:validation:Required
+	LinkClientID string `json:"linkClientId"`
+	// Address of the interface in CIDR notation, e.g. 10.128.0.1/24.
+	// +kubebuilder:validation:Required
+	Address string `json:"address"`
+	// Shaping of the traffic sent on the interface.
+	// +optional
+	Shaping *LinkShaping `json:"shaping,omitempty"`
+}
+
+// LinkShaping is the traffic shaping applied to an interface, from the RSpec link properties.
+type LinkShaping struct {
+	// Capacity in kbps.
+	// +optional
+	Capacity int64 `json:"capacity,omitempty"`
+	// Latency in ms.
+	// +optional
+	Latency int64 `json:"latency,omitempty"`
+	// Packet loss, as a fraction between 0 and 1, e.g. 0.01.
+	// +optional
+	PacketLoss string `json:"packetLoss,omitempty"`
 }
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
@@ -43,3 +185,56 @@ type SliverList struct {
 	metav1.ListMeta `json:"metadata"`
 	Items           []Sliver `json:"items"`
 }
+
+// +genclient
+// +genclient:nonNamespaced
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// +kubebuilder:printcolumn:name="IMAGE",type="string",JSONPath=".spec.image"
+// +kubebuilder:printcolumn:name="DEFAULT",type="boolean",JSONPath=".spec.default"
+// +kubebuilder:printcolumn:name="AGE",type="date",JSONPath=".metadata.creationTimestamp"
+// +kubebuilder:resource:singular=containerimage,path=containerimages,scope=Cluster
+type ContainerImage struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+	Spec              ContainerImageSpec `json:"spec,omitempty"`
+}
+
+// ContainerImageSpec describes a disk image that can be requested in an RSpec.
+// The image is advertised and requested under the name of the object.
+type ContainerImageSpec struct {
+	// OCI reference of the image, e.g. docker.io/library/ubuntu:20.04.
+	// +kubebuilder:validation:Required
+	Image string `json:"image"`
+	// Architectures supported by the image, as in the kubernetes.io/arch node label.
+	// The image is assumed to support all the architectures if empty.
+	// +optional
+	Architectures []string `json:"architectures,omitempty"`
+	// Images to use instead of the main image on specific architectures.
+	// +optional
+	Variants []ContainerImageVariant `json:"variants,omitempty"`
+	// +optional
+	Description string `json:"description,omitempty"`
+	// +optional
+	OS string `json:"os,omitempty"`
+	// +optional
+	OSVersion string `json:"osVersion,omitempty"`
+	// Use this image when no disk image is requested.
+	// +optional
+	Default bool `json:"default,omitempty"`
+}
+
+type ContainerImageVariant struct {
+	// +kubebuilder:validation:Required
+	Architecture string `json:"architecture"`
+	// +kubebuilder:validation:Required
+	Image string `json:"image"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+type ContainerImageList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata"`
+	Items           []ContainerImage `json:"items"`
+}
@@ -25,6 +25,124 @@ import (
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ContainerImage) DeepCopyInto(out *ContainerImage) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ContainerImage.
+func (in *ContainerImage) DeepCopy() *ContainerImage {
+	if in == nil {
+		return nil
+	}
+	out := new(ContainerImage)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ContainerImage) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ContainerImageList) DeepCopyInto(out *ContainerImageList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]ContainerImage, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ContainerImageList.
+func (in *ContainerImageList) DeepCopy() *ContainerImageList {
+	if in == nil {
+		return nil
+	}
+	out := new(ContainerImageList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ContainerImageList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ContainerImageSpec) DeepCopyInto(out *ContainerImageSpec) {
+	*out = *in
+	if in.Architectures != nil {
+		in, out := &in.Architectures, &out.Architectures
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Variants != nil {
+		in, out := &in.Variants, &out.Variants
+		*out = make([]ContainerImageVariant, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ContainerImageSpec.
+func (in *ContainerImageSpec) DeepCopy() *ContainerImageSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ContainerImageSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ContainerImageVariant) DeepCopyInto(out *ContainerImageVariant) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ContainerImageVariant.
+func (in *ContainerImageVariant) DeepCopy() *ContainerImageVariant {
+	if in == nil {
+		return nil
+	}
+	out := new(ContainerImageVariant)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LinkShaping) DeepCopyInto(out *LinkShaping) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LinkShaping.
+func (in *LinkShaping) DeepCopy() *LinkShaping {
+	if in == nil {
+		return nil
+	}
+	out := new(LinkShaping)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Sliver) DeepCopyInto(out *Sliver) {
 	*out = *in
@@ -52,6 +170,75 @@ func (in *Sliver) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SliverExecute) DeepCopyInto(out *SliverExecute) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SliverExecute.
+func (in *SliverExecute) DeepCopy() *SliverExecute {
+	if in == nil {
+		return nil
+	}
+	out := new(SliverExecute)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SliverGroup) DeepCopyInto(out *SliverGroup) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SliverGroup.
+func (in *SliverGroup) DeepCopy() *SliverGroup {
+	if in == nil {
+		return nil
+	}
+	out := new(SliverGroup)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SliverInstall) DeepCopyInto(out *SliverInstall) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SliverInstall.
+func (in *SliverInstall) DeepCopy() *SliverInstall {
+	if in == nil {
+		return nil
+	}
+	out := new(SliverInstall)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SliverInterface) DeepCopyInto(out *SliverInterface) {
+	*out = *in
+	if in.Shaping != nil {
+		in, out := &in.Shaping, &out.Shaping
+		*out = new(LinkShaping)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SliverInterface.
+func (in *SliverInterface) DeepCopy() *SliverInterface {
+	if in == nil {
+		return nil
+	}
+	out := new(SliverInterface)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *SliverList) DeepCopyInto(out *SliverList) {
 	*out = *in
@@ -85,20 +272,130 @@ func (in *SliverList) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SliverPlacement) DeepCopyInto(out *SliverPlacement) {
+	*out = *in
+	if in.Countries != nil {
+		in, out := &in.Countries, &out.Countries
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Nodes != nil {
+		in, out := &in.Nodes, &out.Nodes
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Labels != nil {
+		in, out := &in.Labels, &out.Labels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SliverPlacement.
+func (in *SliverPlacement) DeepCopy() *SliverPlacement {
+	if in == nil {
+		return nil
+	}
+	out := new(SliverPlacement)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SliverPort) DeepCopyInto(out *SliverPort) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SliverPort.
+func (in *SliverPort) DeepCopy() *SliverPort {
+	if in == nil {
+		return nil
+	}
+	out := new(SliverPort)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *SliverSpec) DeepCopyInto(out *SliverSpec) {
 	*out = *in
 	in.Expires.DeepCopyInto(&out.Expires)
+	if in.SliceExpires != nil {
+		in, out := &in.SliceExpires, &out.SliceExpires
+		*out = (*in).DeepCopy()
+	}
 	if in.RequestedArch != nil {
 		in, out := &in.RequestedArch, &out.RequestedArch
 		*out = new(string)
 		**out = **in
 	}
+	if in.Architectures != nil {
+		in, out := &in.Architectures, &out.Architectures
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 	if in.RequestedNode != nil {
 		in, out := &in.RequestedNode, &out.RequestedNode
 		*out = new(string)
 		**out = **in
 	}
+	if in.Placement != nil {
+		in, out := &in.Placement, &out.Placement
+		*out = new(SliverPlacement)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Groups != nil {
+		in, out := &in.Groups, &out.Groups
+		*out = make([]SliverGroup, len(*in))
+		copy(*out, *in)
+	}
+	if in.CpuRequest != nil {
+		in, out := &in.CpuRequest, &out.CpuRequest
+		*out = new(string)
+		**out = **in
+	}
+	if in.MemoryRequest != nil {
+		in, out := &in.MemoryRequest, &out.MemoryRequest
+		*out = new(string)
+		**out = **in
+	}
+	if in.Interfaces != nil {
+		in, out := &in.Interfaces, &out.Interfaces
+		*out = make([]SliverInterface, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Installs != nil {
+		in, out := &in.Installs, &out.Installs
+		*out = make([]SliverInstall, len(*in))
+		copy(*out, *in)
+	}
+	if in.Executes != nil {
+		in, out := &in.Executes, &out.Executes
+		*out = make([]SliverExecute, len(*in))
+		copy(*out, *in)
+	}
+	if in.Ports != nil {
+		in, out := &in.Ports, &out.Ports
+		*out = make([]SliverPort, len(*in))
+		copy(*out, *in)
+	}
+	if in.Volumes != nil {
+		in, out := &in.Volumes, &out.Volumes
+		*out = make([]SliverVolume, len(*in))
+		copy(*out, *in)
+	}
+	if in.Users != nil {
+		in, out := &in.Users, &out.Users
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 	return
 }
 
@@ -111,3 +408,19 @@ func (in *SliverSpec) DeepCopy() *SliverSpec {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SliverVolume) DeepCopyInto(out *SliverVolume) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SliverVolume.
+func (in *SliverVolume) DeepCopy() *SliverVolume {
+	if in == nil {
+		return nil
+	}
+	out := new(SliverVolume)
+	in.DeepCopyInto(out)
+	return out
+}
@@ -21,8 +21,19 @@ const (
 const (
 	ErrorBadAction        = "Unsupported action"
 	ErrorBadCredentials   = "Invalid credentials"
+	ErrorBadDiskImage     = "Unsupported disk image"
+	ErrorBadExclusive     = "Unsupported exclusive node"
+	ErrorBadGroup         = "Unsupported group"
+	ErrorBadHardwareType  = "Unsupported hardware type"
+	ErrorBadLink          = "Unsupported link"
+	ErrorBadPlacement     = "Unsupported placement"
+	ErrorBadPort          = "Unsupported port"
 	ErrorBadTime          = "Failed to parse time"
 	ErrorBadIdentifier    = "Failed to parse identifier"
+	ErrorBadQuantity      = "Failed to parse quantity"
+	ErrorBadRspec         = "Invalid request rspec"
+	ErrorBadService       = "Unsupported service"
+	ErrorBadVolume        = "Unsupported volume"
 	ErrorBuildResources   = "Failed to build resources"
 	ErrorCreateResource   = "Failed to create resource"
 	ErrorDeleteResource   = "Failed to delete resource"
@@ -36,7 +47,10 @@ const (
 // Names for Kubernetes objects labels and annotations.
 const (
 	Fed4FireClientId   = "fed4fire.eu/client-id"
+	Fed4FireExclusive  = "fed4fire.eu/exclusive"
 	Fed4FireExpires    = "fed4fire.eu/expires"
+	Fed4FireGroup      = "group.fed4fire.eu/"
+	Fed4FireRetain     = "fed4fire.eu/retain"
 	Fed4FireSlice      = "fed4fire.eu/slice"
 	Fed4FireSliceHash  = "fed4fire.eu/slice-hash"
 	Fed4FireSliver     = "fed4fire.eu/sliver"
@@ -48,6 +62,13 @@ const (
 	GeniActionStart = "geni_start"
 )
 
+// Sliver types supported by this AM.
+const (
+	SliverTypeContainer = "container"
+	// Container running an image outside of the catalog, without SSH access.
+	SliverTypeCustomContainer = "custom-container"
+)
+
 // https://groups.geni.net/geni/attachment/wiki/GAPI_AM_API_V3/CommonConcepts/geni-error-codes.xml
 const (
 	// Success
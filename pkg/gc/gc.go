@@ -2,7 +2,9 @@ package gc
 
 import (
 	"context"
+	"github.com/EdgeNet-project/fed4fire/pkg/constants"
 	"github.com/EdgeNet-project/fed4fire/pkg/generated/clientset/versioned"
+	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"time"
 
@@ -15,35 +17,52 @@ type GC struct {
 	KubernetesClient kubernetes.Interface
 	Interval         time.Duration
 	Timeout          time.Duration
-	Namespace        string
+	// Namespace in which to look for slivers, or all the namespaces if empty.
+	Namespace string
+	// Delete the per-slice namespaces which no longer contain any sliver or retained volume.
+	NamespacePerSlice bool
 }
 
-func (w GC) Start() {
-	go w.loop()
+// Empty namespaces are not deleted right after their creation,
+// since the AM creates the namespace of a slice before its slivers.
+const emptyNamespaceGracePeriod = time.Minute
+
+// Start runs the collector in the background until ctx is cancelled.
+func (w GC) Start(ctx context.Context) {
+	go w.loop(ctx)
 	klog.InfoS("Started collector")
 }
 
-func (w GC) loop() {
-	w.collect() // Run instantly on start.
-	for range time.Tick(w.Interval) {
-		w.collect()
+func (w GC) loop(ctx context.Context) {
+	ticker := time.NewTicker(w.Interval)
+	defer ticker.Stop()
+	w.collect(ctx) // Run instantly on start.
+	for {
+		select {
+		case <-ctx.Done():
+			klog.InfoS("Stopped collector")
+			return
+		case <-ticker.C:
+			w.collect(ctx)
+		}
 	}
 }
 
-func (w GC) collect() {
+func (w GC) collect(ctx context.Context) {
 	sliversClient := w.Fed4FireClient.Fed4fireV1().Slivers(w.Namespace)
-	deploymentsClient := w.KubernetesClient.AppsV1().Deployments(w.Namespace)
 
-	ctx, cancel := context.WithTimeout(context.Background(), w.Timeout)
+	ctx, cancel := context.WithTimeout(ctx, w.Timeout)
 	defer cancel()
 
 	slivers, err := sliversClient.List(ctx, metav1.ListOptions{})
 	if err != nil {
 		klog.ErrorS(err, "Failed to list slivers")
+		return
 	}
 
 	for _, sliver := range slivers.Items {
 		if time.Now().After(sliver.Spec.Expires.Time) {
+			deploymentsClient := w.KubernetesClient.AppsV1().Deployments(sliver.Namespace)
 			deployment, err := deploymentsClient.Get(ctx, sliver.Name, metav1.GetOptions{})
 			if err != nil {
 				klog.ErrorS(err, "Failed to get deployment")
@@ -57,4 +76,87 @@ func (w GC) collect() {
 			klog.InfoS("Deleted expired deployment", "sliver", sliver.Name)
 		}
 	}
+
+	w.collectVolumeClaims(ctx)
+
+	if w.NamespacePerSlice {
+		w.collectNamespaces(ctx)
+	}
+}
+
+// collectVolumeClaims deletes the retained volumes of the slivers once they expire.
+// The volumes which are not retained are owned by their slivers, and deleted with them.
+func (w GC) collectVolumeClaims(ctx context.Context) {
+	claimsClient := w.KubernetesClient.CoreV1().PersistentVolumeClaims(w.Namespace)
+
+	claims, err := claimsClient.List(ctx, metav1.ListOptions{
+		LabelSelector: constants.Fed4FireRetain,
+	})
+	if err != nil {
+		klog.ErrorS(err, "Failed to list volume claims")
+		return
+	}
+
+	for _, claim := range claims.Items {
+		expires, err := time.Parse(time.RFC3339, claim.Annotations[constants.Fed4FireExpires])
+		if err != nil {
+			klog.ErrorS(err, "Failed to parse volume claim expiration", "claim", claim.Name)
+			continue
+		}
+		if time.Now().Before(expires) {
+			continue
+		}
+		err = w.KubernetesClient.CoreV1().PersistentVolumeClaims(claim.Namespace).
+			Delete(ctx, claim.Name, metav1.DeleteOptions{})
+		if err != nil && !errors.IsNotFound(err) {
+			klog.ErrorS(err, "Failed to delete volume claim")
+			continue
+		}
+		klog.InfoS("Deleted expired volume claim", "claim", claim.Name)
+	}
+}
+
+func (w GC) collectNamespaces(ctx context.Context) {
+	namespacesClient := w.KubernetesClient.CoreV1().Namespaces()
+
+	namespaces, err := namespacesClient.List(ctx, metav1.ListOptions{
+		LabelSelector: constants.Fed4FireSliceHash,
+	})
+	if err != nil {
+		klog.ErrorS(err, "Failed to list namespaces")
+		return
+	}
+
+	for _, namespace := range namespaces.Items {
+		if time.Since(namespace.CreationTimestamp.Time) < emptyNamespaceGracePeriod {
+			continue
+		}
+		slivers, err := w.Fed4FireClient.Fed4fireV1().
+			Slivers(namespace.Name).
+			List(ctx, metav1.ListOptions{})
+		if err != nil {
+			klog.ErrorS(err, "Failed to list slivers")
+			continue
+		}
+		if len(slivers.Items) > 0 {
+			continue
+		}
+		// The retained volumes must expire first.
+		claims, err := w.KubernetesClient.CoreV1().
+			PersistentVolumeClaims(namespace.Name).
+			List(ctx, metav1.ListOptions{LabelSelector: constants.Fed4FireRetain})
+		if err != nil {
+			klog.ErrorS(err, "Failed to list volume claims")
+			continue
+		}
+		if len(claims.Items) > 0 {
+			continue
+		}
+		err = namespacesClient.Delete(ctx, namespace.Name, metav1.DeleteOptions{})
+		if err != nil && !errors.IsNotFound(err) {
+			klog.ErrorS(err, "Failed to delete namespace")
+			continue
+		}
+		klog.InfoS("Deleted empty slice namespace", "namespace", namespace.Name)
+	}
 }
@@ -0,0 +1,125 @@
+package gc
+
+import (
+	"context"
+	v1 "github.com/EdgeNet-project/fed4fire/pkg/apis/fed4fire/v1"
+	"github.com/EdgeNet-project/fed4fire/pkg/constants"
+	f4ftestclient "github.com/EdgeNet-project/fed4fire/pkg/generated/clientset/versioned/fake"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	kubetestclient "k8s.io/client-go/kubernetes/fake"
+)
+
+func testGC(f4fObjects []runtime.Object, kubernetesObjects []runtime.Object) GC {
+	return GC{
+		Fed4FireClient:    f4ftestclient.NewSimpleClientset(f4fObjects...),
+		KubernetesClient:  kubetestclient.NewSimpleClientset(kubernetesObjects...),
+		Interval:          time.Minute,
+		Timeout:           time.Minute,
+		NamespacePerSlice: true,
+	}
+}
+
+func testNamespace(name string, age time.Duration) *corev1.Namespace {
+	return &corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              name,
+			CreationTimestamp: metav1.NewTime(time.Now().Add(-age)),
+			Labels: map[string]string{
+				constants.Fed4FireSliceHash: name,
+			},
+		},
+	}
+}
+
+func namespaceExists(w GC, name string) bool {
+	_, err := w.KubernetesClient.CoreV1().Namespaces().Get(context.TODO(), name, metav1.GetOptions{})
+	return err == nil
+}
+
+func TestCollectNamespaces(t *testing.T) {
+	sliver := &v1.Sliver{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "sliver",
+			Namespace: "with-sliver",
+		},
+	}
+	w := testGC(
+		[]runtime.Object{sliver},
+		[]runtime.Object{
+			testNamespace("recent", time.Second),
+			testNamespace("with-sliver", time.Hour),
+			testNamespace("empty", time.Hour),
+		},
+	)
+	w.collectNamespaces(context.TODO())
+	// The AM creates the namespace of a slice before its slivers.
+	assert.True(t, namespaceExists(w, "recent"))
+	assert.True(t, namespaceExists(w, "with-sliver"))
+	assert.False(t, namespaceExists(w, "empty"))
+}
+
+func TestCollectNamespaces_OtherNamespaces(t *testing.T) {
+	other := &corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              "other",
+			CreationTimestamp: metav1.NewTime(time.Now().Add(-time.Hour)),
+		},
+	}
+	w := testGC(nil, []runtime.Object{other})
+	w.collectNamespaces(context.TODO())
+	assert.True(t, namespaceExists(w, "other"))
+}
+
+func testRetainedClaim(name string, namespace string, expires string) *corev1.PersistentVolumeClaim {
+	return &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+			Labels: map[string]string{
+				constants.Fed4FireRetain: "true",
+			},
+			Annotations: map[string]string{
+				constants.Fed4FireExpires: expires,
+			},
+		},
+	}
+}
+
+func claimExists(w GC, namespace string, name string) bool {
+	_, err := w.KubernetesClient.CoreV1().PersistentVolumeClaims(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+	return err == nil
+}
+
+func TestCollectNamespaces_RetainedClaim(t *testing.T) {
+	expires := time.Now().Add(time.Hour).Format(time.RFC3339)
+	w := testGC(
+		nil,
+		[]runtime.Object{
+			testNamespace("with-claim", time.Hour),
+			testRetainedClaim("data", "with-claim", expires),
+		},
+	)
+	w.collectNamespaces(context.TODO())
+	assert.True(t, namespaceExists(w, "with-claim"))
+}
+
+func TestCollectVolumeClaims(t *testing.T) {
+	w := testGC(
+		nil,
+		[]runtime.Object{
+			testRetainedClaim("expired", "slice", time.Now().Add(-time.Hour).Format(time.RFC3339)),
+			testRetainedClaim("unexpired", "slice", time.Now().Add(time.Hour).Format(time.RFC3339)),
+			testRetainedClaim("unparsable", "slice", "tomorrow"),
+		},
+	)
+	w.collectVolumeClaims(context.TODO())
+	assert.False(t, claimExists(w, "slice", "expired"))
+	assert.True(t, claimExists(w, "slice", "unexpired"))
+	assert.True(t, claimExists(w, "slice", "unparsable"))
+}
@@ -28,6 +28,10 @@ type FakeFed4fireV1 struct {
 	*testing.Fake
 }
 
+func (c *FakeFed4fireV1) ContainerImages() v1.ContainerImageInterface {
+	return &FakeContainerImages{c}
+}
+
 func (c *FakeFed4fireV1) Slivers(namespace string) v1.SliverInterface {
 	return &FakeSlivers{c, namespace}
 }
@@ -26,6 +26,7 @@ import (
 
 type Fed4fireV1Interface interface {
 	RESTClient() rest.Interface
+	ContainerImagesGetter
 	SliversGetter
 }
 
@@ -34,6 +35,10 @@ type Fed4fireV1Client struct {
 	restClient rest.Interface
 }
 
+func (c *Fed4fireV1Client) ContainerImages() ContainerImageInterface {
+	return newContainerImages(c)
+}
+
 func (c *Fed4fireV1Client) Slivers(namespace string) SliverInterface {
 	return newSlivers(c, namespace)
 }
@@ -0,0 +1,110 @@
+// Package imagepolicy decides whether experimenters can deploy an arbitrary OCI image,
+// outside of the container image catalog.
+package imagepolicy
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+var (
+	pathComponentRe = regexp.MustCompile(`^[a-z0-9]+(?:(?:[._]|__|-+)[a-z0-9]+)*$`)
+	tagRe           = regexp.MustCompile(`^\w[\w.-]{0,127}$`)
+	digestRe        = regexp.MustCompile(`^sha256:[a-f0-9]{64}$`)
+)
+
+// Reference is a fully-qualified OCI image reference: registry/repository[:tag][@digest].
+type Reference struct {
+	Registry   string
+	Repository string
+	Tag        string
+	Digest     string
+}
+
+// ParseReference parses an image reference which must include the registry,
+// e.g. docker.io/library/ubuntu:20.04 but not ubuntu:20.04.
+func ParseReference(s string) (*Reference, error) {
+	ref := &Reference{}
+	name := s
+	if i := strings.Index(name, "@"); i >= 0 {
+		ref.Digest = name[i+1:]
+		name = name[:i]
+		if !digestRe.MatchString(ref.Digest) {
+			return nil, fmt.Errorf("invalid digest in image reference %s", s)
+		}
+	}
+	i := strings.Index(name, "/")
+	if i < 0 {
+		return nil, fmt.Errorf("missing registry in image reference %s", s)
+	}
+	ref.Registry = name[:i]
+	if !strings.ContainsAny(ref.Registry, ".:") && ref.Registry != "localhost" {
+		return nil, fmt.Errorf("missing registry in image reference %s", s)
+	}
+	ref.Repository = name[i+1:]
+	if j := strings.LastIndex(ref.Repository, ":"); j >= 0 {
+		ref.Tag = ref.Repository[j+1:]
+		ref.Repository = ref.Repository[:j]
+		if !tagRe.MatchString(ref.Tag) {
+			return nil, fmt.Errorf("invalid tag in image reference %s", s)
+		}
+	}
+	for _, component := range strings.Split(ref.Repository, "/") {
+		if !pathComponentRe.MatchString(component) {
+			return nil, fmt.Errorf("invalid repository in image reference %s", s)
+		}
+	}
+	return ref, nil
+}
+
+// IsReference returns true if s is a fully-qualified image reference.
+func IsReference(s string) bool {
+	_, err := ParseReference(s)
+	return err == nil
+}
+
+// Name returns the registry and the repository of the image, without the tag and the digest.
+func (r Reference) Name() string {
+	return r.Registry + "/" + r.Repository
+}
+
+type Policy struct {
+	// Patterns of the allowed images, matched against registry/repository with path.Match,
+	// e.g. docker.io/library/* or ghcr.io/edgenet-project/*.
+	Allowlist []string
+	// Only allow images pinned by digest.
+	RequireDigest bool
+}
+
+// NewPolicy returns a policy after checking the syntax of the allowlist patterns.
+func NewPolicy(allowlist []string, requireDigest bool) (Policy, error) {
+	for _, pattern := range allowlist {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return Policy{}, fmt.Errorf("invalid image pattern %s: %s", pattern, err)
+		}
+	}
+	return Policy{allowlist, requireDigest}, nil
+}
+
+// Check returns the allowlist pattern matching an image reference,
+// or an error explaining why the image is not allowed.
+func (p Policy) Check(s string) (string, error) {
+	if len(p.Allowlist) == 0 {
+		return "", fmt.Errorf("custom images are not allowed on this aggregate")
+	}
+	ref, err := ParseReference(s)
+	if err != nil {
+		return "", err
+	}
+	if p.RequireDigest && ref.Digest == "" {
+		return "", fmt.Errorf("image %s must be pinned by digest", s)
+	}
+	for _, pattern := range p.Allowlist {
+		if ok, _ := path.Match(pattern, ref.Name()); ok {
+			return pattern, nil
+		}
+	}
+	return "", fmt.Errorf("image %s does not match any allowed pattern", s)
+}
@@ -0,0 +1,58 @@
+package imagepolicy
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+const testDigest = "sha256:b795f8e0caaaacad9859a9a38fe1c78154f8301fdaf0872eaf1520d66d9c0b98"
+
+func TestParseReference(t *testing.T) {
+	ref, err := ParseReference("docker.io/library/ubuntu:20.04")
+	assert.Nil(t, err)
+	assert.Equal(t, Reference{"docker.io", "library/ubuntu", "20.04", ""}, *ref)
+	assert.Equal(t, "docker.io/library/ubuntu", ref.Name())
+
+	ref, err = ParseReference("localhost:5000/ubuntu@" + testDigest)
+	assert.Nil(t, err)
+	assert.Equal(t, Reference{"localhost:5000", "ubuntu", "", testDigest}, *ref)
+
+	for _, s := range []string{
+		"ubuntu2004",
+		"ubuntu:20.04",
+		"library/ubuntu",
+		"docker.io/library/Ubuntu",
+		"docker.io/library/ubuntu:",
+		"docker.io/library/ubuntu@sha256:1234",
+	} {
+		assert.False(t, IsReference(s), s)
+	}
+}
+
+func TestNewPolicy(t *testing.T) {
+	_, err := NewPolicy([]string{"docker.io/library/*"}, false)
+	assert.Nil(t, err)
+	_, err = NewPolicy([]string{"docker.io/library/["}, false)
+	assert.NotNil(t, err)
+}
+
+func TestPolicyCheck(t *testing.T) {
+	_, err := Policy{}.Check("docker.io/library/ubuntu:20.04")
+	assert.NotNil(t, err)
+
+	policy := Policy{Allowlist: []string{"docker.io/library/*", "ghcr.io/edgenet-project/fed4fire"}}
+	pattern, err := policy.Check("docker.io/library/ubuntu:20.04")
+	assert.Nil(t, err)
+	assert.Equal(t, "docker.io/library/*", pattern)
+	pattern, err = policy.Check("ghcr.io/edgenet-project/fed4fire@" + testDigest)
+	assert.Nil(t, err)
+	assert.Equal(t, "ghcr.io/edgenet-project/fed4fire", pattern)
+	_, err = policy.Check("docker.io/someone/ubuntu:20.04")
+	assert.NotNil(t, err)
+
+	policy.RequireDigest = true
+	_, err = policy.Check("docker.io/library/ubuntu:20.04")
+	assert.NotNil(t, err)
+	_, err = policy.Check("docker.io/library/ubuntu:20.04@" + testDigest)
+	assert.Nil(t, err)
+}
@@ -0,0 +1,78 @@
+// Package leader runs background workers on a single AM replica at a time.
+// The replicas compete for a Kubernetes Lease, and only the current holder runs the workers.
+package leader
+
+import (
+	"context"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+	"k8s.io/klog/v2"
+)
+
+// Worker is a background task that must stop when its context is cancelled.
+type Worker interface {
+	Start(ctx context.Context)
+}
+
+type Elector struct {
+	KubernetesClient kubernetes.Interface
+	// Unique name of this replica, e.g. the pod name.
+	Identity      string
+	LockName      string
+	LockNamespace string
+	LeaseDuration time.Duration
+	RenewDeadline time.Duration
+	RetryPeriod   time.Duration
+	Workers       []Worker
+}
+
+// Run blocks until ctx is cancelled, starting the workers each time this replica
+// becomes the leader and stopping them as soon as the leadership is lost.
+// The lease is released on cancellation so that another replica can take over immediately.
+func (e Elector) Run(ctx context.Context) {
+	lock := &resourcelock.LeaseLock{
+		LeaseMeta: metav1.ObjectMeta{
+			Name:      e.LockName,
+			Namespace: e.LockNamespace,
+		},
+		Client: e.KubernetesClient.CoordinationV1(),
+		LockConfig: resourcelock.ResourceLockConfig{
+			Identity: e.Identity,
+		},
+	}
+	config := leaderelection.LeaderElectionConfig{
+		Lock:            lock,
+		LeaseDuration:   e.LeaseDuration,
+		RenewDeadline:   e.RenewDeadline,
+		RetryPeriod:     e.RetryPeriod,
+		ReleaseOnCancel: true,
+		Name:            e.LockName,
+		Callbacks: leaderelection.LeaderCallbacks{
+			OnStartedLeading: func(ctx context.Context) {
+				klog.InfoS("Started leading", "identity", e.Identity)
+				StartWorkers(ctx, e.Workers)
+			},
+			OnStoppedLeading: func() {
+				klog.InfoS("Stopped leading", "identity", e.Identity)
+			},
+			OnNewLeader: func(identity string) {
+				klog.InfoS("New leader elected", "identity", identity)
+			},
+		},
+	}
+	// RunOrDie returns when the leadership is lost; in this case we become a candidate again.
+	for ctx.Err() == nil {
+		leaderelection.RunOrDie(ctx, config)
+	}
+}
+
+// StartWorkers starts the workers with the given context.
+func StartWorkers(ctx context.Context, workers []Worker) {
+	for _, worker := range workers {
+		worker.Start(ctx)
+	}
+}
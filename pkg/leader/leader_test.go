@@ -0,0 +1,67 @@
+package leader
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubetestclient "k8s.io/client-go/kubernetes/fake"
+)
+
+type testWorker struct {
+	started chan struct{}
+	stopped chan struct{}
+}
+
+func (w testWorker) Start(ctx context.Context) {
+	close(w.started)
+	go func() {
+		<-ctx.Done()
+		close(w.stopped)
+	}()
+}
+
+func TestElector(t *testing.T) {
+	client := kubetestclient.NewSimpleClientset()
+	worker := testWorker{started: make(chan struct{}), stopped: make(chan struct{})}
+	elector := Elector{
+		KubernetesClient: client,
+		Identity:         "replica-1",
+		LockName:         "test",
+		LockNamespace:    "default",
+		LeaseDuration:    2 * time.Second,
+		RenewDeadline:    1 * time.Second,
+		RetryPeriod:      100 * time.Millisecond,
+		Workers:          []Worker{worker},
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		elector.Run(ctx)
+		close(done)
+	}()
+
+	select {
+	case <-worker.started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("worker was not started")
+	}
+	lease, err := client.CoordinationV1().Leases("default").Get(context.TODO(), "test", metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, "replica-1", *lease.Spec.HolderIdentity)
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("elector did not stop")
+	}
+	<-worker.stopped
+
+	// The lease must be released so that another replica can take over without waiting for it to expire.
+	lease, err = client.CoordinationV1().Leases("default").Get(context.TODO(), "test", metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, "", *lease.Spec.HolderIdentity)
+}
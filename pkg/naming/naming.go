@@ -5,6 +5,7 @@ package naming
 import (
 	"crypto/sha512"
 	"fmt"
+	"strings"
 )
 
 func SliceHash(sliceUrn string) string {
@@ -15,6 +16,54 @@ func SliverName(sliceUrn string, clientId string) string {
 	return "h" + sha512Sum(sliceUrn + clientId)[:16]
 }
 
+// LinkName returns the name of the network of an RSpec link.
+// It uses a different prefix than the slivers, whose names derive from the same inputs.
+func LinkName(sliceUrn string, linkClientId string) string {
+	return "l" + sha512Sum(sliceUrn + linkClientId)[:16]
+}
+
+// GroupName returns the name of an EdgeNet group of a slice, used in the labels of its pods.
+func GroupName(sliceUrn string, groupName string) string {
+	return "g" + sha512Sum(sliceUrn + groupName)[:16]
+}
+
+// VolumeName returns the name of a volume shared by the slivers of a slice.
+func VolumeName(sliceUrn string, volumeName string) string {
+	return "v" + sha512Sum(sliceUrn + volumeName)[:16]
+}
+
+// HostLabel returns the DNS label of the hostname of a sliver port, e.g. pc1-8080-h0123456789abcdef.
+// The client ID is kept readable, but truncated, and the slice hash keeps the labels of different slices apart.
+func HostLabel(sliceUrn string, clientId string, port int32) string {
+	client := HostName(clientId)
+	if len(client) > 32 {
+		client = strings.TrimRight(client[:32], "-")
+	}
+	return fmt.Sprintf("%s-%d-%s", client, port, SliceHash(sliceUrn))
+}
+
+// HostName returns the hostname of a sliver in its slice, e.g. pc1 for PC1.
+// The client ID is reduced to lowercase letters, digits and hyphens, and truncated to a DNS label.
+func HostName(clientId string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(clientId) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			b.WriteRune(r)
+		} else {
+			b.WriteRune('-')
+		}
+	}
+	name := b.String()
+	if len(name) > 63 {
+		name = name[:63]
+	}
+	name = strings.Trim(name, "-")
+	if name == "" {
+		name = "node"
+	}
+	return name
+}
+
 func sha512Sum(s string) string {
 	h := sha512.Sum512([]byte(s))
 	return fmt.Sprintf("%x", h)
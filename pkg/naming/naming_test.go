@@ -2,6 +2,7 @@ package naming
 
 import (
 	"github.com/stretchr/testify/assert"
+	"strings"
 	"testing"
 
 	"github.com/EdgeNet-project/fed4fire/pkg/identifiers"
@@ -21,3 +22,42 @@ func TestSliverName(t *testing.T) {
 	errs := validation.IsValidLabelValue(h)
 	assert.Len(t, errs, 0)
 }
+
+func TestLinkName(t *testing.T) {
+	h := LinkName(testSliceIdentifier.URN(), "link0")
+	errs := validation.IsDNS1123Label(h)
+	assert.Len(t, errs, 0)
+	assert.NotEqual(t, SliverName(testSliceIdentifier.URN(), "link0"), h)
+}
+
+func TestGroupName(t *testing.T) {
+	h := GroupName(testSliceIdentifier.URN(), "servers")
+	errs := validation.IsValidLabelValue(h)
+	assert.Len(t, errs, 0)
+	assert.NotEqual(t, SliverName(testSliceIdentifier.URN(), "servers"), h)
+}
+
+func TestVolumeName(t *testing.T) {
+	h := VolumeName(testSliceIdentifier.URN(), "data")
+	errs := validation.IsDNS1123Label(h)
+	assert.Len(t, errs, 0)
+	assert.NotEqual(t, SliverName(testSliceIdentifier.URN(), "data"), h)
+}
+
+func TestHostLabel(t *testing.T) {
+	h := HostLabel(testSliceIdentifier.URN(), "PC1", 8080)
+	assert.Equal(t, "pc1-8080-"+SliceHash(testSliceIdentifier.URN()), h)
+	for _, clientId := range []string{"Client$Id&*", "-*-", strings.Repeat("a", 100)} {
+		h = HostLabel(testSliceIdentifier.URN(), clientId, 65535)
+		errs := validation.IsDNS1123Label(h)
+		assert.Len(t, errs, 0)
+	}
+}
+
+func TestHostName(t *testing.T) {
+	assert.Equal(t, "pc1", HostName("PC1"))
+	assert.Equal(t, "client-id", HostName("Client$Id&*"))
+	assert.Equal(t, "node", HostName("-*-"))
+	errs := validation.IsDNS1123Label(HostName(strings.Repeat("a", 100)))
+	assert.Len(t, errs, 0)
+}
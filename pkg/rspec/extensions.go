@@ -0,0 +1,374 @@
+package rspec
+
+import (
+	"encoding/xml"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// XML namespace of the GENI RSpecs.
+const RspecNamespace = "http://www.geni.net/resources/rspec/3"
+
+const (
+	xmlnsPrefix  = "xmlns"
+	xmlPrefix    = "xml"
+	xmlNamespace = "http://www.w3.org/XML/1998/namespace"
+)
+
+// Extension is an XML element which is not modeled by this package, such as the jFed layout hints.
+// The unknown elements and attributes of the RSpecs are kept so that they can be echoed in the manifests.
+type Extension struct {
+	XMLName  xml.Name
+	Attrs    []xml.Attr  `xml:",any,attr"`
+	Text     string      `xml:",chardata"`
+	Children []Extension `xml:",any"`
+}
+
+// MarshalXML writes the RSpec with the namespace prefixes of the unknown elements and attributes.
+// encoding/xml keeps the namespaces but not the prefixes when unmarshaling, and would otherwise
+// declare a new namespace on each element, and mangle the namespace declarations of the request.
+func (r Rspec) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	type rspec Rspec
+	s := scope{
+		defaultNamespace: RspecNamespace,
+		prefixes:         map[string]string{xmlPrefix: xmlNamespace},
+		generated:        new(int),
+	}
+	s, r.Attrs = s.enter(r.Attrs, true)
+	r.Extensions = s.resolveAll(r.Extensions)
+	nodes := make([]Node, len(r.Nodes))
+	for i, node := range r.Nodes {
+		nodes[i] = node.resolve(s)
+	}
+	r.Nodes = nodes
+	links := make([]Link, len(r.Links))
+	for i, link := range r.Links {
+		links[i] = link.resolve(s)
+	}
+	r.Links = links
+	start.Name = xml.Name{Space: RspecNamespace, Local: "rspec"}
+	return e.EncodeElement(rspec(r), start)
+}
+
+// NamespaceDeclarations returns the xmlns:prefix attributes, e.g. of a request RSpec to declare them in the manifest.
+func NamespaceDeclarations(attrs []xml.Attr) []xml.Attr {
+	declarations := make([]xml.Attr, 0)
+	for _, attr := range attrs {
+		if attr.Name.Space == xmlnsPrefix {
+			declarations = append(declarations, attr)
+		}
+	}
+	return declarations
+}
+
+// nodeExtensions holds the unknown attributes and elements of a node, stored with the sliver.
+// The RSpec namespace is declared with an attribute, since encoding/xml would otherwise
+// reset the default namespace of the prefixed elements.
+type nodeExtensions struct {
+	XMLName    xml.Name    `xml:"node"`
+	Attrs      []xml.Attr  `xml:",any,attr"`
+	Extensions []Extension `xml:",any"`
+}
+
+// MarshalNodeExtensions returns the unknown attributes and elements of a node of an RSpec, as an XML node element
+// declaring the namespaces of the RSpec. It returns an empty string if there are none.
+func MarshalNodeExtensions(r Rspec, node Node) (string, error) {
+	if len(node.Attrs) == 0 && len(node.Extensions) == 0 {
+		return "", nil
+	}
+	s := scope{
+		defaultNamespace: RspecNamespace,
+		prefixes:         map[string]string{xmlPrefix: xmlNamespace},
+		generated:        new(int),
+	}
+	s, attrs := s.enter(append(NamespaceDeclarations(r.Attrs), node.Attrs...), true)
+	attrs = append([]xml.Attr{{Name: xml.Name{Local: xmlnsPrefix}, Value: RspecNamespace}}, attrs...)
+	b, err := xml.Marshal(nodeExtensions{Attrs: attrs, Extensions: s.resolveAll(node.Extensions)})
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// UnmarshalNodeExtensions restores the attributes and elements returned by MarshalNodeExtensions in a node.
+// The namespace declarations are restored as attributes of the node.
+func UnmarshalNodeExtensions(s string, node *Node) error {
+	if s == "" {
+		return nil
+	}
+	v := nodeExtensions{}
+	err := xml.Unmarshal([]byte(s), &v)
+	if err != nil {
+		return err
+	}
+	for _, attr := range v.Attrs {
+		if attr.Name.Space != "" || attr.Name.Local != xmlnsPrefix {
+			node.Attrs = append(node.Attrs, attr)
+		}
+	}
+	node.Extensions = append(node.Extensions, v.Extensions...)
+	return nil
+}
+
+// rspecExtensions holds the namespace declarations and the unknown elements of an RSpec,
+// and the unknown attributes and elements of some of its links, stored with the slivers.
+// As for the nodes, the RSpec namespace is declared with an attribute.
+type rspecExtensions struct {
+	XMLName    xml.Name         `xml:"rspec"`
+	Attrs      []xml.Attr       `xml:",any,attr"`
+	Links      []linkExtensions `xml:"link"`
+	Extensions []Extension      `xml:",any"`
+}
+
+type linkExtensions struct {
+	XMLName    xml.Name    `xml:"link"`
+	ClientID   string      `xml:"client_id,attr"`
+	Attrs      []xml.Attr  `xml:",any,attr"`
+	Extensions []Extension `xml:",any"`
+}
+
+// MarshalRspecExtensions returns the namespace declarations and the unknown elements of an RSpec,
+// and the unknown attributes and elements of its links of the given client IDs, as an XML rspec element.
+// It returns an empty string if there are none.
+func MarshalRspecExtensions(r Rspec, linkClientIDs []string) (string, error) {
+	s := scope{
+		defaultNamespace: RspecNamespace,
+		prefixes:         map[string]string{xmlPrefix: xmlNamespace},
+		generated:        new(int),
+	}
+	s, attrs := s.enter(NamespaceDeclarations(r.Attrs), true)
+	v := rspecExtensions{Extensions: s.resolveAll(r.Extensions)}
+	for _, link := range r.Links {
+		if len(link.Attrs) == 0 && len(link.Extensions) == 0 {
+			continue
+		}
+		for _, clientID := range linkClientIDs {
+			if clientID == link.ClientID {
+				link = link.resolve(s)
+				v.Links = append(v.Links, linkExtensions{
+					ClientID:   link.ClientID,
+					Attrs:      link.Attrs,
+					Extensions: link.Extensions,
+				})
+				break
+			}
+		}
+	}
+	if len(attrs) == 0 && len(v.Links) == 0 && len(v.Extensions) == 0 {
+		return "", nil
+	}
+	v.Attrs = append([]xml.Attr{{Name: xml.Name{Local: xmlnsPrefix}, Value: RspecNamespace}}, attrs...)
+	b, err := xml.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// UnmarshalRspecExtensions restores the declarations and elements returned by MarshalRspecExtensions in an RSpec,
+// e.g. a manifest built from several slivers. The declarations and the elements already present are skipped,
+// as well as the attributes and elements of the links which already have some.
+func UnmarshalRspecExtensions(s string, r *Rspec) error {
+	if s == "" {
+		return nil
+	}
+	v := rspecExtensions{}
+	err := xml.Unmarshal([]byte(s), &v)
+	if err != nil {
+		return err
+	}
+	for _, attr := range v.Attrs {
+		if attr.Name.Space == xmlnsPrefix && !containsAttr(r.Attrs, attr) {
+			r.Attrs = append(r.Attrs, attr)
+		}
+	}
+	for _, extension := range v.Extensions {
+		if !containsExtension(r.Extensions, extension) {
+			r.Extensions = append(r.Extensions, extension)
+		}
+	}
+	for i, link := range r.Links {
+		if len(link.Attrs) > 0 || len(link.Extensions) > 0 {
+			continue
+		}
+		for _, linkExtensions := range v.Links {
+			if linkExtensions.ClientID == link.ClientID {
+				for _, attr := range linkExtensions.Attrs {
+					if attr.Name.Space != "" || attr.Name.Local != xmlnsPrefix {
+						r.Links[i].Attrs = append(r.Links[i].Attrs, attr)
+					}
+				}
+				r.Links[i].Extensions = linkExtensions.Extensions
+			}
+		}
+	}
+	return nil
+}
+
+func containsAttr(attrs []xml.Attr, attr xml.Attr) bool {
+	for _, attr_ := range attrs {
+		if attr_ == attr {
+			return true
+		}
+	}
+	return false
+}
+
+func containsExtension(extensions []Extension, extension Extension) bool {
+	for _, extension_ := range extensions {
+		if reflect.DeepEqual(extension_, extension) {
+			return true
+		}
+	}
+	return false
+}
+
+func (n Node) resolve(s scope) Node {
+	s, n.Attrs = s.enter(n.Attrs, true)
+	n.Extensions = s.resolveAll(n.Extensions)
+	n.SliverType = n.SliverType.resolve(s)
+	interfaces := make([]Interface, len(n.Interfaces))
+	for i, interface_ := range n.Interfaces {
+		interfaces[i] = interface_.resolve(s)
+	}
+	n.Interfaces = interfaces
+	return n
+}
+
+func (t SliverType) resolve(s scope) SliverType {
+	s, t.Attrs = s.enter(t.Attrs, true)
+	t.Extensions = s.resolveAll(t.Extensions)
+	return t
+}
+
+func (i Interface) resolve(s scope) Interface {
+	s, i.Attrs = s.enter(i.Attrs, true)
+	i.Extensions = s.resolveAll(i.Extensions)
+	return i
+}
+
+func (l Link) resolve(s scope) Link {
+	s, l.Attrs = s.enter(l.Attrs, true)
+	l.Extensions = s.resolveAll(l.Extensions)
+	return l
+}
+
+// scope holds the namespaces bound in an element.
+type scope struct {
+	defaultNamespace string
+	// Namespaces indexed by prefix.
+	prefixes map[string]string
+	// Number of prefixes generated for the namespaces which are not declared, shared by all the scopes.
+	generated *int
+}
+
+// prefix returns the first prefix bound to a namespace.
+func (s scope) prefix(namespace string) (string, bool) {
+	prefixes := make([]string, 0)
+	for prefix, namespace_ := range s.prefixes {
+		if namespace_ == namespace {
+			prefixes = append(prefixes, prefix)
+		}
+	}
+	if len(prefixes) == 0 {
+		return "", false
+	}
+	sort.Strings(prefixes)
+	return prefixes[0], true
+}
+
+// enter returns the scope of an element declaring the given attributes, and the attributes with prefixed names.
+// The default namespace cannot be changed on the modeled elements, which are always in the RSpec namespace.
+func (s scope) enter(attrs []xml.Attr, modeled bool) (scope, []xml.Attr) {
+	child := scope{
+		defaultNamespace: s.defaultNamespace,
+		prefixes:         make(map[string]string),
+		generated:        s.generated,
+	}
+	for prefix, namespace := range s.prefixes {
+		child.prefixes[prefix] = namespace
+	}
+	declarations := make([]xml.Attr, 0)
+	others := make([]xml.Attr, 0)
+	for _, attr := range attrs {
+		switch {
+		case attr.Name.Space == xmlnsPrefix:
+			// Skip the declarations which are already in scope.
+			if s.prefixes[attr.Name.Local] != attr.Value {
+				child.prefixes[attr.Name.Local] = attr.Value
+				declarations = append(declarations, xml.Attr{
+					Name:  xml.Name{Local: xmlnsPrefix + ":" + attr.Name.Local},
+					Value: attr.Value,
+				})
+			}
+		case attr.Name.Space == "" && attr.Name.Local == xmlnsPrefix:
+			if !modeled && s.defaultNamespace != attr.Value {
+				child.defaultNamespace = attr.Value
+				declarations = append(declarations, attr)
+			}
+		default:
+			others = append(others, attr)
+		}
+	}
+	for _, attr := range others {
+		if attr.Name.Space != "" {
+			// Unprefixed attributes are not in the default namespace, so a prefix is always required.
+			prefix, ok := child.prefix(attr.Name.Space)
+			if !ok {
+				prefix = child.generatePrefix()
+				child.prefixes[prefix] = attr.Name.Space
+				declarations = append(declarations, xml.Attr{
+					Name:  xml.Name{Local: xmlnsPrefix + ":" + prefix},
+					Value: attr.Name.Space,
+				})
+			}
+			attr.Name = xml.Name{Local: prefix + ":" + attr.Name.Local}
+		}
+		declarations = append(declarations, attr)
+	}
+	return child, declarations
+}
+
+func (s scope) generatePrefix() string {
+	for {
+		*s.generated++
+		prefix := fmt.Sprintf("ns%d", *s.generated)
+		if _, ok := s.prefixes[prefix]; !ok {
+			return prefix
+		}
+	}
+}
+
+// resolve returns a copy of an extension with prefixed names, which encoding/xml writes as is.
+func (s scope) resolve(e Extension) Extension {
+	child, attrs := s.enter(e.Attrs, false)
+	name := e.XMLName.Local
+	if e.XMLName.Space != child.defaultNamespace {
+		if prefix, ok := child.prefix(e.XMLName.Space); ok {
+			name = prefix + ":" + name
+		} else {
+			child.defaultNamespace = e.XMLName.Space
+			attrs = append([]xml.Attr{{Name: xml.Name{Local: xmlnsPrefix}, Value: e.XMLName.Space}}, attrs...)
+		}
+	}
+	resolved := Extension{XMLName: xml.Name{Local: name}, Attrs: attrs}
+	// The whitespace between the child elements is not kept.
+	if strings.TrimSpace(e.Text) != "" {
+		resolved.Text = e.Text
+	}
+	resolved.Children = child.resolveAll(e.Children)
+	return resolved
+}
+
+func (s scope) resolveAll(extensions []Extension) []Extension {
+	if len(extensions) == 0 {
+		return nil
+	}
+	resolved := make([]Extension, len(extensions))
+	for i, extension := range extensions {
+		resolved[i] = s.resolve(extension)
+	}
+	return resolved
+}
@@ -12,10 +12,45 @@ const (
 	RspecLoginAuthenticationSSH = "ssh-keys"
 )
 
+const (
+	RspecPortProtocolTCP = "tcp"
+	RspecPortProtocolUDP = "udp"
+)
+
+const (
+	RspecIPTypeIPv4  = "ipv4"
+	RspecLinkTypeLAN = "lan"
+)
+
+// XML namespace of the EdgeNet RSpec extensions.
+const RspecExtensionEdgeNet = "http://www.edge-net.org/resources/rspec/ext/1"
+
+// XML namespace of the GENI operational state machine extension.
+const RspecExtensionOpstate = "http://www.geni.net/resources/rspec/ext/opstate/1"
+
+// Policies and topologies of the EdgeNet groups.
+const (
+	GroupPolicySpread     = "spread"
+	GroupPolicyBalance    = "balance"
+	GroupPolicyColocate   = "colocate"
+	GroupTopologyHostname = "hostname"
+	GroupTopologyCountry  = "country"
+)
+
+const (
+	OpstateWaitSuccess = "geni_success"
+	OpstateWaitFailure = "geni_failure"
+)
+
 type Rspec struct {
-	XMLName xml.Name `xml:"http://www.geni.net/resources/rspec/3 rspec"`
-	Type    string   `xml:"type,attr"`
-	Nodes   []Node   `xml:"node"`
+	XMLName    xml.Name    `xml:"http://www.geni.net/resources/rspec/3 rspec"`
+	Type       string      `xml:"type,attr"`
+	Attrs      []xml.Attr  `xml:",any,attr"`
+	Nodes      []Node      `xml:"node"`
+	Links      []Link      `xml:"link"`
+	Groups     []Group     `xml:"http://www.edge-net.org/resources/rspec/ext/1 group,omitempty"`
+	Opstate    *Opstate    `xml:"http://www.geni.net/resources/rspec/ext/opstate/1 rspec_opstate,omitempty"`
+	Extensions []Extension `xml:",any"`
 }
 
 type Node struct {
@@ -26,16 +61,31 @@ type Node struct {
 	ComponentName      string        `xml:"component_name,attr,omitempty"`
 	SliverID           string        `xml:"sliver_id,attr,omitempty"`
 	Exclusive          bool          `xml:"exclusive,attr"`
+	Count              string        `xml:"http://www.edge-net.org/resources/rspec/ext/1 count,attr,omitempty"`
+	Attrs              []xml.Attr    `xml:",any,attr"`
 	HardwareType       *HardwareType `xml:"hardware_type,omitempty"`
 	SliverType         SliverType    `xml:"sliver_type"`
+	Interfaces         []Interface   `xml:"interface"`
 	Services           *Services     `xml:"services,omitempty"`
 	Available          *Available    `xml:"available,omitempty"`
-	Location           *Location     `xml:"location,omitempty"`
+	Location           *Location     `xml:"http://www.geni.net/resources/rspec/3 location,omitempty"`
+	Resources          *Resources    `xml:"http://www.edge-net.org/resources/rspec/ext/1 resources,omitempty"`
+	Placement          *Placement    `xml:"http://www.edge-net.org/resources/rspec/ext/1 placement,omitempty"`
+	Ports              []Port        `xml:"http://www.edge-net.org/resources/rspec/ext/1 port,omitempty"`
+	Volumes            []Volume      `xml:"http://www.edge-net.org/resources/rspec/ext/1 volume,omitempty"`
+	Host               *Host         `xml:"http://www.edge-net.org/resources/rspec/ext/1 host,omitempty"`
+	Capacity           *Capacity     `xml:"http://www.edge-net.org/resources/rspec/ext/1 capacity,omitempty"`
+	Usage              *Usage        `xml:"http://www.edge-net.org/resources/rspec/ext/1 usage,omitempty"`
+	Extensions         []Extension   `xml:",any"`
 }
 
 type DiskImage struct {
-	XMLName xml.Name `xml:"disk_image"`
-	Name    string   `xml:"name,attr"`
+	XMLName     xml.Name `xml:"disk_image"`
+	Name        string   `xml:"name,attr,omitempty"`
+	URL         string   `xml:"url,attr,omitempty"`
+	OS          string   `xml:"os,attr,omitempty"`
+	Version     string   `xml:"version,attr,omitempty"`
+	Description string   `xml:"description,attr,omitempty"`
 }
 
 type HardwareType struct {
@@ -46,12 +96,70 @@ type HardwareType struct {
 type SliverType struct {
 	XMLName    xml.Name    `xml:"sliver_type"`
 	Name       string      `xml:"name,attr"`
+	Attrs      []xml.Attr  `xml:",any,attr"`
 	DiskImages []DiskImage `xml:"disk_image"`
+	Extensions []Extension `xml:",any"`
+}
+
+type Interface struct {
+	XMLName    xml.Name    `xml:"interface"`
+	ClientID   string      `xml:"client_id,attr"`
+	SliverID   string      `xml:"sliver_id,attr,omitempty"`
+	Attrs      []xml.Attr  `xml:",any,attr"`
+	IPs        []IP        `xml:"ip"`
+	Extensions []Extension `xml:",any"`
+}
+
+type IP struct {
+	XMLName xml.Name `xml:"ip"`
+	Address string   `xml:"address,attr"`
+	Netmask string   `xml:"netmask,attr,omitempty"`
+	Type    string   `xml:"type,attr,omitempty"`
+}
+
+type Link struct {
+	XMLName           xml.Name           `xml:"link"`
+	ClientID          string             `xml:"client_id,attr"`
+	SliverID          string             `xml:"sliver_id,attr,omitempty"`
+	Attrs             []xml.Attr         `xml:",any,attr"`
+	ComponentManagers []ComponentManager `xml:"component_manager"`
+	InterfaceRefs     []InterfaceRef     `xml:"interface_ref"`
+	LinkTypes         []LinkType         `xml:"link_type"`
+	Properties        []LinkProperty     `xml:"property"`
+	Extensions        []Extension        `xml:",any"`
+}
+
+type ComponentManager struct {
+	XMLName xml.Name `xml:"component_manager"`
+	Name    string   `xml:"name,attr"`
+}
+
+type InterfaceRef struct {
+	XMLName  xml.Name `xml:"interface_ref"`
+	ClientID string   `xml:"client_id,attr"`
+}
+
+// LinkProperty describes the traffic from an interface to another interface of a link.
+// The capacity is in kbps, the latency in ms, and the packet loss is a fraction between 0 and 1.
+type LinkProperty struct {
+	XMLName    xml.Name `xml:"property"`
+	SourceID   string   `xml:"source_id,attr"`
+	DestID     string   `xml:"dest_id,attr"`
+	Capacity   string   `xml:"capacity,attr,omitempty"`
+	Latency    string   `xml:"latency,attr,omitempty"`
+	PacketLoss string   `xml:"packet_loss,attr,omitempty"`
+}
+
+type LinkType struct {
+	XMLName xml.Name `xml:"link_type"`
+	Name    string   `xml:"name,attr"`
 }
 
 type Services struct {
-	XMLName xml.Name `xml:"services"`
-	Logins  []Login  `xml:"login"`
+	XMLName  xml.Name  `xml:"services"`
+	Logins   []Login   `xml:"login"`
+	Executes []Execute `xml:"execute"`
+	Installs []Install `xml:"install"`
 }
 
 type Login struct {
@@ -62,14 +170,167 @@ type Login struct {
 	Username       string   `xml:"username,attr"`
 }
 
+// Execute is a command run when the node starts.
+type Execute struct {
+	XMLName xml.Name `xml:"execute"`
+	Shell   string   `xml:"shell,attr"`
+	Command string   `xml:"command,attr"`
+}
+
+// Install is a file downloaded, and extracted if it is an archive, in a directory before the node starts.
+type Install struct {
+	XMLName     xml.Name `xml:"install"`
+	URL         string   `xml:"url,attr"`
+	InstallPath string   `xml:"install_path,attr"`
+}
+
 type Available struct {
 	XMLName xml.Name `xml:"available"`
 	Now     bool     `xml:"now,attr"`
 }
 
+// Location is the GENI location of a node.
+// Its namespace must be given in the Node field to distinguish it from the jFed location.
 type Location struct {
 	XMLName   xml.Name `xml:"location"`
 	Country   string   `xml:"country,attr"`
 	Latitude  string   `xml:"latitude,attr"`
 	Longitude string   `xml:"longitude,attr"`
 }
+
+// Resources requested for a node (EdgeNet extension), e.g. <edgenet:resources cpu="2" memory="4Gi"/>.
+// The values are Kubernetes quantities.
+type Resources struct {
+	XMLName xml.Name `xml:"http://www.edge-net.org/resources/rspec/ext/1 resources"`
+	CPU     string   `xml:"cpu,attr,omitempty"`
+	Memory  string   `xml:"memory,attr,omitempty"`
+}
+
+// Placement restricts the nodes on which a node can be allocated (EdgeNet extension), e.g.
+// <edgenet:placement country="DE,FR" latitude="48.85" longitude="2.35" radius="500"/>.
+// The country is a comma-separated list of ISO codes, and the radius around the coordinates is in km.
+// The labels must be among those allowed by the operator.
+type Placement struct {
+	XMLName   xml.Name         `xml:"http://www.edge-net.org/resources/rspec/ext/1 placement"`
+	Country   string           `xml:"country,attr,omitempty"`
+	Latitude  string           `xml:"latitude,attr,omitempty"`
+	Longitude string           `xml:"longitude,attr,omitempty"`
+	Radius    string           `xml:"radius,attr,omitempty"`
+	Labels    []PlacementLabel `xml:"http://www.edge-net.org/resources/rspec/ext/1 label"`
+}
+
+// PlacementLabel is a label that the nodes must have (EdgeNet extension).
+type PlacementLabel struct {
+	XMLName xml.Name `xml:"http://www.edge-net.org/resources/rspec/ext/1 label"`
+	Key     string   `xml:"key,attr"`
+	Value   string   `xml:"value,attr"`
+}
+
+// Port of a node exposed outside of the cluster (EdgeNet extension), e.g. <edgenet:port number="8080" protocol="tcp"/>.
+// The protocol is tcp (the default) or udp. In the manifests, the port is reachable at host:external_port.
+// A tcp port with ingress="true" is also served over HTTP(S) by an ingress, at the URL of the manifests.
+type Port struct {
+	XMLName      xml.Name `xml:"http://www.edge-net.org/resources/rspec/ext/1 port"`
+	Number       string   `xml:"number,attr"`
+	Protocol     string   `xml:"protocol,attr,omitempty"`
+	Ingress      bool     `xml:"ingress,attr,omitempty"`
+	Host         string   `xml:"host,attr,omitempty"`
+	ExternalPort int      `xml:"external_port,attr,omitempty"`
+	URL          string   `xml:"url,attr,omitempty"`
+}
+
+// Group of nodes of the request (EdgeNet extension), e.g.
+// <edgenet:group name="servers" policy="spread" topology="country"><edgenet:member client_id="PC1"/>...</edgenet:group>.
+// The spread policy places each member in a distinct topology domain, the balance policy spreads them
+// evenly across the domains, and the colocate policy places all of them in the same domain.
+// The topology is hostname (the default) or country.
+type Group struct {
+	XMLName  xml.Name      `xml:"http://www.edge-net.org/resources/rspec/ext/1 group"`
+	Name     string        `xml:"name,attr"`
+	Policy   string        `xml:"policy,attr"`
+	Topology string        `xml:"topology,attr,omitempty"`
+	Members  []GroupMember `xml:"http://www.edge-net.org/resources/rspec/ext/1 member"`
+}
+
+// GroupMember is a node of a group, by client ID (EdgeNet extension).
+type GroupMember struct {
+	XMLName  xml.Name `xml:"http://www.edge-net.org/resources/rspec/ext/1 member"`
+	ClientID string   `xml:"client_id,attr"`
+}
+
+// Persistent volume of a node (EdgeNet extension), e.g. <edgenet:volume name="data" size="1Gi" path="/data"/>.
+// The nodes of a slice with a shared="true" volume of the same name share it. A volume with retain="true"
+// outlives its sliver until the expiration of the slice, and is reused by a node of the same client_id.
+type Volume struct {
+	XMLName xml.Name `xml:"http://www.edge-net.org/resources/rspec/ext/1 volume"`
+	Name    string   `xml:"name,attr"`
+	Size    string   `xml:"size,attr"`
+	Path    string   `xml:"path,attr"`
+	Shared  bool     `xml:"shared,attr,omitempty"`
+	Retain  bool     `xml:"retain,attr,omitempty"`
+}
+
+// Host of a sliver in its slice (EdgeNet extension), in the manifests, e.g.
+// <edgenet:host name="pc1" fqdn="pc1.h0123456789abcdef.default.svc.cluster.local" address="10.244.0.5"/>.
+// The other slivers of the slice resolve the name to the address of the sliver.
+type Host struct {
+	XMLName xml.Name `xml:"http://www.edge-net.org/resources/rspec/ext/1 host"`
+	Name    string   `xml:"name,attr"`
+	FQDN    string   `xml:"fqdn,attr"`
+	Address string   `xml:"address,attr,omitempty"`
+}
+
+// Capacity of a node (EdgeNet extension), i.e. the resources allocatable to the containers.
+// The values are Kubernetes quantities.
+type Capacity struct {
+	XMLName          xml.Name `xml:"http://www.edge-net.org/resources/rspec/ext/1 capacity"`
+	CPU              string   `xml:"cpu,attr"`
+	Memory           string   `xml:"memory,attr"`
+	EphemeralStorage string   `xml:"ephemeral_storage,attr"`
+}
+
+// Usage of a node (EdgeNet extension), i.e. the resources requested by the containers scheduled on it.
+// The values are Kubernetes quantities.
+type Usage struct {
+	XMLName xml.Name `xml:"http://www.edge-net.org/resources/rspec/ext/1 usage"`
+	CPU     string   `xml:"cpu,attr"`
+	Memory  string   `xml:"memory,attr"`
+	Slivers int      `xml:"slivers,attr"`
+}
+
+// Opstate is the operational state machine of the slivers of the given types (opstate extension).
+// The actions of a state are those accepted by PerformOperationalAction,
+// and the waits are the transitions that happen without experimenter action.
+type Opstate struct {
+	XMLName            xml.Name            `xml:"http://www.geni.net/resources/rspec/ext/opstate/1 rspec_opstate"`
+	AggregateManagerID string              `xml:"aggregate_manager_id,attr"`
+	Start              string              `xml:"start,attr"`
+	SliverTypes        []OpstateSliverType `xml:"sliver_type"`
+	States             []OpstateState      `xml:"state"`
+}
+
+type OpstateSliverType struct {
+	XMLName xml.Name `xml:"sliver_type"`
+	Name    string   `xml:"name,attr"`
+}
+
+type OpstateState struct {
+	XMLName     xml.Name        `xml:"state"`
+	Name        string          `xml:"name,attr"`
+	Actions     []OpstateAction `xml:"action"`
+	Waits       []OpstateWait   `xml:"wait"`
+	Description string          `xml:"description"`
+}
+
+type OpstateAction struct {
+	XMLName     xml.Name `xml:"action"`
+	Name        string   `xml:"name,attr"`
+	Next        string   `xml:"next,attr"`
+	Description string   `xml:"description"`
+}
+
+type OpstateWait struct {
+	XMLName xml.Name `xml:"wait"`
+	Type    string   `xml:"type,attr"`
+	Next    string   `xml:"next,attr"`
+}
@@ -0,0 +1,315 @@
+package rspec
+
+import (
+	"encoding/xml"
+	"flag"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testRspecResources = `<rspec type="request" xmlns="http://www.geni.net/resources/rspec/3" xmlns:edgenet="http://www.edge-net.org/resources/rspec/ext/1">
+  <node client_id="PC1" exclusive="false">
+    <sliver_type name="container"/>
+    <edgenet:resources cpu="2" memory="4Gi"/>
+  </node>
+  <node client_id="PC2" exclusive="false">
+    <sliver_type name="container"/>
+  </node>
+</rspec>`
+
+func TestResources(t *testing.T) {
+	v := Rspec{}
+	err := xml.Unmarshal([]byte(testRspecResources), &v)
+	assert.Nil(t, err)
+	assert.Len(t, v.Nodes, 2)
+	assert.Equal(t, "2", v.Nodes[0].Resources.CPU)
+	assert.Equal(t, "4Gi", v.Nodes[0].Resources.Memory)
+	assert.Nil(t, v.Nodes[1].Resources)
+
+	// The extension must be marshaled in its own namespace.
+	b, err := xml.Marshal(v)
+	assert.Nil(t, err)
+	assert.Contains(
+		t,
+		string(b),
+		`<resources xmlns="http://www.edge-net.org/resources/rspec/ext/1" cpu="2" memory="4Gi">`,
+	)
+}
+
+const testRspecPlacement = `<rspec type="request" xmlns="http://www.geni.net/resources/rspec/3" xmlns:edgenet="http://www.edge-net.org/resources/rspec/ext/1">
+  <node client_id="PC1" exclusive="false">
+    <sliver_type name="container"/>
+    <edgenet:placement country="FR,BE" latitude="48.8566" longitude="2.3522" radius="50">
+      <edgenet:label key="example.org/gpu" value="true"/>
+    </edgenet:placement>
+  </node>
+</rspec>`
+
+func TestPlacement(t *testing.T) {
+	v := Rspec{}
+	err := xml.Unmarshal([]byte(testRspecPlacement), &v)
+	assert.Nil(t, err)
+	placement := v.Nodes[0].Placement
+	assert.Equal(t, "FR,BE", placement.Country)
+	assert.Equal(t, "48.8566", placement.Latitude)
+	assert.Equal(t, "2.3522", placement.Longitude)
+	assert.Equal(t, "50", placement.Radius)
+	assert.Len(t, placement.Labels, 1)
+	assert.Equal(t, "example.org/gpu", placement.Labels[0].Key)
+	assert.Equal(t, "true", placement.Labels[0].Value)
+}
+
+const testRspecGroups = `<rspec type="request" xmlns="http://www.geni.net/resources/rspec/3" xmlns:edgenet="http://www.edge-net.org/resources/rspec/ext/1">
+  <node client_id="PC1" exclusive="false">
+    <sliver_type name="container"/>
+  </node>
+  <node client_id="PC2" exclusive="false">
+    <sliver_type name="container"/>
+  </node>
+  <edgenet:group name="servers" policy="spread" topology="country">
+    <edgenet:member client_id="PC1"/>
+    <edgenet:member client_id="PC2"/>
+  </edgenet:group>
+</rspec>`
+
+func TestGroups(t *testing.T) {
+	v := Rspec{}
+	err := xml.Unmarshal([]byte(testRspecGroups), &v)
+	assert.Nil(t, err)
+	assert.Len(t, v.Groups, 1)
+	assert.Equal(t, "servers", v.Groups[0].Name)
+	assert.Equal(t, GroupPolicySpread, v.Groups[0].Policy)
+	assert.Equal(t, GroupTopologyCountry, v.Groups[0].Topology)
+	assert.Len(t, v.Groups[0].Members, 2)
+	assert.Equal(t, "PC2", v.Groups[0].Members[1].ClientID)
+	// The groups must not be kept as unknown extensions.
+	assert.Len(t, v.Extensions, 0)
+}
+
+const testRspecVolumes = `<rspec type="request" xmlns="http://www.geni.net/resources/rspec/3" xmlns:edgenet="http://www.edge-net.org/resources/rspec/ext/1">
+  <node client_id="PC1" exclusive="false">
+    <sliver_type name="container"/>
+    <edgenet:volume name="data" size="1Gi" path="/data"/>
+    <edgenet:volume name="shared" size="5Gi" path="/shared" shared="true" retain="true"/>
+  </node>
+</rspec>`
+
+func TestVolumes(t *testing.T) {
+	v := Rspec{}
+	err := xml.Unmarshal([]byte(testRspecVolumes), &v)
+	assert.Nil(t, err)
+	volumes := v.Nodes[0].Volumes
+	assert.Len(t, volumes, 2)
+	assert.Equal(t, "data", volumes[0].Name)
+	assert.Equal(t, "1Gi", volumes[0].Size)
+	assert.Equal(t, "/data", volumes[0].Path)
+	assert.False(t, volumes[0].Shared)
+	assert.True(t, volumes[1].Shared)
+	assert.True(t, volumes[1].Retain)
+	assert.Len(t, v.Nodes[0].Extensions, 0)
+}
+
+func TestNodeCount(t *testing.T) {
+	v := Rspec{}
+	err := xml.Unmarshal([]byte(strings.Replace(
+		testRspecResources,
+		`<node client_id="PC2"`,
+		`<node client_id="PC2" edgenet:count="100"`,
+		1,
+	)), &v)
+	assert.Nil(t, err)
+	assert.Equal(t, "", v.Nodes[0].Count)
+	assert.Equal(t, "100", v.Nodes[1].Count)
+	// The count must not be kept as an unknown attribute.
+	assert.Len(t, v.Nodes[1].Attrs, 0)
+}
+
+const testRspecLink = `<rspec type="request" xmlns="http://www.geni.net/resources/rspec/3">
+  <node client_id="PC1" exclusive="false">
+    <sliver_type name="container"/>
+    <interface client_id="PC1:if0">
+      <ip address="192.168.0.1" netmask="255.255.255.0" type="ipv4"/>
+    </interface>
+  </node>
+  <node client_id="PC2" exclusive="false">
+    <sliver_type name="container"/>
+    <interface client_id="PC2:if0"/>
+  </node>
+  <link client_id="link0">
+    <component_manager name="urn:publicid:IDN+example.org+authority+am"/>
+    <interface_ref client_id="PC1:if0"/>
+    <interface_ref client_id="PC2:if0"/>
+    <link_type name="lan"/>
+    <property source_id="PC1:if0" dest_id="PC2:if0" capacity="10000" latency="20" packet_loss="0.01"/>
+  </link>
+</rspec>`
+
+func TestLinks(t *testing.T) {
+	v := Rspec{}
+	err := xml.Unmarshal([]byte(testRspecLink), &v)
+	assert.Nil(t, err)
+	assert.Len(t, v.Nodes, 2)
+	assert.Equal(t, "PC1:if0", v.Nodes[0].Interfaces[0].ClientID)
+	assert.Equal(t, "192.168.0.1", v.Nodes[0].Interfaces[0].IPs[0].Address)
+	assert.Len(t, v.Nodes[1].Interfaces[0].IPs, 0)
+	assert.Len(t, v.Links, 1)
+	assert.Equal(t, "link0", v.Links[0].ClientID)
+	assert.Len(t, v.Links[0].InterfaceRefs, 2)
+	assert.Equal(t, RspecLinkTypeLAN, v.Links[0].LinkTypes[0].Name)
+	assert.Equal(t, LinkProperty{
+		XMLName:    xml.Name{Space: "http://www.geni.net/resources/rspec/3", Local: "property"},
+		SourceID:   "PC1:if0",
+		DestID:     "PC2:if0",
+		Capacity:   "10000",
+		Latency:    "20",
+		PacketLoss: "0.01",
+	}, v.Links[0].Properties[0])
+}
+
+const testRspecServices = `<rspec type="request" xmlns="http://www.geni.net/resources/rspec/3">
+  <node client_id="PC1" exclusive="false">
+    <sliver_type name="container"/>
+    <services>
+      <install url="https://example.org/tools.tar.gz" install_path="/local"/>
+      <execute shell="sh" command="/local/tools/setup.sh"/>
+    </services>
+  </node>
+</rspec>`
+
+func TestServices(t *testing.T) {
+	v := Rspec{}
+	err := xml.Unmarshal([]byte(testRspecServices), &v)
+	assert.Nil(t, err)
+	services := v.Nodes[0].Services
+	assert.Len(t, services.Logins, 0)
+	assert.Len(t, services.Installs, 1)
+	assert.Equal(t, "https://example.org/tools.tar.gz", services.Installs[0].URL)
+	assert.Equal(t, "/local", services.Installs[0].InstallPath)
+	assert.Len(t, services.Executes, 1)
+	assert.Equal(t, "sh", services.Executes[0].Shell)
+	assert.Equal(t, "/local/tools/setup.sh", services.Executes[0].Command)
+}
+
+var update = flag.Bool("update", false, "update the golden files")
+
+// TestGolden checks that the unknown elements and the namespace declarations of real jFed RSpecs are preserved.
+func TestGolden(t *testing.T) {
+	inputs, err := filepath.Glob(filepath.Join("testdata", "*.xml"))
+	assert.Nil(t, err)
+	assert.NotEmpty(t, inputs)
+	for _, input := range inputs {
+		b, err := ioutil.ReadFile(input)
+		assert.Nil(t, err)
+		v := Rspec{}
+		err = xml.Unmarshal(b, &v)
+		assert.Nil(t, err)
+		v.Attrs = NamespaceDeclarations(v.Attrs)
+		output, err := xml.MarshalIndent(v, "", "  ")
+		assert.Nil(t, err)
+		golden := strings.TrimSuffix(input, ".xml") + ".golden"
+		if *update {
+			err = ioutil.WriteFile(golden, output, 0644)
+			assert.Nil(t, err)
+		}
+		expected, err := ioutil.ReadFile(golden)
+		assert.Nil(t, err)
+		assert.Equal(t, string(expected), string(output), input)
+
+		// The output must be equivalent to the input once parsed again.
+		w := Rspec{}
+		err = xml.Unmarshal(output, &w)
+		assert.Nil(t, err)
+		assert.Equal(t, v.Attrs, NamespaceDeclarations(w.Attrs), input)
+		for i := range v.Nodes {
+			assert.Equal(t, v.Nodes[i].Extensions, w.Nodes[i].Extensions, input)
+		}
+		for i := range v.Links {
+			assert.Equal(t, v.Links[i].Extensions, w.Links[i].Extensions, input)
+		}
+	}
+}
+
+func TestNodeExtensions(t *testing.T) {
+	b, err := ioutil.ReadFile(filepath.Join("testdata", "jfed_lan.xml"))
+	assert.Nil(t, err)
+	v := Rspec{}
+	err = xml.Unmarshal(b, &v)
+	assert.Nil(t, err)
+	s, err := MarshalNodeExtensions(v, v.Nodes[0])
+	assert.Nil(t, err)
+	assert.Contains(t, s, `xmlns:emulab="http://www.protogeni.net/resources/rspec/ext/emulab/1"`)
+	assert.Contains(t, s, `<emulab:routable_control_ip></emulab:routable_control_ip>`)
+
+	node := Node{ClientID: "node0"}
+	err = UnmarshalNodeExtensions(s, &node)
+	assert.Nil(t, err)
+	assert.Equal(t, v.Nodes[0].Extensions, node.Extensions)
+	// The namespaces are declared on the node, unless the RSpec already declares them.
+	output, err := xml.Marshal(Rspec{Nodes: []Node{node}})
+	assert.Nil(t, err)
+	assert.Contains(t, string(output), `<node client_id="node0" exclusive="false" xmlns:emulab=`)
+	output, err = xml.Marshal(Rspec{Attrs: NamespaceDeclarations(v.Attrs), Nodes: []Node{node}})
+	assert.Nil(t, err)
+	assert.Contains(t, string(output), `<node client_id="node0" exclusive="false"><sliver_type`)
+
+	s, err = MarshalNodeExtensions(v, Node{})
+	assert.Nil(t, err)
+	assert.Equal(t, "", s)
+}
+
+func TestRspecExtensions(t *testing.T) {
+	b, err := ioutil.ReadFile(filepath.Join("testdata", "jfed_lan.xml"))
+	assert.Nil(t, err)
+	v := Rspec{}
+	err = xml.Unmarshal(b, &v)
+	assert.Nil(t, err)
+	s, err := MarshalRspecExtensions(v, []string{"link0"})
+	assert.Nil(t, err)
+	assert.Contains(t, s, `xmlns:jfed="http://jfed.iminds.be/rspec/ext/jfed/1"`)
+	assert.Contains(t, s, `<jfed:link_layout><jfed:point x="200.0" y="120.0"></jfed:point></jfed:link_layout>`)
+
+	// The declarations and the elements are restored once, even from several slivers.
+	manifest := Rspec{Links: []Link{{ClientID: "link0"}}}
+	for i := 0; i < 2; i++ {
+		err = UnmarshalRspecExtensions(s, &manifest)
+		assert.Nil(t, err)
+	}
+	assert.Equal(t, NamespaceDeclarations(v.Attrs), manifest.Attrs)
+	assert.Len(t, manifest.Links[0].Extensions, 1)
+	assert.Equal(t, v.Links[0].Extensions[0].XMLName, manifest.Links[0].Extensions[0].XMLName)
+	output, err := xml.Marshal(manifest)
+	assert.Nil(t, err)
+	assert.Contains(t, string(output), `<jfed:link_layout><jfed:point x="200.0" y="120.0"></jfed:point></jfed:link_layout>`)
+
+	// The links of other slivers are not stored.
+	s, err = MarshalRspecExtensions(v, []string{"link1"})
+	assert.Nil(t, err)
+	assert.NotContains(t, s, "link_layout")
+	s, err = MarshalRspecExtensions(Rspec{}, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "", s)
+}
+
+func TestExtensionsUndeclaredNamespace(t *testing.T) {
+	v := Rspec{Nodes: []Node{{
+		ClientID: "node0",
+		Attrs:    []xml.Attr{{Name: xml.Name{Space: "urn:a", Local: "foo"}, Value: "bar"}},
+		Extensions: []Extension{{
+			XMLName:  xml.Name{Space: "urn:b", Local: "hint"},
+			Text:     "text",
+			Children: []Extension{{XMLName: xml.Name{Space: "urn:b", Local: "child"}}},
+		}},
+	}}}
+	output, err := xml.Marshal(v)
+	assert.Nil(t, err)
+	assert.Contains(t, string(output), `xmlns:ns1="urn:a" ns1:foo="bar"`)
+	assert.Contains(t, string(output), `<hint xmlns="urn:b">text<child></child></hint>`)
+	w := Rspec{}
+	err = xml.Unmarshal(output, &w)
+	assert.Nil(t, err)
+	assert.Equal(t, v.Nodes[0].Attrs, w.Nodes[0].Attrs[1:])
+	assert.Equal(t, v.Nodes[0].Extensions[0].Children, w.Nodes[0].Extensions[0].Children)
+}
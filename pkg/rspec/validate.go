@@ -0,0 +1,246 @@
+package rspec
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ValidationError is a problem found in an RSpec, with its position in the document.
+type ValidationError struct {
+	Line    int
+	Column  int
+	Message string
+}
+
+func (e ValidationError) Error() string {
+	return fmt.Sprintf("line %d, column %d: %s", e.Line, e.Column, e.Message)
+}
+
+// elementSchema describes an element of the GENI and EdgeNet namespaces in a request RSpec.
+type elementSchema struct {
+	// Attributes which must be present.
+	required []string
+	// Attributes which must be xs:boolean values.
+	booleans []string
+	// Child elements of the GENI and EdgeNet namespaces allowed in the element.
+	children []xml.Name
+	// Child elements allowed at most once in the element.
+	unique []xml.Name
+}
+
+func geni(local string) xml.Name {
+	return xml.Name{Space: RspecNamespace, Local: local}
+}
+
+func edgenet(local string) xml.Name {
+	return xml.Name{Space: RspecExtensionEdgeNet, Local: local}
+}
+
+// requestSchema lists the elements of a request RSpec, following the GENI RSpec version 3 request schema
+// of the http://www.geni.net/resources/rspec/3 namespace, published at
+// http://www.geni.net/resources/rspec/3/request.xsd, and the EdgeNet extension.
+// As in the schema, the elements and attributes of other namespaces are allowed anywhere.
+var requestSchema = map[xml.Name]elementSchema{
+	geni("rspec"): {
+		children: []xml.Name{geni("node"), geni("link"), edgenet("group")},
+	},
+	geni("node"): {
+		required: []string{"client_id"},
+		booleans: []string{"exclusive"},
+		children: []xml.Name{
+			geni("relation"),
+			geni("location"),
+			geni("sliver_type"),
+			geni("services"),
+			geni("interface"),
+			geni("hardware_type"),
+			edgenet("resources"),
+			edgenet("placement"),
+			edgenet("port"),
+			edgenet("volume"),
+		},
+		unique: []xml.Name{
+			geni("sliver_type"),
+			geni("services"),
+			geni("location"),
+			edgenet("resources"),
+			edgenet("placement"),
+		},
+	},
+	geni("relation"):      {required: []string{"type"}},
+	geni("location"):      {},
+	geni("hardware_type"): {required: []string{"name"}},
+	geni("sliver_type"): {
+		required: []string{"name"},
+		children: []xml.Name{geni("disk_image")},
+	},
+	geni("disk_image"): {},
+	geni("services"): {
+		children: []xml.Name{geni("login"), geni("install"), geni("execute")},
+	},
+	geni("login"):   {required: []string{"authentication"}},
+	geni("install"): {required: []string{"url", "install_path"}},
+	geni("execute"): {required: []string{"shell", "command"}},
+	geni("interface"): {
+		required: []string{"client_id"},
+		children: []xml.Name{geni("ip")},
+	},
+	geni("ip"): {required: []string{"address"}},
+	geni("link"): {
+		required: []string{"client_id"},
+		children: []xml.Name{
+			geni("component_manager"),
+			geni("interface_ref"),
+			geni("property"),
+			geni("link_type"),
+		},
+	},
+	geni("component_manager"): {required: []string{"name"}},
+	geni("interface_ref"):     {required: []string{"client_id"}},
+	geni("property"):          {required: []string{"source_id", "dest_id"}},
+	geni("link_type"):         {required: []string{"name"}},
+	edgenet("resources"):      {},
+	edgenet("placement"): {
+		children: []xml.Name{edgenet("label")},
+	},
+	edgenet("label"): {required: []string{"key", "value"}},
+	edgenet("port"): {
+		required: []string{"number"},
+		booleans: []string{"ingress"},
+	},
+	edgenet("volume"): {
+		required: []string{"name", "size", "path"},
+		booleans: []string{"shared", "retain"},
+	},
+	edgenet("group"): {
+		required: []string{"name", "policy"},
+		children: []xml.Name{edgenet("member")},
+	},
+	edgenet("member"): {required: []string{"client_id"}},
+}
+
+// ValidateRequest checks that a document is a request RSpec with the structure of the GENI v3 request schema.
+// It returns a ValidationError locating the first problem.
+func ValidateRequest(b []byte) error {
+	decoder := xml.NewDecoder(bytes.NewReader(b))
+	position := func(offset int64) (int, int) {
+		line := 1 + bytes.Count(b[:offset], []byte("\n"))
+		column := int(offset) - bytes.LastIndexByte(b[:offset], '\n')
+		return line, column
+	}
+	type frame struct {
+		name   xml.Name
+		schema *elementSchema
+		seen   map[xml.Name]bool
+	}
+	stack := make([]frame, 0)
+	clientIDs := make(map[string]bool)
+	root := false
+	for {
+		offset := decoder.InputOffset()
+		token, err := decoder.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			line, column := position(decoder.InputOffset())
+			message := err.Error()
+			var syntaxError *xml.SyntaxError
+			if errors.As(err, &syntaxError) {
+				message = syntaxError.Msg
+			}
+			return ValidationError{line, column, message}
+		}
+		switch token := token.(type) {
+		case xml.StartElement:
+			line, column := position(offset)
+			fail := func(format string, a ...interface{}) error {
+				return ValidationError{line, column, fmt.Sprintf(format, a...)}
+			}
+			if len(stack) == 0 {
+				if root {
+					return fail("only one root element is allowed")
+				}
+				root = true
+				if token.Name != geni("rspec") {
+					return fail("root element must be rspec in namespace %s", RspecNamespace)
+				}
+				for _, attr := range token.Attr {
+					if attr.Name.Space == "" && attr.Name.Local == "type" && attr.Value != RspecTypeRequest {
+						return fail("rspec type must be %s, not %s", RspecTypeRequest, attr.Value)
+					}
+				}
+			}
+			var schema *elementSchema
+			if token.Name.Space == RspecNamespace || token.Name.Space == RspecExtensionEdgeNet {
+				s, ok := requestSchema[token.Name]
+				if !ok {
+					return fail("unknown element %s in namespace %s", token.Name.Local, token.Name.Space)
+				}
+				schema = &s
+			}
+			if len(stack) > 0 {
+				parent := stack[len(stack)-1]
+				if parent.schema != nil && schema != nil {
+					if !containsName(parent.schema.children, token.Name) {
+						return fail("element %s is not allowed in %s", token.Name.Local, parent.name.Local)
+					}
+					if parent.seen[token.Name] && containsName(parent.schema.unique, token.Name) {
+						return fail("element %s is allowed only once in %s", token.Name.Local, parent.name.Local)
+					}
+					parent.seen[token.Name] = true
+				}
+			}
+			if schema != nil {
+				attrs := make(map[string]string)
+				for _, attr := range token.Attr {
+					if attr.Name.Space == "" {
+						attrs[attr.Name.Local] = attr.Value
+					}
+				}
+				for _, name := range schema.required {
+					if _, ok := attrs[name]; !ok {
+						return fail("element %s requires attribute %s", token.Name.Local, name)
+					}
+				}
+				for _, name := range schema.booleans {
+					switch value, ok := attrs[name]; {
+					case !ok, value == "true", value == "false", value == "1", value == "0":
+					default:
+						return fail("attribute %s of element %s must be a boolean, not %s", name, token.Name.Local, value)
+					}
+				}
+				if token.Name == geni("node") {
+					if clientIDs[attrs["client_id"]] {
+						return fail("duplicate node client_id %s", attrs["client_id"])
+					}
+					clientIDs[attrs["client_id"]] = true
+				}
+			}
+			stack = append(stack, frame{
+				name:   token.Name,
+				schema: schema,
+				seen:   make(map[xml.Name]bool),
+			})
+		case xml.EndElement:
+			stack = stack[:len(stack)-1]
+		}
+	}
+	if !root {
+		line, column := position(int64(len(b)))
+		return ValidationError{line, column, "missing rspec element"}
+	}
+	return nil
+}
+
+func containsName(names []xml.Name, name xml.Name) bool {
+	for _, name_ := range names {
+		if name_ == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,110 @@
+package rspec
+
+import (
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+func TestValidateRequest(t *testing.T) {
+	for _, s := range []string{
+		testRspecResources,
+		testRspecLink,
+		testRspecServices,
+		testRspecPlacement,
+		testRspecGroups,
+		testRspecVolumes,
+	} {
+		assert.Nil(t, ValidateRequest([]byte(s)))
+	}
+}
+
+// The request RSpecs exported by the jFed RSpec editor must be accepted as is.
+func TestValidateRequest_JFed(t *testing.T) {
+	inputs, err := filepath.Glob(filepath.Join("testdata", "jfed_*.xml"))
+	assert.Nil(t, err)
+	assert.NotEmpty(t, inputs)
+	for _, input := range inputs {
+		b, err := ioutil.ReadFile(input)
+		assert.Nil(t, err)
+		assert.Nil(t, ValidateRequest(b), input)
+	}
+}
+
+func TestValidateRequest_Invalid(t *testing.T) {
+	tests := []struct {
+		rspec string
+		err   ValidationError
+	}{
+		{
+			"",
+			ValidationError{1, 1, "missing rspec element"},
+		},
+		{
+			`<rspec type="request">`,
+			ValidationError{1, 1, "root element must be rspec in namespace http://www.geni.net/resources/rspec/3"},
+		},
+		{
+			`<rspec type="manifest" xmlns="http://www.geni.net/resources/rspec/3"/>`,
+			ValidationError{1, 1, "rspec type must be request, not manifest"},
+		},
+		{
+			`<rspec type="request" xmlns="http://www.geni.net/resources/rspec/3">
+  <node exclusive="false"/>
+</rspec>`,
+			ValidationError{2, 3, "element node requires attribute client_id"},
+		},
+		{
+			`<rspec type="request" xmlns="http://www.geni.net/resources/rspec/3">
+  <node client_id="PC1" exclusive="no"/>
+</rspec>`,
+			ValidationError{2, 3, "attribute exclusive of element node must be a boolean, not no"},
+		},
+		{
+			`<rspec type="request" xmlns="http://www.geni.net/resources/rspec/3">
+  <node client_id="PC1"/>
+  <node client_id="PC1"/>
+</rspec>`,
+			ValidationError{3, 3, "duplicate node client_id PC1"},
+		},
+		{
+			`<rspec type="request" xmlns="http://www.geni.net/resources/rspec/3">
+  <node client_id="PC1">
+    <sliver_type name="container"/>
+    <sliver_type name="container"/>
+  </node>
+</rspec>`,
+			ValidationError{4, 5, "element sliver_type is allowed only once in node"},
+		},
+		{
+			`<rspec type="request" xmlns="http://www.geni.net/resources/rspec/3">
+  <link client_id="link0">
+    <interface client_id="PC1:if0"/>
+  </link>
+</rspec>`,
+			ValidationError{3, 5, "element interface is not allowed in link"},
+		},
+		{
+			`<rspec type="request" xmlns="http://www.geni.net/resources/rspec/3" xmlns:edgenet="http://www.edge-net.org/resources/rspec/ext/1">
+  <node client_id="PC1">
+    <edgenet:usage cpu="1" memory="1Gi" slivers="1"/>
+  </node>
+</rspec>`,
+			ValidationError{
+				3,
+				5,
+				"unknown element usage in namespace http://www.edge-net.org/resources/rspec/ext/1",
+			},
+		},
+		{
+			`<rspec type="request" xmlns="http://www.geni.net/resources/rspec/3">
+  <node client_id="PC1">
+</rspec>`,
+			ValidationError{3, 9, "element <node> closed by </rspec>"},
+		},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.err, ValidateRequest([]byte(test.rspec)), test.rspec)
+	}
+}
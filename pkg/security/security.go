@@ -0,0 +1,112 @@
+// Package security defines the security profiles applied to the experimenter containers.
+// The profiles follow the Kubernetes Pod Security Standards:
+// https://kubernetes.io/docs/concepts/security/pod-security-standards/
+package security
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/utils/pointer"
+)
+
+type Profile string
+
+const (
+	// Unrestricted profile, the container runs as root with the default capabilities.
+	ProfilePrivileged Profile = "privileged"
+	// The container runs as root with a minimal set of capabilities required by sshd.
+	ProfileBaseline Profile = "baseline"
+	// The container runs as an unprivileged user without any capability.
+	ProfileRestricted Profile = "restricted"
+)
+
+// Capabilities kept by the baseline profile, which are required to run sshd as root.
+var baselineCapabilities = []corev1.Capability{
+	"AUDIT_WRITE",
+	"CHOWN",
+	"DAC_OVERRIDE",
+	"FOWNER",
+	"KILL",
+	"NET_BIND_SERVICE",
+	"SETGID",
+	"SETUID",
+	"SYS_CHROOT",
+}
+
+// User under which the restricted containers run.
+const restrictedUID = 1000
+
+func Parse(s string) (Profile, error) {
+	switch p := Profile(s); p {
+	case ProfilePrivileged, ProfileBaseline, ProfileRestricted:
+		return p, nil
+	default:
+		return "", fmt.Errorf(
+			"profile must be one of %s, %s or %s",
+			ProfilePrivileged,
+			ProfileBaseline,
+			ProfileRestricted,
+		)
+	}
+}
+
+// SupportsSshd returns false if the profile cannot run sshd from the default image, images/openssh-server.dockerfile.
+// There, sshd runs as root, reads root-owned host keys and binds to port 22, and only root can log in,
+// which is not possible under the restricted profile.
+func (p Profile) SupportsSshd() bool {
+	return p != ProfileRestricted
+}
+
+// PodSecurityContext returns the security context applied to all the containers of a pod.
+func (p Profile) PodSecurityContext() *corev1.PodSecurityContext {
+	switch p {
+	case ProfileBaseline:
+		return &corev1.PodSecurityContext{
+			SeccompProfile: &corev1.SeccompProfile{
+				Type: corev1.SeccompProfileTypeRuntimeDefault,
+			},
+		}
+	case ProfileRestricted:
+		return &corev1.PodSecurityContext{
+			RunAsNonRoot: pointer.BoolPtr(true),
+			RunAsUser:    pointer.Int64Ptr(restrictedUID),
+			RunAsGroup:   pointer.Int64Ptr(restrictedUID),
+			FSGroup:      pointer.Int64Ptr(restrictedUID),
+			SeccompProfile: &corev1.SeccompProfile{
+				Type: corev1.SeccompProfileTypeRuntimeDefault,
+			},
+		}
+	default:
+		return nil
+	}
+}
+
+// SecurityContext returns the security context of a container.
+func (p Profile) SecurityContext(readOnlyRootFilesystem bool) *corev1.SecurityContext {
+	var securityContext *corev1.SecurityContext
+	switch p {
+	case ProfileBaseline:
+		securityContext = &corev1.SecurityContext{
+			AllowPrivilegeEscalation: pointer.BoolPtr(false),
+			Capabilities: &corev1.Capabilities{
+				Add:  baselineCapabilities,
+				Drop: []corev1.Capability{"ALL"},
+			},
+		}
+	case ProfileRestricted:
+		securityContext = &corev1.SecurityContext{
+			AllowPrivilegeEscalation: pointer.BoolPtr(false),
+			Capabilities: &corev1.Capabilities{
+				Drop: []corev1.Capability{"ALL"},
+			},
+		}
+	}
+	if readOnlyRootFilesystem {
+		if securityContext == nil {
+			securityContext = &corev1.SecurityContext{}
+		}
+		securityContext.ReadOnlyRootFilesystem = pointer.BoolPtr(true)
+	}
+	return securityContext
+}
@@ -0,0 +1,56 @@
+package security
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestParse(t *testing.T) {
+	for _, s := range []string{"privileged", "baseline", "restricted"} {
+		p, err := Parse(s)
+		assert.Nil(t, err)
+		assert.Equal(t, Profile(s), p)
+	}
+	_, err := Parse("invalid")
+	assert.NotNil(t, err)
+}
+
+func TestProfilePrivileged(t *testing.T) {
+	assert.Nil(t, ProfilePrivileged.PodSecurityContext())
+	assert.Nil(t, ProfilePrivileged.SecurityContext(false))
+	assert.True(t, *ProfilePrivileged.SecurityContext(true).ReadOnlyRootFilesystem)
+}
+
+func TestProfileBaseline(t *testing.T) {
+	podSecurityContext := ProfileBaseline.PodSecurityContext()
+	assert.Equal(
+		t,
+		corev1.SeccompProfileTypeRuntimeDefault,
+		podSecurityContext.SeccompProfile.Type,
+	)
+	assert.Nil(t, podSecurityContext.RunAsNonRoot)
+	securityContext := ProfileBaseline.SecurityContext(false)
+	assert.False(t, *securityContext.AllowPrivilegeEscalation)
+	assert.Equal(t, []corev1.Capability{"ALL"}, securityContext.Capabilities.Drop)
+	assert.Contains(t, securityContext.Capabilities.Add, corev1.Capability("SETUID"))
+	assert.Nil(t, securityContext.ReadOnlyRootFilesystem)
+}
+
+func TestProfileRestricted(t *testing.T) {
+	podSecurityContext := ProfileRestricted.PodSecurityContext()
+	assert.True(t, *podSecurityContext.RunAsNonRoot)
+	assert.NotEqual(t, int64(0), *podSecurityContext.RunAsUser)
+	securityContext := ProfileRestricted.SecurityContext(true)
+	assert.False(t, *securityContext.AllowPrivilegeEscalation)
+	assert.Equal(t, []corev1.Capability{"ALL"}, securityContext.Capabilities.Drop)
+	assert.Empty(t, securityContext.Capabilities.Add)
+	assert.True(t, *securityContext.ReadOnlyRootFilesystem)
+}
+
+func TestSupportsSshd(t *testing.T) {
+	assert.True(t, ProfilePrivileged.SupportsSshd())
+	assert.True(t, ProfileBaseline.SupportsSshd())
+	assert.False(t, ProfileRestricted.SupportsSshd())
+}
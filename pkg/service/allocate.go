@@ -1,13 +1,15 @@
 package service
 
 import (
+	"context"
 	"encoding/xml"
 	"fmt"
 	v1 "github.com/EdgeNet-project/fed4fire/pkg/apis/fed4fire/v1"
 	"github.com/EdgeNet-project/fed4fire/pkg/constants"
 	"github.com/EdgeNet-project/fed4fire/pkg/utils"
-	"html"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
 	"net/http"
 	"time"
 
@@ -37,9 +39,14 @@ type AllocateReply struct {
 	}
 }
 
-func (v *AllocateReply) SetAndLogError(err error, msg string, keysAndValues ...interface{}) error {
+func (v *AllocateReply) SetAndLogError(
+	err error,
+	msg string,
+	code int,
+	keysAndValues ...interface{},
+) error {
 	klog.ErrorSDepth(1, err, msg, keysAndValues)
-	v.Data.Code.Code = constants.GeniCodeError
+	v.Data.Code.Code = code
 	v.Data.Output = fmt.Sprintf("%s: %s", msg, err)
 	return nil
 }
@@ -51,43 +58,167 @@ func (v *AllocateReply) SetAndLogError(err error, msg string, keysAndValues ...i
 func (s *Service) Allocate(r *http.Request, args *AllocateArgs, reply *AllocateReply) error {
 	userIdentifier, err := identifiers.Parse(r.Header.Get(constants.HttpHeaderUser))
 	if err != nil {
-		return reply.SetAndLogError(err, constants.ErrorBadIdentifier)
+		return reply.SetAndLogError(err, constants.ErrorBadIdentifier, constants.GeniCodeError)
 	}
 	sliceIdentifier, err := identifiers.Parse(args.SliceURN)
 	if err != nil {
-		return reply.SetAndLogError(err, constants.ErrorBadIdentifier)
+		return reply.SetAndLogError(err, constants.ErrorBadIdentifier, constants.GeniCodeError)
 	}
-	_, err = FindCredential(
+	credential, err := FindCredential(
 		*userIdentifier,
 		sliceIdentifier,
 		args.Credentials,
 		s.TrustedCertificates,
 	)
 	if err != nil {
-		return reply.SetAndLogError(err, constants.ErrorBadCredentials)
+		return reply.SetAndLogError(err, constants.ErrorBadCredentials, constants.GeniCodeError)
 	}
+	sliceExpires := metav1.NewTime(credential.Expires)
 
+	if len(args.Rspec) > s.MaxRspecSize {
+		return reply.SetAndLogError(
+			fmt.Errorf("rspec is larger than %d bytes", s.MaxRspecSize),
+			constants.ErrorBadRspec,
+			constants.GeniCodeToobig,
+		)
+	}
+	requestXml, err := DecodeDocument(args.Rspec, int64(s.MaxRspecSize))
+	if err == utils.ErrTooLarge {
+		return reply.SetAndLogError(err, constants.ErrorBadRspec, constants.GeniCodeToobig)
+	}
+	if err != nil {
+		return reply.SetAndLogError(err, constants.ErrorBadRspec, constants.GeniCodeBadargs)
+	}
+	err = rspec.ValidateRequest(requestXml)
+	if err != nil {
+		return reply.SetAndLogError(err, constants.ErrorBadRspec, constants.GeniCodeBadargs)
+	}
 	requestRspec := rspec.Rspec{}
-	err = xml.Unmarshal([]byte(html.UnescapeString(args.Rspec)), &requestRspec)
+	err = xml.Unmarshal(requestXml, &requestRspec)
 	if err != nil {
-		return reply.SetAndLogError(err, constants.ErrorDeserializeRspec)
+		return reply.SetAndLogError(err, constants.ErrorDeserializeRspec, constants.GeniCodeError)
+	}
+	requestRspec, err = expandNodeCounts(requestRspec, s.MaxNodeCount)
+	if err != nil {
+		return reply.SetAndLogError(err, constants.ErrorBadRspec, constants.GeniCodeBadargs)
 	}
 
-	returnRspec := rspec.Rspec{Type: rspec.RspecTypeRequest}
+	// The new slivers must not collide with the existing slivers of the slice.
+	existing, err := s.ListSlivers(r.Context(), *sliceIdentifier)
+	if err != nil {
+		return reply.SetAndLogError(err, constants.ErrorListResources, constants.GeniCodeError)
+	}
 
-	for _, node := range requestRspec.Nodes {
-		sliverName := naming.SliverName(sliceIdentifier.URN(), node.ClientID)
-		// Fixup the sliver type if not specified.
-		node.SliverType.Name = "container"
-		node.Location = nil
-		// We're very lenient here: if there is no image specified, or
-		// if a disk image is specified but does not exist, we use a default one.
-		diskImage := s.ContainerImages[utils.Keys(s.ContainerImages)[0]]
-		if len(node.SliverType.DiskImages) > 0 {
-			if image, ok := s.ContainerImages[node.SliverType.DiskImages[0].Name]; ok {
-				diskImage = image
+	if s.ClusterDomain != "" {
+		err = checkHostNames(requestRspec, existing)
+		if err != nil {
+			return reply.SetAndLogError(err, constants.ErrorBadRspec, constants.GeniCodeBadargs)
+		}
+	}
+
+	namespace := s.SliceNamespace(sliceIdentifier.URN())
+	images, err := s.ListContainerImages(r.Context())
+	if err != nil {
+		return reply.SetAndLogError(err, constants.ErrorListResources, constants.GeniCodeError)
+	}
+	defaultImage := defaultContainerImage(images)
+	if defaultImage == nil {
+		return reply.SetAndLogError(
+			fmt.Errorf("no container image is configured"),
+			constants.ErrorGetResource,
+			constants.GeniCodeError,
+		)
+	}
+
+	if len(requestRspec.Links) > 0 && s.LinkCniConfig == nil {
+		return reply.SetAndLogError(
+			fmt.Errorf("links are not supported by this aggregate"),
+			constants.ErrorBadLink,
+			constants.GeniCodeUnsupported,
+		)
+	}
+	interfaces, err := assignInterfaceAddresses(s.LinkSubnet, requestRspec)
+	if err != nil {
+		return reply.SetAndLogError(err, constants.ErrorBadLink, constants.GeniCodeBadargs)
+	}
+	err = assignLinkShaping(*s, requestRspec, interfaces)
+	if err != nil {
+		return reply.SetAndLogError(err, constants.ErrorBadLink, constants.GeniCodeBadargs)
+	}
+	for _, interface_ := range interfaces {
+		if needsNetem(interface_.Shaping) && s.LinkShapingImage == "" {
+			return reply.SetAndLogError(
+				fmt.Errorf("link latency and packet loss are not supported by this aggregate"),
+				constants.ErrorBadLink,
+				constants.GeniCodeUnsupported,
+			)
+		}
+	}
+
+	maxRequest := s.MaxRequest(*userIdentifier)
+
+	groups, err := sliverGroupsForRspec(requestRspec)
+	if err != nil {
+		return reply.SetAndLogError(err, constants.ErrorBadGroup, constants.GeniCodeBadargs)
+	}
+	volumes, err := sliverVolumesForRspec(*s, requestRspec)
+	if err != nil {
+		return reply.SetAndLogError(err, constants.ErrorBadVolume, constants.GeniCodeBadargs)
+	}
+	for _, nodeVolumes := range volumes {
+		for _, volume := range nodeVolumes {
+			if (volume.Shared && s.SharedStorageClass == "") || (!volume.Shared && s.StorageClass == "") {
+				return reply.SetAndLogError(
+					fmt.Errorf("volume %s is not supported by this aggregate", volume.Name),
+					constants.ErrorBadVolume,
+					constants.GeniCodeUnsupported,
+				)
 			}
 		}
+	}
+
+	needsNodes := len(requestRspec.Groups) > 0
+	exclusive := false
+	for _, node := range requestRspec.Nodes {
+		needsNodes = needsNodes || node.Placement != nil || node.Exclusive
+		exclusive = exclusive || node.Exclusive
+	}
+	var kubernetesNodes []corev1.Node
+	if needsNodes {
+		nodes, err := s.Nodes().List(r.Context(), metav1.ListOptions{})
+		if err != nil {
+			return reply.SetAndLogError(err, constants.ErrorListResources, constants.GeniCodeError)
+		}
+		kubernetesNodes = nodes.Items
+	}
+	if exclusive {
+		if s.ExclusiveNodeLabel == "" {
+			return reply.SetAndLogError(
+				fmt.Errorf("exclusive nodes are not supported by this aggregate"),
+				constants.ErrorBadExclusive,
+				constants.GeniCodeUnsupported,
+			)
+		}
+		usages, err := s.listNodeUsages(r.Context())
+		if err != nil {
+			return reply.SetAndLogError(err, constants.ErrorListResources, constants.GeniCodeError)
+		}
+		err = checkExclusiveNodes(*s, requestRspec, groups, kubernetesNodes, usages)
+		if err != nil {
+			return reply.SetAndLogError(err, constants.ErrorBadExclusive, constants.GeniCodeRefused)
+		}
+	}
+	if len(requestRspec.Groups) > 0 {
+		err = checkSliverGroups(requestRspec, groups, kubernetesNodes)
+		if err != nil {
+			return reply.SetAndLogError(err, constants.ErrorBadGroup, constants.GeniCodeRefused)
+		}
+	}
+
+	// The slivers are only created once all the nodes are validated.
+	requested := make([]*v1.Sliver, 0, len(requestRspec.Nodes))
+	for _, node := range requestRspec.Nodes {
+		sliverName := naming.SliverName(sliceIdentifier.URN(), node.ClientID)
 		var requestedArch *string
 		if node.HardwareType != nil {
 			requestedArch = &node.HardwareType.Name
@@ -96,10 +227,142 @@ func (s *Service) Allocate(r *http.Request, args *AllocateArgs, reply *AllocateR
 		if node.ComponentID != "" {
 			componentId, err := identifiers.Parse(node.ComponentID)
 			if err != nil {
-				return reply.SetAndLogError(err, constants.ErrorBadIdentifier)
+				return reply.SetAndLogError(err, constants.ErrorBadIdentifier, constants.GeniCodeError)
 			}
 			requestedNode = &componentId.ResourceName
 		}
+		// A node pinned by component_id determines the architecture.
+		if requestedNode != nil {
+			nodeArch, err := s.nodeArch(r.Context(), *requestedNode)
+			if err != nil {
+				return reply.SetAndLogError(err, constants.ErrorGetResource, constants.GeniCodeError)
+			}
+			if nodeArch != "" {
+				if requestedArch != nil && *requestedArch != nodeArch {
+					return reply.SetAndLogError(
+						fmt.Errorf("node %s is not of hardware type %s", *requestedNode, *requestedArch),
+						constants.ErrorBadHardwareType,
+						constants.GeniCodeBadargs,
+					)
+				}
+				requestedArch = &nodeArch
+			}
+		}
+		sliverType := constants.SliverTypeContainer
+		var diskImageName, image string
+		var architectures []string
+		if reference := s.customImageReference(images, node.SliverType.DiskImages); reference != "" {
+			pattern, err := s.ImagePolicy.Check(reference)
+			if err != nil {
+				return reply.SetAndLogError(
+					err,
+					constants.ErrorBadDiskImage,
+					constants.GeniCodeBadargs,
+					"image", reference,
+				)
+			}
+			klog.InfoS("Allowed custom image", "image", reference, "pattern", pattern)
+			sliverType = constants.SliverTypeCustomContainer
+			diskImageName, image = reference, reference
+		} else {
+			// Unless in strict mode, we're lenient here: if there is no image specified, or
+			// if a disk image is specified but does not exist, we use the default one.
+			diskImage := defaultImage
+			if len(node.SliverType.DiskImages) > 0 {
+				name := node.SliverType.DiskImages[0].Name
+				if found := s.findDiskImage(images, name); found != nil {
+					diskImage = found
+				} else if s.StrictDiskImages {
+					return reply.SetAndLogError(
+						fmt.Errorf("disk image %s does not exist", name),
+						constants.ErrorBadDiskImage,
+						constants.GeniCodeBadargs,
+					)
+				} else {
+					klog.InfoS("Unknown disk image, using the default one", "name", name, "default", diskImage.Name)
+				}
+			}
+			var arch string
+			if requestedArch != nil {
+				arch = *requestedArch
+			} else if len(diskImage.Spec.Variants) > 0 {
+				// The variant to run cannot be chosen without knowing the architecture of the node.
+				return reply.SetAndLogError(
+					fmt.Errorf("image %s has per-architecture variants, a hardware_type must be requested", diskImage.Name),
+					constants.ErrorBadDiskImage,
+					constants.GeniCodeBadargs,
+				)
+			} else {
+				// The pod must be scheduled on a node of an architecture supported by the image.
+				architectures = diskImage.Spec.Architectures
+			}
+			image, err = imageReference(*diskImage, arch)
+			if err != nil {
+				return reply.SetAndLogError(err, constants.ErrorBadDiskImage, constants.GeniCodeBadargs)
+			}
+			diskImageName = s.AuthorityIdentifier.Copy(identifiers.ResourceTypeImage, diskImage.Name).URN()
+		}
+		installs, executes, err := sliverServicesForNode(node, sliverType)
+		if err != nil {
+			return reply.SetAndLogError(err, constants.ErrorBadService, constants.GeniCodeBadargs)
+		}
+		ports, err := sliverPortsForNode(node, sliverType)
+		if err != nil {
+			return reply.SetAndLogError(err, constants.ErrorBadPort, constants.GeniCodeBadargs)
+		}
+		for _, port := range ports {
+			if port.Ingress && s.IngressDomain == "" {
+				return reply.SetAndLogError(
+					fmt.Errorf("ingresses are not supported by this AM"),
+					constants.ErrorBadPort,
+					constants.GeniCodeUnsupported,
+				)
+			}
+		}
+		placement, err := sliverPlacementForNode(*s, node, kubernetesNodes)
+		if err != nil {
+			return reply.SetAndLogError(err, constants.ErrorBadPlacement, constants.GeniCodeBadargs)
+		}
+		if placement != nil {
+			satisfiable := false
+			for _, kubernetesNode := range kubernetesNodes {
+				satisfiable = satisfiable || placementMatches(*placement, kubernetesNode)
+			}
+			if !satisfiable {
+				return reply.SetAndLogError(
+					fmt.Errorf("no node satisfies the placement of %s", node.ClientID),
+					constants.ErrorBadPlacement,
+					constants.GeniCodeRefused,
+				)
+			}
+		}
+		var cpuRequest, memoryRequest *string
+		if node.Resources != nil {
+			cpuRequest, err = clampQuantity(node.Resources.CPU, maxRequest.Cpu)
+			if err != nil {
+				return reply.SetAndLogError(err, constants.ErrorBadQuantity, constants.GeniCodeBadargs)
+			}
+			memoryRequest, err = clampQuantity(node.Resources.Memory, maxRequest.Memory)
+			if err != nil {
+				return reply.SetAndLogError(err, constants.ErrorBadQuantity, constants.GeniCodeBadargs)
+			}
+		}
+		sliverInterfaces := make([]v1.SliverInterface, 0)
+		linkClientIDs := make([]string, 0)
+		for _, interface_ := range node.Interfaces {
+			if sliverInterface, ok := interfaces[interface_.ClientID]; ok {
+				sliverInterfaces = append(sliverInterfaces, sliverInterface)
+				linkClientIDs = append(linkClientIDs, sliverInterface.LinkClientID)
+			}
+		}
+		extensions, err := rspec.MarshalNodeExtensions(requestRspec, node)
+		if err != nil {
+			return reply.SetAndLogError(err, constants.ErrorSerializeRspec, constants.GeniCodeError)
+		}
+		sliceExtensions, err := rspec.MarshalRspecExtensions(requestRspec, linkClientIDs)
+		if err != nil {
+			return reply.SetAndLogError(err, constants.ErrorSerializeRspec, constants.GeniCodeError)
+		}
 		labels := map[string]string{
 			// We store the hash since the full URN would not be a valid label value;
 			// this allows us to easily get all the resources belonging to a slice.
@@ -108,45 +371,125 @@ func (s *Service) Allocate(r *http.Request, args *AllocateArgs, reply *AllocateR
 		}
 		sliver := &v1.Sliver{
 			ObjectMeta: metav1.ObjectMeta{
-				Name:   sliverName,
-				Labels: labels,
+				Name:      sliverName,
+				Namespace: namespace,
+				Labels:    labels,
 			},
 			Spec: v1.SliverSpec{
 				URN: s.AuthorityIdentifier.Copy(identifiers.ResourceTypeSliver, sliverName).
 					URN(),
-				SliceURN:      sliceIdentifier.URN(),
-				UserURN:       userIdentifier.URN(),
-				Expires:       metav1.NewTime(time.Now().Add(24 * time.Hour)),
-				ClientID:      node.ClientID,
-				Image:         diskImage,
-				RequestedArch: requestedArch,
-				RequestedNode: requestedNode,
+				SliceURN:             sliceIdentifier.URN(),
+				UserURN:              userIdentifier.URN(),
+				Expires:              metav1.NewTime(time.Now().Add(24 * time.Hour)),
+				SliceExpires:         &sliceExpires,
+				ClientID:             node.ClientID,
+				Image:                image,
+				DiskImageName:        diskImageName,
+				SliverType:           sliverType,
+				RequestedArch:        requestedArch,
+				Architectures:        architectures,
+				RequestedNode:        requestedNode,
+				Exclusive:            node.Exclusive,
+				Placement:            placement,
+				Groups:               groups[node.ClientID],
+				CpuRequest:           cpuRequest,
+				MemoryRequest:        memoryRequest,
+				Interfaces:           sliverInterfaces,
+				Installs:             installs,
+				Executes:             executes,
+				Ports:                ports,
+				Volumes:              volumes[node.ClientID],
+				RspecExtensions:      extensions,
+				RspecSliceExtensions: sliceExtensions,
 			},
 		}
-		sliver, err = s.Slivers().Create(r.Context(), sliver, metav1.CreateOptions{})
+		requested = append(requested, sliver)
+	}
+	if s.IngressDomain != "" {
+		err = checkIngressHosts(*s, requested, existing)
+		if err != nil {
+			return reply.SetAndLogError(err, constants.ErrorBadPort, constants.GeniCodeBadargs)
+		}
+	}
+
+	err = createSliceNamespace(r.Context(), *s, sliceIdentifier.URN())
+	if err != nil {
+		return reply.SetAndLogError(err, constants.ErrorCreateResource, constants.GeniCodeError)
+	}
+
+	// The allocation is atomic: the slivers created by this call are deleted if a node cannot be allocated.
+	created := make([]v1.Sliver, 0)
+	allocated := false
+	defer func() {
+		if !allocated {
+			rollbackSlivers(r.Context(), *s, created)
+		}
+	}()
+
+	slivers := make([]v1.Sliver, 0)
+	for _, sliver := range requested {
+		sliverName := sliver.Name
+		sliver, err := s.Slivers(namespace).Create(r.Context(), sliver, metav1.CreateOptions{})
 		if err != nil {
 			if errors.IsAlreadyExists(err) {
-				sliver, err = s.Slivers().Get(r.Context(), sliverName, metav1.GetOptions{})
+				sliver, err = s.Slivers(namespace).Get(r.Context(), sliverName, metav1.GetOptions{})
 				if err != nil {
-					return reply.SetAndLogError(err, constants.ErrorGetResource)
+					return reply.SetAndLogError(err, constants.ErrorGetResource, constants.GeniCodeError)
 				}
 			} else {
-				return reply.SetAndLogError(err, constants.ErrorCreateResource)
+				return reply.SetAndLogError(err, constants.ErrorCreateResource, constants.GeniCodeError)
 			}
+		} else {
+			created = append(created, *sliver)
 		}
-		allocationStatus, operationalStatus := s.GetSliverStatus(r.Context(), sliverName)
+		allocationStatus, operationalStatus := s.GetSliverStatus(r.Context(), namespace, sliverName)
 		reply.Data.Value.Slivers = append(
 			reply.Data.Value.Slivers,
 			NewSliver(*sliver, allocationStatus, operationalStatus),
 		)
-		returnRspec.Nodes = append(returnRspec.Nodes, node)
+		slivers = append(slivers, *sliver)
 	}
 
+	returnRspec, err := s.manifestRspec(r.Context(), slivers)
+	if err != nil {
+		return reply.SetAndLogError(err, constants.ErrorDeserializeRspec, constants.GeniCodeError)
+	}
 	xml_, err := MarshalRspec(returnRspec, args.Options.Compressed)
 	if err != nil {
-		return reply.SetAndLogError(err, constants.ErrorSerializeRspec)
+		return reply.SetAndLogError(err, constants.ErrorSerializeRspec, constants.GeniCodeError)
 	}
 	reply.Data.Value.Rspec = xml_
 	reply.Data.Code.Code = constants.GeniCodeSuccess
+	allocated = true
 	return nil
 }
+
+// rollbackSlivers deletes the slivers created by a failed allocation.
+func rollbackSlivers(ctx context.Context, s Service, slivers []v1.Sliver) {
+	for _, sliver := range slivers {
+		err := s.Slivers(sliver.Namespace).Delete(ctx, sliver.Name, metav1.DeleteOptions{})
+		if err != nil && !errors.IsNotFound(err) {
+			klog.ErrorS(err, constants.ErrorDeleteResource, "sliver", sliver.Name)
+		}
+	}
+}
+
+// clampQuantity parses a requested quantity and caps it to the given maximum.
+// It returns nil if no quantity is requested.
+func clampQuantity(requested string, max resource.Quantity) (*string, error) {
+	if requested == "" {
+		return nil, nil
+	}
+	quantity, err := resource.ParseQuantity(requested)
+	if err != nil {
+		return nil, err
+	}
+	if quantity.Sign() <= 0 {
+		return nil, fmt.Errorf("quantity must be positive")
+	}
+	if quantity.Cmp(max) > 0 {
+		quantity = max
+	}
+	v := quantity.String()
+	return &v, nil
+}
@@ -1,7 +1,16 @@
 package service
 
 import (
+	"context"
+	"fmt"
+	v1 "github.com/EdgeNet-project/fed4fire/pkg/apis/fed4fire/v1"
+	"github.com/EdgeNet-project/fed4fire/pkg/naming"
+	"github.com/EdgeNet-project/fed4fire/pkg/utils"
 	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"strings"
 	"testing"
 
 	"github.com/EdgeNet-project/fed4fire/pkg/constants"
@@ -51,3 +60,1222 @@ func TestAllocate_Many(t *testing.T) {
 	slivers := listTestSlivers(s)
 	assert.Len(t, slivers, 2)
 }
+
+func TestAllocate_Resources(t *testing.T) {
+	s := testService()
+	r := testRequest()
+	args := &AllocateArgs{
+		SliceURN:    testSliceIdentifier.URN(),
+		Credentials: []Credential{testSliceCredential},
+		Rspec:       testRspecResources,
+	}
+	reply := &AllocateReply{}
+	err := s.Allocate(r, args, reply)
+	assert.Nil(t, err)
+	assert.Equal(t, constants.GeniCodeSuccess, reply.Data.Code.Code)
+	// The memory request must be capped to the maximum.
+	slivers := listTestSlivers(s)
+	assert.Len(t, slivers, 1)
+	assert.Equal(t, "3", *slivers[0].Spec.CpuRequest)
+	assert.Equal(t, "4Gi", *slivers[0].Spec.MemoryRequest)
+	v := unmarshalTestRspec(reply.Data.Value.Rspec)
+	assert.Equal(t, "3", v.Nodes[0].Resources.CPU)
+	assert.Equal(t, "4Gi", v.Nodes[0].Resources.Memory)
+}
+
+func TestAllocate_UserMaxRequest(t *testing.T) {
+	s := testService()
+	s.UserMaxRequests = map[string]MaxRequest{
+		"example.org": {Cpu: resource.MustParse("2"), Memory: resource.MustParse("8Gi")},
+	}
+	r := testRequest()
+	args := &AllocateArgs{
+		SliceURN:    testSliceIdentifier.URN(),
+		Credentials: []Credential{testSliceCredential},
+		Rspec:       testRspecResources,
+	}
+	reply := &AllocateReply{}
+	err := s.Allocate(r, args, reply)
+	assert.Nil(t, err)
+	assert.Equal(t, constants.GeniCodeSuccess, reply.Data.Code.Code)
+	// The requests must be capped to the maxima of the authority of the user.
+	slivers := listTestSlivers(s)
+	assert.Len(t, slivers, 1)
+	assert.Equal(t, "2", *slivers[0].Spec.CpuRequest)
+	assert.Equal(t, "8Gi", *slivers[0].Spec.MemoryRequest)
+}
+
+func TestAllocate_BadResources(t *testing.T) {
+	s := testService()
+	r := testRequest()
+	args := &AllocateArgs{
+		SliceURN:    testSliceIdentifier.URN(),
+		Credentials: []Credential{testSliceCredential},
+		Rspec:       strings.Replace(testRspecResources, `cpu="3"`, `cpu="three"`, 1),
+	}
+	reply := &AllocateReply{}
+	err := s.Allocate(r, args, reply)
+	assert.Nil(t, err)
+	assert.Equal(t, constants.GeniCodeBadargs, reply.Data.Code.Code)
+	assert.Len(t, listTestSlivers(s), 0)
+}
+
+func TestAllocate_ContainerImages(t *testing.T) {
+	s := testService()
+	r := testRequest()
+	images := []*v1.ContainerImage{
+		testContainerImage("debian11", "docker.io/library/debian:11", nil, false),
+		testContainerImage("fedora35", "docker.io/library/fedora:35", nil, true),
+	}
+	for _, image := range images {
+		s.ContainerImageCatalog().Create(context.TODO(), image, metav1.CreateOptions{})
+	}
+
+	// The default image of the catalog takes precedence over the command line images.
+	allocateTestSlice(s, r, testRspecSingle)
+	slivers := listTestSlivers(s)
+	assert.Len(t, slivers, 1)
+	assert.Equal(t, "docker.io/library/fedora:35", slivers[0].Spec.Image)
+
+	// Images given on the command line can still be requested.
+	s = testService()
+	allocateTestSlice(s, r, testRspecDiskImage)
+	slivers = listTestSlivers(s)
+	assert.Len(t, slivers, 1)
+	assert.Equal(t, "docker.io/library/ubuntu:20.04", slivers[0].Spec.Image)
+
+	// The catalog overrides the command line images with the same name.
+	s = testService()
+	s.ContainerImageCatalog().Create(
+		context.TODO(),
+		testContainerImage("ubuntu2004", "docker.io/library/ubuntu:focal", nil, false),
+		metav1.CreateOptions{},
+	)
+	allocateTestSlice(s, r, testRspecDiskImage)
+	slivers = listTestSlivers(s)
+	assert.Len(t, slivers, 1)
+	assert.Equal(t, "docker.io/library/ubuntu:focal", slivers[0].Spec.Image)
+}
+
+func TestAllocate_NoContainerImages(t *testing.T) {
+	s := testService()
+	s.ContainerImages = nil
+	r := testRequest()
+	args := &AllocateArgs{
+		SliceURN:    testSliceIdentifier.URN(),
+		Credentials: []Credential{testSliceCredential},
+		Rspec:       testRspecSingle,
+	}
+	reply := &AllocateReply{}
+	err := s.Allocate(r, args, reply)
+	assert.Nil(t, err)
+	assert.Equal(t, constants.GeniCodeError, reply.Data.Code.Code)
+	assert.Len(t, listTestSlivers(s), 0)
+}
+
+func TestAllocate_DiskImageURN(t *testing.T) {
+	s := testService()
+	r := testRequest()
+	s.ContainerImageCatalog().Create(
+		context.TODO(),
+		testContainerImage("debian11", "docker.io/library/debian:11", nil, false),
+		metav1.CreateOptions{},
+	)
+	allocateTestSlice(s, r, strings.Replace(
+		testRspecDiskImage,
+		`name="ubuntu2004"`,
+		`name="urn:publicid:IDN+example.org+image+debian11"`,
+		1,
+	))
+	slivers := listTestSlivers(s)
+	assert.Len(t, slivers, 1)
+	assert.Equal(t, "docker.io/library/debian:11", slivers[0].Spec.Image)
+}
+
+func TestAllocate_StrictDiskImages(t *testing.T) {
+	rspec_ := strings.Replace(testRspecDiskImage, `name="ubuntu2004"`, `name="unknown"`, 1)
+	for _, strict := range []bool{false, true} {
+		s := testService()
+		s.StrictDiskImages = strict
+		r := testRequest()
+		args := &AllocateArgs{
+			SliceURN:    testSliceIdentifier.URN(),
+			Credentials: []Credential{testSliceCredential},
+			Rspec:       rspec_,
+		}
+		reply := &AllocateReply{}
+		err := s.Allocate(r, args, reply)
+		assert.Nil(t, err)
+		if strict {
+			assert.Equal(t, constants.GeniCodeBadargs, reply.Data.Code.Code)
+			assert.Len(t, listTestSlivers(s), 0)
+		} else {
+			assert.Equal(t, constants.GeniCodeSuccess, reply.Data.Code.Code)
+			assert.Len(t, listTestSlivers(s), 1)
+		}
+	}
+}
+
+func TestAllocate_DiskImageVariants(t *testing.T) {
+	image := testContainerImage("ubuntu2004", "docker.io/library/ubuntu:20.04", []string{"amd64"}, true)
+	image.Spec.Variants = []v1.ContainerImageVariant{
+		{Architecture: "arm64", Image: "docker.io/arm64v8/ubuntu:20.04"},
+	}
+	hardwareType := `<sliver_type name="container">`
+	tests := []struct {
+		arch  string
+		image string
+		code  int
+	}{
+		// The variant to run is unknown without a hardware type.
+		{"", "", constants.GeniCodeBadargs},
+		{"amd64", "docker.io/library/ubuntu:20.04", constants.GeniCodeSuccess},
+		{"arm64", "docker.io/arm64v8/ubuntu:20.04", constants.GeniCodeSuccess},
+		{"riscv64", "", constants.GeniCodeBadargs},
+	}
+	for _, test := range tests {
+		s := testService()
+		s.ContainerImageCatalog().Create(context.TODO(), image, metav1.CreateOptions{})
+		r := testRequest()
+		rspec_ := testRspecDiskImage
+		if test.arch != "" {
+			rspec_ = strings.Replace(
+				rspec_,
+				hardwareType,
+				`<hardware_type name="`+test.arch+`"/>`+hardwareType,
+				1,
+			)
+		}
+		args := &AllocateArgs{
+			SliceURN:    testSliceIdentifier.URN(),
+			Credentials: []Credential{testSliceCredential},
+			Rspec:       rspec_,
+		}
+		reply := &AllocateReply{}
+		err := s.Allocate(r, args, reply)
+		assert.Nil(t, err)
+		assert.Equal(t, test.code, reply.Data.Code.Code, test.arch)
+		slivers := listTestSlivers(s)
+		if test.image != "" {
+			assert.Len(t, slivers, 1)
+			assert.Equal(t, test.image, slivers[0].Spec.Image)
+		} else {
+			assert.Len(t, slivers, 0)
+		}
+	}
+}
+
+func TestAllocate_DiskImageArchitectures(t *testing.T) {
+	s := testService()
+	r := testRequest()
+	image := testContainerImage("ubuntu2004", "docker.io/library/ubuntu:20.04", []string{"amd64", "arm64"}, true)
+	s.ContainerImageCatalog().Create(context.TODO(), image, metav1.CreateOptions{})
+	allocateTestSlice(s, r, testRspecSingle)
+	slivers := listTestSlivers(s)
+	assert.Len(t, slivers, 1)
+	assert.Nil(t, slivers[0].Spec.RequestedArch)
+	assert.Equal(t, []string{"amd64", "arm64"}, slivers[0].Spec.Architectures)
+}
+
+func TestAllocate_ComponentIDArch(t *testing.T) {
+	node := testNode("node-1", true)
+	node.Labels[corev1.LabelArchStable] = "arm64"
+	rspec_ := strings.Replace(
+		testRspecSingle,
+		`client_id="PC1"`,
+		`client_id="PC1" component_id="urn:publicid:IDN+example.org+node+node-1"`,
+		1,
+	)
+	hardwareType := `<sliver_type name="container"/>`
+	tests := []struct {
+		arch string
+		code int
+	}{
+		{"", constants.GeniCodeSuccess},
+		{"arm64", constants.GeniCodeSuccess},
+		{"amd64", constants.GeniCodeBadargs},
+	}
+	for _, test := range tests {
+		s := testService()
+		s.Nodes().Create(context.TODO(), node, metav1.CreateOptions{})
+		r := testRequest()
+		request := rspec_
+		if test.arch != "" {
+			request = strings.Replace(
+				request,
+				hardwareType,
+				hardwareType+`<hardware_type name="`+test.arch+`"/>`,
+				1,
+			)
+		}
+		args := &AllocateArgs{
+			SliceURN:    testSliceIdentifier.URN(),
+			Credentials: []Credential{testSliceCredential},
+			Rspec:       request,
+		}
+		reply := &AllocateReply{}
+		err := s.Allocate(r, args, reply)
+		assert.Nil(t, err)
+		assert.Equal(t, test.code, reply.Data.Code.Code, test.arch)
+		slivers := listTestSlivers(s)
+		if test.code == constants.GeniCodeSuccess {
+			// The architecture of the pinned node is used.
+			assert.Len(t, slivers, 1)
+			assert.Equal(t, "arm64", *slivers[0].Spec.RequestedArch)
+		} else {
+			assert.Len(t, slivers, 0)
+		}
+	}
+}
+
+func TestAllocate_CustomImage(t *testing.T) {
+	const image = "docker.io/library/nginx:1.21"
+	tests := []struct {
+		rspec     string
+		allowlist []string
+		code      int
+	}{
+		// Custom images are refused by default.
+		{`name="` + image + `"`, nil, constants.GeniCodeBadargs},
+		{`name="` + image + `"`, []string{"docker.io/library/*"}, constants.GeniCodeSuccess},
+		{`name="any" url="` + image + `"`, []string{"docker.io/library/*"}, constants.GeniCodeSuccess},
+		{`name="` + image + `"`, []string{"docker.io/edgenet/*"}, constants.GeniCodeBadargs},
+	}
+	for _, test := range tests {
+		s := testService()
+		s.ImagePolicy.Allowlist = test.allowlist
+		r := testRequest()
+		args := &AllocateArgs{
+			SliceURN:    testSliceIdentifier.URN(),
+			Credentials: []Credential{testSliceCredential},
+			Rspec:       strings.Replace(testRspecDiskImage, `name="ubuntu2004"`, test.rspec, 1),
+		}
+		reply := &AllocateReply{}
+		err := s.Allocate(r, args, reply)
+		assert.Nil(t, err)
+		assert.Equal(t, test.code, reply.Data.Code.Code, test.rspec)
+		slivers := listTestSlivers(s)
+		if test.code != constants.GeniCodeSuccess {
+			assert.Len(t, slivers, 0)
+			continue
+		}
+		assert.Len(t, slivers, 1)
+		assert.Equal(t, image, slivers[0].Spec.Image)
+		assert.Equal(t, constants.SliverTypeCustomContainer, slivers[0].Spec.SliverType)
+		v := unmarshalTestRspec(reply.Data.Value.Rspec)
+		assert.Equal(t, constants.SliverTypeCustomContainer, v.Nodes[0].SliverType.Name)
+		assert.Equal(t, image, v.Nodes[0].SliverType.DiskImages[0].URL)
+	}
+}
+
+func TestAllocate_Links(t *testing.T) {
+	s := testService()
+	r := testRequest()
+	args := &AllocateArgs{
+		SliceURN:    testSliceIdentifier.URN(),
+		Credentials: []Credential{testSliceCredential},
+		Rspec:       testRspecLinks,
+	}
+	reply := &AllocateReply{}
+	err := s.Allocate(r, args, reply)
+	assert.Nil(t, err)
+	assert.Equal(t, constants.GeniCodeSuccess, reply.Data.Code.Code)
+	slivers := listTestSlivers(s)
+	assert.Len(t, slivers, 2)
+	addresses := make(map[string]string)
+	for _, sliver := range slivers {
+		assert.Len(t, sliver.Spec.Interfaces, 1)
+		assert.Equal(t, "link0", sliver.Spec.Interfaces[0].LinkClientID)
+		addresses[sliver.Spec.Interfaces[0].ClientID] = sliver.Spec.Interfaces[0].Address
+	}
+	// The requested address must be kept, and skipped by the automatic ones.
+	assert.Equal(t, "10.128.0.2/24", addresses["PC1:if0"])
+	assert.Equal(t, "10.128.0.1/24", addresses["PC2:if0"])
+	v := unmarshalTestRspec(reply.Data.Value.Rspec)
+	assert.Len(t, v.Links, 1)
+	assert.Len(t, v.Links[0].InterfaceRefs, 2)
+	for _, node := range v.Nodes {
+		assert.Len(t, node.Interfaces, 1)
+		assert.Len(t, node.Interfaces[0].IPs, 1)
+	}
+}
+
+func TestAllocate_BadLinks(t *testing.T) {
+	tests := []struct {
+		rspec string
+		code  int
+	}{
+		{strings.Replace(testRspecLinks, `<interface_ref client_id="PC2:if0"/>`, `<interface_ref client_id="PC3:if0"/>`, 1), constants.GeniCodeBadargs},
+		{strings.Replace(testRspecLinks, `address="10.128.0.1"`, `address="10.128.0"`, 1), constants.GeniCodeBadargs},
+		// The requested addresses must be host addresses of the /24 of the link.
+		{strings.Replace(testRspecLinks, `address="10.128.0.1"`, `address="192.168.1.2"`, 1), constants.GeniCodeBadargs},
+		{strings.Replace(testRspecLinks, `address="10.128.0.1"`, `address="10.128.1.1"`, 1), constants.GeniCodeBadargs},
+		{strings.Replace(testRspecLinks, `address="10.128.0.1"`, `address="10.128.0.255"`, 1), constants.GeniCodeBadargs},
+		{strings.Replace(testRspecLinks, `netmask="255.255.255.0"`, `netmask="255.255.0.0"`, 1), constants.GeniCodeBadargs},
+		// The requested addresses must be unique.
+		{strings.Replace(testRspecLinks, `<interface client_id="PC1:if0"/>`, `<interface client_id="PC1:if0"><ip address="10.128.0.1"/></interface>`, 1), constants.GeniCodeBadargs},
+	}
+	for _, test := range tests {
+		s := testService()
+		r := testRequest()
+		args := &AllocateArgs{
+			SliceURN:    testSliceIdentifier.URN(),
+			Credentials: []Credential{testSliceCredential},
+			Rspec:       test.rspec,
+		}
+		reply := &AllocateReply{}
+		err := s.Allocate(r, args, reply)
+		assert.Nil(t, err)
+		assert.Equal(t, test.code, reply.Data.Code.Code)
+		assert.Len(t, listTestSlivers(s), 0)
+	}
+	// Links are not supported without a CNI configuration.
+	s := testService()
+	s.LinkCniConfig = nil
+	r := testRequest()
+	args := &AllocateArgs{
+		SliceURN:    testSliceIdentifier.URN(),
+		Credentials: []Credential{testSliceCredential},
+		Rspec:       testRspecLinks,
+	}
+	reply := &AllocateReply{}
+	err := s.Allocate(r, args, reply)
+	assert.Nil(t, err)
+	assert.Equal(t, constants.GeniCodeUnsupported, reply.Data.Code.Code)
+	assert.Len(t, listTestSlivers(s), 0)
+}
+
+func TestAllocate_LinkProperties(t *testing.T) {
+	tests := []struct {
+		properties string
+		code       int
+		shaping    *v1.LinkShaping
+	}{
+		{`capacity="10000" latency="20" packet_loss="0.010"`, constants.GeniCodeSuccess, &v1.LinkShaping{Capacity: 10000, Latency: 20, PacketLoss: "0.01"}},
+		{`capacity="10000"`, constants.GeniCodeSuccess, &v1.LinkShaping{Capacity: 10000}},
+		{`packet_loss="0"`, constants.GeniCodeSuccess, nil},
+		// Beyond the limits of the aggregate.
+		{`capacity="1000000"`, constants.GeniCodeBadargs, nil},
+		{`latency="5000"`, constants.GeniCodeBadargs, nil},
+		{`packet_loss="2"`, constants.GeniCodeBadargs, nil},
+		{`capacity="fast"`, constants.GeniCodeBadargs, nil},
+		{`capacity="10000" dest_id="PC3:if0"`, constants.GeniCodeBadargs, nil},
+	}
+	for _, test := range tests {
+		s := testService()
+		r := testRequest()
+		rspec := fmt.Sprintf(testRspecLinkProperties, test.properties)
+		if strings.Contains(test.properties, "dest_id") {
+			rspec = strings.Replace(rspec, `dest_id="PC2:if0" `, "", 1)
+		}
+		args := &AllocateArgs{
+			SliceURN:    testSliceIdentifier.URN(),
+			Credentials: []Credential{testSliceCredential},
+			Rspec:       rspec,
+		}
+		reply := &AllocateReply{}
+		err := s.Allocate(r, args, reply)
+		assert.Nil(t, err)
+		assert.Equal(t, test.code, reply.Data.Code.Code, test.properties)
+		slivers := listTestSlivers(s)
+		if test.code != constants.GeniCodeSuccess {
+			assert.Len(t, slivers, 0)
+			continue
+		}
+		assert.Len(t, slivers, 2)
+		for _, sliver := range slivers {
+			if sliver.Spec.ClientID == "PC1" {
+				assert.Equal(t, test.shaping, sliver.Spec.Interfaces[0].Shaping, test.properties)
+			} else {
+				assert.Nil(t, sliver.Spec.Interfaces[0].Shaping)
+			}
+		}
+	}
+}
+
+func TestAllocate_BadLinkProperties(t *testing.T) {
+	// The traffic sent by an interface cannot be shaped differently for each destination.
+	s := testService()
+	r := testRequest()
+	args := &AllocateArgs{
+		SliceURN:    testSliceIdentifier.URN(),
+		Credentials: []Credential{testSliceCredential},
+		Rspec: strings.Replace(
+			testRspecLinks,
+			`<link_type name="lan"/>`,
+			`<property source_id="PC1:if0" dest_id="PC2:if0" latency="10"/>
+			<property source_id="PC1:if0" dest_id="PC2:if0" latency="20"/>`,
+			1,
+		),
+	}
+	reply := &AllocateReply{}
+	err := s.Allocate(r, args, reply)
+	assert.Nil(t, err)
+	assert.Equal(t, constants.GeniCodeBadargs, reply.Data.Code.Code)
+
+	// Latency requires the link shaping image.
+	s = testService()
+	s.LinkShapingImage = ""
+	args.Rspec = fmt.Sprintf(testRspecLinkProperties, `latency="10"`)
+	reply = &AllocateReply{}
+	err = s.Allocate(r, args, reply)
+	assert.Nil(t, err)
+	assert.Equal(t, constants.GeniCodeUnsupported, reply.Data.Code.Code)
+	assert.Len(t, listTestSlivers(s), 0)
+}
+
+func TestAllocate_Services(t *testing.T) {
+	s := testService()
+	r := testRequest()
+	args := &AllocateArgs{
+		SliceURN:    testSliceIdentifier.URN(),
+		Credentials: []Credential{testSliceCredential},
+		Rspec:       testRspecServices,
+	}
+	reply := &AllocateReply{}
+	err := s.Allocate(r, args, reply)
+	assert.Nil(t, err)
+	assert.Equal(t, constants.GeniCodeSuccess, reply.Data.Code.Code)
+	slivers := listTestSlivers(s)
+	assert.Len(t, slivers, 1)
+	assert.Equal(t, []v1.SliverInstall{
+		{URL: "https://example.org/tools.tar.gz", InstallPath: "/local"},
+		{URL: "https://example.org/data.csv", InstallPath: "/local"},
+	}, slivers[0].Spec.Installs)
+	assert.Equal(t, []v1.SliverExecute{
+		{Shell: "bash", Command: "echo 'Hello' > /tmp/hello"},
+	}, slivers[0].Spec.Executes)
+	v := unmarshalTestRspec(reply.Data.Value.Rspec)
+	assert.Len(t, v.Nodes[0].Services.Installs, 2)
+	assert.Len(t, v.Nodes[0].Services.Executes, 1)
+}
+
+func TestAllocate_BadServices(t *testing.T) {
+	tests := []struct {
+		old string
+		new string
+	}{
+		{`url="https://example.org/tools.tar.gz"`, `url="file:///etc/passwd"`},
+		{`install_path="/local"`, `install_path="local"`},
+		{`install_path="/local"`, `install_path="/"`},
+		{`install_path="/local"`, `install_path="/usr/local/bin"`},
+		{`install_path="/local"`, `install_path="/lib64"`},
+		{`install_path="/local"`, `install_path="/opt/../etc"`},
+		{`shell="bash"`, `shell="csh"`},
+	}
+	for _, test := range tests {
+		s := testService()
+		r := testRequest()
+		args := &AllocateArgs{
+			SliceURN:    testSliceIdentifier.URN(),
+			Credentials: []Credential{testSliceCredential},
+			Rspec:       strings.Replace(testRspecServices, test.old, test.new, 1),
+		}
+		reply := &AllocateReply{}
+		err := s.Allocate(r, args, reply)
+		assert.Nil(t, err)
+		assert.Equal(t, constants.GeniCodeBadargs, reply.Data.Code.Code, test.new)
+		assert.Len(t, listTestSlivers(s), 0)
+	}
+
+	// The command of custom images is unknown, so the execute services cannot be run before it.
+	s := testService()
+	s.ImagePolicy.Allowlist = []string{"docker.io/library/*"}
+	r := testRequest()
+	args := &AllocateArgs{
+		SliceURN:    testSliceIdentifier.URN(),
+		Credentials: []Credential{testSliceCredential},
+		Rspec: strings.Replace(
+			testRspecServices,
+			`<sliver_type name="container"/>`,
+			`<sliver_type name="container"><disk_image url="docker.io/library/nginx:1.21"/></sliver_type>`,
+			1,
+		),
+	}
+	reply := &AllocateReply{}
+	err := s.Allocate(r, args, reply)
+	assert.Nil(t, err)
+	assert.Equal(t, constants.GeniCodeBadargs, reply.Data.Code.Code)
+}
+
+func TestAllocate_Extensions(t *testing.T) {
+	s := testService()
+	r := testRequest()
+	args := &AllocateArgs{
+		SliceURN:    testSliceIdentifier.URN(),
+		Credentials: []Credential{testSliceCredential},
+		Rspec:       testRspecExtensions,
+	}
+	reply := &AllocateReply{}
+	err := s.Allocate(r, args, reply)
+	assert.Nil(t, err)
+	assert.Equal(t, constants.GeniCodeSuccess, reply.Data.Code.Code)
+	assert.Contains(t, reply.Data.Value.Rspec, `xmlns:jfed="http://jfed.iminds.be/rspec/ext/jfed/1"`)
+	assert.Contains(t, reply.Data.Value.Rspec, `<emulab:routable_control_ip></emulab:routable_control_ip>`)
+	assert.Contains(
+		t,
+		reply.Data.Value.Rspec,
+		`<location xmlns="http://jfed.iminds.be/rspec/ext/jfed/1" x="107.5" y="91.5"></location>`,
+	)
+	slivers := listTestSlivers(s)
+	assert.Len(t, slivers, 1)
+	assert.Contains(t, slivers[0].Spec.RspecExtensions, "routable_control_ip")
+}
+
+func TestAllocate_BadRspec(t *testing.T) {
+	s := testService()
+	r := testRequest()
+	tests := []struct {
+		rspec string
+		code  int
+	}{
+		{strings.Replace(testRspecSingle, `type="request"`, `type="manifest"`, 1), constants.GeniCodeBadargs},
+		{`<rspec type="request"><node client_id="PC1"/></rspec>`, constants.GeniCodeBadargs},
+		{testRspecSingle + strings.Repeat(" ", s.MaxRspecSize), constants.GeniCodeToobig},
+	}
+	for _, test := range tests {
+		args := &AllocateArgs{
+			SliceURN:    testSliceIdentifier.URN(),
+			Credentials: []Credential{testSliceCredential},
+			Rspec:       test.rspec,
+		}
+		reply := &AllocateReply{}
+		err := s.Allocate(r, args, reply)
+		assert.Nil(t, err)
+		assert.Equal(t, test.code, reply.Data.Code.Code)
+	}
+	assert.Len(t, listTestSlivers(s), 0)
+}
+
+func TestAllocate_Compressed(t *testing.T) {
+	s := testService()
+	r := testRequest()
+	tests := []struct {
+		rspec string
+		code  int
+	}{
+		{utils.CompressZlibBase64([]byte(testRspecMany)), constants.GeniCodeSuccess},
+		{"eJzLSM3JyQcABiwCFQ==\n", constants.GeniCodeBadargs},
+		{"not an rspec", constants.GeniCodeBadargs},
+		{
+			utils.CompressZlibBase64([]byte(testRspecSingle + strings.Repeat(" ", s.MaxRspecSize))),
+			constants.GeniCodeToobig,
+		},
+	}
+	for _, test := range tests {
+		args := &AllocateArgs{
+			SliceURN:    testSliceIdentifier.URN(),
+			Credentials: []Credential{testSliceCredential},
+			Rspec:       test.rspec,
+		}
+		reply := &AllocateReply{}
+		err := s.Allocate(r, args, reply)
+		assert.Nil(t, err)
+		assert.Equal(t, test.code, reply.Data.Code.Code, reply.Data.Output)
+	}
+	assert.Len(t, listTestSlivers(s), 2)
+}
+
+func createTestPlacementNodes(s *Service) {
+	// node-1 is in São Paulo, node-2 in Paris.
+	nodes := []*corev1.Node{testNode("node-1", true), testNode("node-2", true)}
+	nodes[0].Labels[constants.EdgeNetLabelCountryISO] = "BR"
+	nodes[0].Labels["example.org/gpu"] = "true"
+	nodes[1].Labels[constants.EdgeNetLabelCountryISO] = "FR"
+	nodes[1].Labels[constants.EdgeNetLabelLatitude] = "n48.856600"
+	nodes[1].Labels[constants.EdgeNetLabelLongitude] = "e2.352200"
+	for _, node := range nodes {
+		_, err := s.Nodes().Create(context.TODO(), node, metav1.CreateOptions{})
+		utils.Check(err)
+	}
+}
+
+func TestAllocate_Placement(t *testing.T) {
+	s := testService()
+	r := testRequest()
+	createTestPlacementNodes(s)
+	args := &AllocateArgs{
+		SliceURN:    testSliceIdentifier.URN(),
+		Credentials: []Credential{testSliceCredential},
+		Rspec:       testRspecPlacement,
+	}
+	reply := &AllocateReply{}
+	err := s.Allocate(r, args, reply)
+	assert.Nil(t, err)
+	assert.Equal(t, constants.GeniCodeSuccess, reply.Data.Code.Code)
+	slivers := listTestSlivers(s)
+	assert.Len(t, slivers, 1)
+	assert.Equal(
+		t,
+		&v1.SliverPlacement{
+			Countries: []string{"BR"},
+			Nodes:     []string{"node-1"},
+			Labels:    map[string]string{"example.org/gpu": "true"},
+		},
+		slivers[0].Spec.Placement,
+	)
+}
+
+func TestAllocate_BadPlacement(t *testing.T) {
+	tests := []struct {
+		old  string
+		new  string
+		code int
+	}{
+		{`country="br"`, `country="BRA"`, constants.GeniCodeBadargs},
+		{` radius="100"`, ``, constants.GeniCodeBadargs},
+		{`radius="100"`, `radius="0"`, constants.GeniCodeBadargs},
+		{`key="example.org/gpu"`, `key="example.org/fpga"`, constants.GeniCodeBadargs},
+		{`country="br"`, `country="fr"`, constants.GeniCodeRefused},
+		{`value="true"`, `value="false"`, constants.GeniCodeRefused},
+		{`latitude="-23.5" longitude="-46.6" radius="100"`, `latitude="0" longitude="0" radius="100"`, constants.GeniCodeRefused},
+	}
+	for _, test := range tests {
+		s := testService()
+		r := testRequest()
+		createTestPlacementNodes(s)
+		args := &AllocateArgs{
+			SliceURN:    testSliceIdentifier.URN(),
+			Credentials: []Credential{testSliceCredential},
+			Rspec:       strings.Replace(testRspecPlacement, test.old, test.new, 1),
+		}
+		reply := &AllocateReply{}
+		err := s.Allocate(r, args, reply)
+		assert.Nil(t, err)
+		assert.Equal(t, test.code, reply.Data.Code.Code, test.new)
+		assert.Len(t, listTestSlivers(s), 0)
+	}
+}
+
+func TestAllocate_Groups(t *testing.T) {
+	s := testService()
+	r := testRequest()
+	createTestPlacementNodes(s)
+	args := &AllocateArgs{
+		SliceURN:    testSliceIdentifier.URN(),
+		Credentials: []Credential{testSliceCredential},
+		Rspec:       testRspecGroups,
+	}
+	reply := &AllocateReply{}
+	err := s.Allocate(r, args, reply)
+	assert.Nil(t, err)
+	assert.Equal(t, constants.GeniCodeSuccess, reply.Data.Code.Code)
+	groups := make(map[string][]v1.SliverGroup)
+	for _, sliver := range listTestSlivers(s) {
+		groups[sliver.Spec.ClientID] = sliver.Spec.Groups
+	}
+	servers := v1.SliverGroup{Name: "servers", Policy: "spread", Topology: "country"}
+	clients := v1.SliverGroup{Name: "clients", Policy: "colocate", Topology: "hostname"}
+	assert.Equal(t, []v1.SliverGroup{servers}, groups["PC1"])
+	assert.Equal(t, []v1.SliverGroup{servers, clients}, groups["PC2"])
+	assert.Equal(t, []v1.SliverGroup{clients}, groups["PC3"])
+	// The groups must be echoed in the manifest.
+	v := unmarshalTestRspec(reply.Data.Value.Rspec)
+	assert.Len(t, v.Groups, 2)
+	assert.Equal(t, "clients", v.Groups[0].Name)
+	assert.Len(t, v.Groups[0].Members, 2)
+}
+
+func TestAllocate_BadGroups(t *testing.T) {
+	tests := []struct {
+		replacements []string
+		code         int
+	}{
+		{[]string{`policy="spread"`, `policy="scatter"`}, constants.GeniCodeBadargs},
+		{[]string{`topology="country"`, `topology="continent"`}, constants.GeniCodeBadargs},
+		{[]string{`name="clients"`, `name="servers"`}, constants.GeniCodeBadargs},
+		{[]string{`<edgenet:member client_id="PC3"/>`, `<edgenet:member client_id="PC4"/>`}, constants.GeniCodeBadargs},
+		// Three nodes cannot be spread on two countries.
+		{
+			[]string{
+				`<edgenet:member client_id="PC1"/>`,
+				`<edgenet:member client_id="PC1"/><edgenet:member client_id="PC3"/>`,
+			},
+			constants.GeniCodeRefused,
+		},
+		// PC2 and PC3 cannot be both spread and colocated on the nodes.
+		{
+			[]string{
+				`policy="spread" topology="country"`,
+				`policy="spread"`,
+				`<edgenet:member client_id="PC1"/>`,
+				`<edgenet:member client_id="PC3"/>`,
+			},
+			constants.GeniCodeRefused,
+		},
+		// PC1 and PC2 cannot be spread if they are requested in the same country.
+		{
+			[]string{
+				`<node client_id="PC1" component_manager_id`,
+				`<node client_id="PC1" component_id="urn:publicid:IDN+example.org+node+node-1" component_manager_id`,
+				`<node client_id="PC2" component_manager_id`,
+				`<node client_id="PC2" component_id="urn:publicid:IDN+example.org+node+node-1" component_manager_id`,
+			},
+			constants.GeniCodeRefused,
+		},
+		{
+			[]string{
+				`<node client_id="PC2" component_manager_id`,
+				`<node client_id="PC2" component_id="urn:publicid:IDN+example.org+node+node-1" component_manager_id`,
+			},
+			constants.GeniCodeSuccess,
+		},
+	}
+	for _, test := range tests {
+		s := testService()
+		r := testRequest()
+		createTestPlacementNodes(s)
+		args := &AllocateArgs{
+			SliceURN:    testSliceIdentifier.URN(),
+			Credentials: []Credential{testSliceCredential},
+			Rspec:       strings.NewReplacer(test.replacements...).Replace(testRspecGroups),
+		}
+		reply := &AllocateReply{}
+		err := s.Allocate(r, args, reply)
+		assert.Nil(t, err)
+		assert.Equal(t, test.code, reply.Data.Code.Code, test.replacements)
+	}
+}
+
+func createTestExclusiveNodes(s *Service) {
+	// node-1 is free, node-2 runs a sliver, and node-3 cannot be allocated exclusively.
+	nodes := []*corev1.Node{testNode("node-1", true), testNode("node-2", true), testNode("node-3", true)}
+	nodes[0].Labels["example.org/exclusive"] = "true"
+	nodes[1].Labels["example.org/exclusive"] = "true"
+	for _, node := range nodes {
+		_, err := s.Nodes().Create(context.TODO(), node, metav1.CreateOptions{})
+		utils.Check(err)
+	}
+	_, err := s.Pods("").Create(
+		context.TODO(),
+		testPod("pod-1", "node-2", "500m", "1Gi", true),
+		metav1.CreateOptions{},
+	)
+	utils.Check(err)
+}
+
+func TestAllocate_Exclusive(t *testing.T) {
+	s := testService()
+	r := testRequest()
+	createTestExclusiveNodes(s)
+	args := &AllocateArgs{
+		SliceURN:    testSliceIdentifier.URN(),
+		Credentials: []Credential{testSliceCredential},
+		Rspec:       strings.Replace(testRspecSingle, `exclusive="false"`, `exclusive="true"`, 1),
+	}
+	reply := &AllocateReply{}
+	err := s.Allocate(r, args, reply)
+	assert.Nil(t, err)
+	assert.Equal(t, constants.GeniCodeSuccess, reply.Data.Code.Code)
+	slivers := listTestSlivers(s)
+	assert.Len(t, slivers, 1)
+	assert.True(t, slivers[0].Spec.Exclusive)
+	v := unmarshalTestRspec(reply.Data.Value.Rspec)
+	assert.True(t, v.Nodes[0].Exclusive)
+}
+
+func TestAllocate_BadExclusive(t *testing.T) {
+	tests := []struct {
+		rspec              string
+		exclusiveNodeLabel string
+		code               int
+	}{
+		{
+			strings.Replace(testRspecSingle, `exclusive="false"`, `exclusive="true"`, 1),
+			"",
+			constants.GeniCodeUnsupported,
+		},
+		// There is only one free exclusive node.
+		{
+			strings.Replace(testRspecMany, `exclusive="false"`, `exclusive="true"`, -1),
+			"example.org/exclusive",
+			constants.GeniCodeRefused,
+		},
+		{
+			strings.Replace(testRspecSingle, `exclusive="false"`, `exclusive="true"`, 1),
+			"example.org/unknown",
+			constants.GeniCodeRefused,
+		},
+		{
+			strings.Replace(
+				testRspecSingle,
+				`exclusive="false"`,
+				`component_id="urn:publicid:IDN+example.org+node+node-2" exclusive="true"`,
+				1,
+			),
+			"example.org/exclusive",
+			constants.GeniCodeRefused,
+		},
+		{
+			strings.Replace(
+				testRspecSingle,
+				`exclusive="false"`,
+				`component_id="urn:publicid:IDN+example.org+node+node-3" exclusive="true"`,
+				1,
+			),
+			"example.org/exclusive",
+			constants.GeniCodeRefused,
+		},
+	}
+	for _, test := range tests {
+		s := testService()
+		s.ExclusiveNodeLabel = test.exclusiveNodeLabel
+		r := testRequest()
+		createTestExclusiveNodes(s)
+		args := &AllocateArgs{
+			SliceURN:    testSliceIdentifier.URN(),
+			Credentials: []Credential{testSliceCredential},
+			Rspec:       test.rspec,
+		}
+		reply := &AllocateReply{}
+		err := s.Allocate(r, args, reply)
+		assert.Nil(t, err)
+		assert.Equal(t, test.code, reply.Data.Code.Code, test.rspec)
+		assert.Len(t, listTestSlivers(s), 0)
+	}
+}
+
+func TestAllocate_Count(t *testing.T) {
+	s := testService()
+	r := testRequest()
+	createTestPlacementNodes(s)
+	args := &AllocateArgs{
+		SliceURN:    testSliceIdentifier.URN(),
+		Credentials: []Credential{testSliceCredential},
+		Rspec:       testRspecCount,
+	}
+	reply := &AllocateReply{}
+	err := s.Allocate(r, args, reply)
+	assert.Nil(t, err)
+	assert.Equal(t, constants.GeniCodeSuccess, reply.Data.Code.Code)
+	clientIDs := make([]string, 0)
+	for _, sliver := range listTestSlivers(s) {
+		clientIDs = append(clientIDs, sliver.Spec.ClientID)
+		assert.Equal(t, naming.SliverName(testSliceIdentifier.URN(), sliver.Spec.ClientID), sliver.Name)
+		if sliver.Spec.ClientID != "server" {
+			index := strings.TrimPrefix(sliver.Spec.ClientID, "client-")
+			assert.Equal(t, "client:if0-"+index, sliver.Spec.Interfaces[0].ClientID)
+			assert.Len(t, sliver.Spec.Groups, 1)
+		}
+	}
+	assert.ElementsMatch(t, []string{"server", "client-0", "client-1", "client-2"}, clientIDs)
+	// Each copy must be listed in the manifest.
+	v := unmarshalTestRspec(reply.Data.Value.Rspec)
+	assert.Len(t, v.Nodes, 4)
+	assert.Len(t, v.Links, 1)
+	assert.Len(t, v.Links[0].InterfaceRefs, 4)
+	assert.Equal(t, "client:if0-2", v.Links[0].InterfaceRefs[3].ClientID)
+	assert.Len(t, v.Groups[0].Members, 3)
+}
+
+func TestAllocate_BadCount(t *testing.T) {
+	tests := []struct {
+		old string
+		new string
+	}{
+		{`edgenet:count="3"`, `edgenet:count="0"`},
+		{`edgenet:count="3"`, `edgenet:count="11"`},
+		// The server and the copies of the client exceed the limit.
+		{`edgenet:count="3"`, `edgenet:count="10"`},
+		{`edgenet:count="3"`, `edgenet:count="three"`},
+		{`<node client_id="server"`, `<node client_id="client-1"`},
+		{`<interface client_id="client:if0"/>`, `<interface client_id="client:if0"><ip address="10.0.0.1"/></interface>`},
+		{`</link>`, `<property source_id="server:if0" dest_id="client:if0" latency="10"/></link>`},
+	}
+	for _, test := range tests {
+		s := testService()
+		r := testRequest()
+		createTestPlacementNodes(s)
+		args := &AllocateArgs{
+			SliceURN:    testSliceIdentifier.URN(),
+			Credentials: []Credential{testSliceCredential},
+			Rspec:       strings.Replace(testRspecCount, test.old, test.new, 1),
+		}
+		reply := &AllocateReply{}
+		err := s.Allocate(r, args, reply)
+		assert.Nil(t, err)
+		assert.Equal(t, constants.GeniCodeBadargs, reply.Data.Code.Code, test.new)
+		assert.Len(t, listTestSlivers(s), 0)
+	}
+}
+
+func TestAllocate_Atomic(t *testing.T) {
+	s := testService()
+	r := testRequest()
+	// The second node fails after the first one is created.
+	rspec := strings.Replace(
+		testRspecMany,
+		`<hardware_type name="amd64"/>`,
+		`<hardware_type name="amd64"/><edgenet:resources xmlns:edgenet="http://www.edge-net.org/resources/rspec/ext/1" cpu="three"/>`,
+		1,
+	)
+	args := &AllocateArgs{
+		SliceURN:    testSliceIdentifier.URN(),
+		Credentials: []Credential{testSliceCredential},
+		Rspec:       rspec,
+	}
+	reply := &AllocateReply{}
+	err := s.Allocate(r, args, reply)
+	assert.Nil(t, err)
+	assert.Equal(t, constants.GeniCodeBadargs, reply.Data.Code.Code)
+	assert.Len(t, listTestSlivers(s), 0)
+	// The slivers of a previous allocation must be kept.
+	allocateTestSlice(s, r, testRspecSingle)
+	reply = &AllocateReply{}
+	err = s.Allocate(r, args, reply)
+	assert.Nil(t, err)
+	assert.Equal(t, constants.GeniCodeBadargs, reply.Data.Code.Code)
+	assert.Len(t, listTestSlivers(s), 1)
+}
+
+func TestAllocate_Ports(t *testing.T) {
+	s := testService()
+	r := testRequest()
+	args := &AllocateArgs{
+		SliceURN:    testSliceIdentifier.URN(),
+		Credentials: []Credential{testSliceCredential},
+		Rspec:       testRspecPorts,
+	}
+	reply := &AllocateReply{}
+	err := s.Allocate(r, args, reply)
+	assert.Nil(t, err)
+	assert.Equal(t, constants.GeniCodeSuccess, reply.Data.Code.Code)
+	slivers := listTestSlivers(s)
+	assert.Len(t, slivers, 1)
+	assert.Equal(
+		t,
+		[]v1.SliverPort{{Port: 8080, Protocol: "TCP"}, {Port: 5353, Protocol: "UDP"}},
+		slivers[0].Spec.Ports,
+	)
+	// The ports are not exposed until the sliver is provisioned.
+	v := unmarshalTestRspec(reply.Data.Value.Rspec)
+	assert.Len(t, v.Nodes[0].Ports, 2)
+	assert.Equal(t, "", v.Nodes[0].Ports[0].Host)
+}
+
+func TestAllocate_BadHostNames(t *testing.T) {
+	s := testService()
+	r := testRequest()
+	args := &AllocateArgs{
+		SliceURN:    testSliceIdentifier.URN(),
+		Credentials: []Credential{testSliceCredential},
+		Rspec:       strings.Replace(testRspecLinks, `client_id="PC2"`, `client_id="pc1"`, 1),
+	}
+	reply := &AllocateReply{}
+	err := s.Allocate(r, args, reply)
+	assert.Nil(t, err)
+	assert.Equal(t, constants.GeniCodeBadargs, reply.Data.Code.Code)
+	assert.Len(t, listTestSlivers(s), 0)
+
+	// The new nodes are checked against the existing slivers of the slice.
+	for _, test := range []struct {
+		clientID string
+		code     int
+	}{
+		{"PC1", constants.GeniCodeSuccess},
+		{"pc1", constants.GeniCodeBadargs},
+		{"PC1", constants.GeniCodeSuccess},
+	} {
+		args.Rspec = strings.Replace(testRspecPorts, `client_id="PC1"`, `client_id="`+test.clientID+`"`, 1)
+		reply = &AllocateReply{}
+		err = s.Allocate(r, args, reply)
+		assert.Nil(t, err)
+		assert.Equal(t, test.code, reply.Data.Code.Code, test.clientID)
+	}
+	assert.Len(t, listTestSlivers(s), 1)
+}
+
+func TestAllocate_Ingress(t *testing.T) {
+	rspec := strings.Replace(
+		testRspecPorts,
+		`<edgenet:port number="8080"/>`,
+		`<edgenet:port number="8080" ingress="true"/>`,
+		1,
+	)
+	s := testService()
+	r := testRequest()
+	args := &AllocateArgs{
+		SliceURN:    testSliceIdentifier.URN(),
+		Credentials: []Credential{testSliceCredential},
+		Rspec:       rspec,
+	}
+	reply := &AllocateReply{}
+	err := s.Allocate(r, args, reply)
+	assert.Nil(t, err)
+	assert.Equal(t, constants.GeniCodeSuccess, reply.Data.Code.Code)
+	slivers := listTestSlivers(s)
+	assert.Len(t, slivers, 1)
+	assert.Equal(
+		t,
+		[]v1.SliverPort{{Port: 8080, Protocol: "TCP", Ingress: true}, {Port: 5353, Protocol: "UDP"}},
+		slivers[0].Spec.Ports,
+	)
+	v := unmarshalTestRspec(reply.Data.Value.Rspec)
+	assert.True(t, v.Nodes[0].Ports[0].Ingress)
+	assert.Equal(
+		t,
+		"http://pc1-8080-"+naming.SliceHash(testSliceIdentifier.URN())+".slivers.example.org/",
+		v.Nodes[0].Ports[0].URL,
+	)
+	assert.Equal(t, "", v.Nodes[0].Ports[1].URL)
+
+	// The ingresses are not supported without a domain.
+	s = testService()
+	s.IngressDomain = ""
+	reply = &AllocateReply{}
+	err = s.Allocate(r, args, reply)
+	assert.Nil(t, err)
+	assert.Equal(t, constants.GeniCodeUnsupported, reply.Data.Code.Code)
+	assert.Len(t, listTestSlivers(s), 0)
+}
+
+func TestAllocate_IngressCollision(t *testing.T) {
+	node := `<node client_id="%s" component_manager_id="urn:publicid:IDN+example.org+authority+am" exclusive="false">
+    <sliver_type name="container"/>
+    <edgenet:port number="8080" ingress="true"/>
+  </node>`
+	rspec := func(clientIDs ...string) string {
+		nodes := make([]string, 0)
+		for _, clientID := range clientIDs {
+			nodes = append(nodes, fmt.Sprintf(node, clientID))
+		}
+		return `<rspec type="request" xmlns="http://www.geni.net/resources/rspec/3" xmlns:edgenet="http://www.edge-net.org/resources/rspec/ext/1">` +
+			strings.Join(nodes, "") +
+			`</rspec>`
+	}
+	s := testService()
+	// The hostnames of the slivers do not collide without a cluster domain, but the ones of their ingresses do.
+	s.ClusterDomain = ""
+	r := testRequest()
+	allocate := func(rspec string) int {
+		args := &AllocateArgs{
+			SliceURN:    testSliceIdentifier.URN(),
+			Credentials: []Credential{testSliceCredential},
+			Rspec:       rspec,
+		}
+		reply := &AllocateReply{}
+		err := s.Allocate(r, args, reply)
+		assert.Nil(t, err)
+		return reply.Data.Code.Code
+	}
+	assert.Equal(t, constants.GeniCodeBadargs, allocate(rspec("Web_1", "web-1")))
+	assert.Len(t, listTestSlivers(s), 0)
+	// The new slivers are checked against the existing ones.
+	assert.Equal(t, constants.GeniCodeSuccess, allocate(rspec("Web_1")))
+	assert.Equal(t, constants.GeniCodeBadargs, allocate(rspec("web-1")))
+	assert.Equal(t, constants.GeniCodeSuccess, allocate(rspec("Web_1")))
+	assert.Len(t, listTestSlivers(s), 1)
+}
+
+func TestAllocate_BadPorts(t *testing.T) {
+	for _, port := range []string{
+		`<edgenet:port number="0"/>`,
+		`<edgenet:port number="http"/>`,
+		`<edgenet:port number="8080" protocol="sctp"/>`,
+		`<edgenet:port number="22"/>`,
+		`<edgenet:port number="5353" protocol="UDP"/>`,
+		`<edgenet:port number="53" protocol="udp" ingress="true"/>`,
+	} {
+		s := testService()
+		r := testRequest()
+		args := &AllocateArgs{
+			SliceURN:    testSliceIdentifier.URN(),
+			Credentials: []Credential{testSliceCredential},
+			Rspec: strings.Replace(
+				testRspecPorts,
+				`<edgenet:port number="8080"/>`,
+				port,
+				1,
+			),
+		}
+		reply := &AllocateReply{}
+		err := s.Allocate(r, args, reply)
+		assert.Nil(t, err)
+		assert.Equal(t, constants.GeniCodeBadargs, reply.Data.Code.Code, port)
+		assert.Len(t, listTestSlivers(s), 0)
+	}
+}
+
+func TestAllocate_Volumes(t *testing.T) {
+	s := testService()
+	r := testRequest()
+	args := &AllocateArgs{
+		SliceURN:    testSliceIdentifier.URN(),
+		Credentials: []Credential{testSliceCredential},
+		Rspec:       testRspecVolumes,
+	}
+	reply := &AllocateReply{}
+	err := s.Allocate(r, args, reply)
+	assert.Nil(t, err)
+	assert.Equal(t, constants.GeniCodeSuccess, reply.Data.Code.Code)
+	for _, sliver := range listTestSlivers(s) {
+		if sliver.Spec.ClientID == "PC1" {
+			assert.Equal(t, []v1.SliverVolume{
+				{Name: "data", Size: "1Gi", Path: "/data"},
+				{Name: "shared", Size: "5Gi", Path: "/shared", Shared: true},
+			}, sliver.Spec.Volumes)
+		} else {
+			assert.Equal(t, []v1.SliverVolume{
+				{Name: "shared", Size: "5Gi", Path: "/mnt/shared", Shared: true},
+			}, sliver.Spec.Volumes)
+		}
+	}
+	v := unmarshalTestRspec(reply.Data.Value.Rspec)
+	for _, node := range v.Nodes {
+		assert.NotEmpty(t, node.Volumes)
+	}
+
+	// The volumes are not supported without a storage class.
+	for _, configure := range []func(s *Service){
+		func(s *Service) { s.StorageClass = "" },
+		func(s *Service) { s.SharedStorageClass = "" },
+	} {
+		s = testService()
+		configure(s)
+		reply = &AllocateReply{}
+		err = s.Allocate(r, args, reply)
+		assert.Nil(t, err)
+		assert.Equal(t, constants.GeniCodeUnsupported, reply.Data.Code.Code)
+		assert.Len(t, listTestSlivers(s), 0)
+	}
+}
+
+func TestAllocate_BadVolumes(t *testing.T) {
+	for _, volume := range []string{
+		`<edgenet:volume name="Data" size="1Gi" path="/data"/>`,
+		`<edgenet:volume name="data" size="100Gi" path="/data"/>`,
+		`<edgenet:volume name="data" size="0" path="/data"/>`,
+		`<edgenet:volume name="data" size="1Gi" path="data"/>`,
+		`<edgenet:volume name="data" size="1Gi" path="/"/>`,
+		`<edgenet:volume name="data" size="1Gi" path="/shared"/>`,
+		`<edgenet:volume name="shared" size="1Gi" path="/data"/>`,
+		`<edgenet:volume name="shared" size="5Gi" path="/data" shared="true" retain="true"/>`,
+	} {
+		s := testService()
+		r := testRequest()
+		args := &AllocateArgs{
+			SliceURN:    testSliceIdentifier.URN(),
+			Credentials: []Credential{testSliceCredential},
+			Rspec: strings.Replace(
+				testRspecVolumes,
+				`<edgenet:volume name="data" size="1024Mi" path="/data/"/>`,
+				volume,
+				1,
+			),
+		}
+		if strings.Contains(volume, "retain") {
+			// The shared volume of PC1 must be the only one, for the one of PC2 to conflict with it.
+			args.Rspec = strings.Replace(
+				args.Rspec,
+				`<edgenet:volume name="shared" size="5Gi" path="/shared" shared="true"/>`,
+				"",
+				1,
+			)
+		}
+		reply := &AllocateReply{}
+		err := s.Allocate(r, args, reply)
+		assert.Nil(t, err)
+		assert.Equal(t, constants.GeniCodeBadargs, reply.Data.Code.Code, volume)
+		assert.Len(t, listTestSlivers(s), 0)
+	}
+}
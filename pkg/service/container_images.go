@@ -0,0 +1,121 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	v1 "github.com/EdgeNet-project/fed4fire/pkg/apis/fed4fire/v1"
+	"github.com/EdgeNet-project/fed4fire/pkg/identifiers"
+	"github.com/EdgeNet-project/fed4fire/pkg/imagepolicy"
+	"github.com/EdgeNet-project/fed4fire/pkg/rspec"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+	"sort"
+)
+
+// ListContainerImages returns the images that can be deployed:
+// the images of the ContainerImage catalog sorted by name, followed by
+// the images given on the command line which are not defined in the catalog.
+// The catalog is read on each call, so that images can be added without restarting the AM.
+func (s Service) ListContainerImages(ctx context.Context) ([]v1.ContainerImage, error) {
+	images := make([]v1.ContainerImage, 0)
+	catalog, err := s.ContainerImageCatalog().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		// The AM can still be deployed without the ContainerImage CRD.
+		if !errors.IsNotFound(err) {
+			return nil, err
+		}
+		klog.V(1).InfoS("ContainerImage resource not found, using the command line images only")
+	} else {
+		images = append(images, catalog.Items...)
+	}
+	sort.Slice(images, func(i, j int) bool {
+		return images[i].Name < images[j].Name
+	})
+	for _, image := range s.ContainerImages {
+		if findContainerImage(images, image.Name) == nil {
+			images = append(images, image)
+		}
+	}
+	return images, nil
+}
+
+// defaultContainerImage returns the first image marked as default, or the first image if none is.
+// It returns nil if there are no images.
+func defaultContainerImage(images []v1.ContainerImage) *v1.ContainerImage {
+	for i := range images {
+		if images[i].Spec.Default {
+			return &images[i]
+		}
+	}
+	if len(images) > 0 {
+		return &images[0]
+	}
+	return nil
+}
+
+// findContainerImage returns the image with the given name, or nil if it does not exist.
+func findContainerImage(images []v1.ContainerImage, name string) *v1.ContainerImage {
+	for i := range images {
+		if images[i].Name == name {
+			return &images[i]
+		}
+	}
+	return nil
+}
+
+// findDiskImage returns the image requested by an RSpec disk_image element,
+// given either by name or by URN, or nil if it does not exist.
+func (s Service) findDiskImage(images []v1.ContainerImage, name string) *v1.ContainerImage {
+	for i := range images {
+		urn := s.AuthorityIdentifier.Copy(identifiers.ResourceTypeImage, images[i].Name).URN()
+		if images[i].Name == name || urn == name {
+			return &images[i]
+		}
+	}
+	return nil
+}
+
+// customImageReference returns the OCI reference of a disk image requested outside of the catalog,
+// either with the url attribute or with a name including the registry, or an empty string otherwise.
+func (s Service) customImageReference(images []v1.ContainerImage, diskImages []rspec.DiskImage) string {
+	if len(diskImages) == 0 {
+		return ""
+	}
+	diskImage := diskImages[0]
+	if diskImage.URL != "" {
+		return diskImage.URL
+	}
+	if s.findDiskImage(images, diskImage.Name) == nil && imagepolicy.IsReference(diskImage.Name) {
+		return diskImage.Name
+	}
+	return ""
+}
+
+// imageReference returns the OCI reference of the image to run on nodes of the given architecture,
+// or an error if the image does not support it. The main reference is returned if arch is empty.
+func imageReference(image v1.ContainerImage, arch string) (string, error) {
+	if arch == "" {
+		return image.Spec.Image, nil
+	}
+	for _, variant := range image.Spec.Variants {
+		if variant.Architecture == arch {
+			return variant.Image, nil
+		}
+	}
+	if len(image.Spec.Architectures) == 0 {
+		return image.Spec.Image, nil
+	}
+	for _, arch_ := range image.Spec.Architectures {
+		if arch_ == arch {
+			return image.Spec.Image, nil
+		}
+	}
+	return "", fmt.Errorf("image %s does not support the %s architecture", image.Name, arch)
+}
+
+// supportsArch returns true if the image can run on nodes of the given architecture.
+func supportsArch(image v1.ContainerImage, arch string) bool {
+	_, err := imageReference(image, arch)
+	return err == nil
+}
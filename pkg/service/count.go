@@ -0,0 +1,115 @@
+package service
+
+import (
+	"fmt"
+	"github.com/EdgeNet-project/fed4fire/pkg/rspec"
+	"strconv"
+)
+
+// expandNodeCounts replaces the nodes of a request RSpec with an edgenet:count attribute by as many copies,
+// whose client IDs are suffixed with their index, e.g. PC-0 to PC-99 for PC.
+// The interfaces of the copies are suffixed in the same way, and replace the original ones in the links and the groups.
+// The number of nodes of the request, once expanded, is limited to maxCount.
+func expandNodeCounts(request rspec.Rspec, maxCount int) (rspec.Rspec, error) {
+	expanded := request
+	expanded.Nodes = make([]rspec.Node, 0, len(request.Nodes))
+	// Client IDs of the copies of the nodes and of the interfaces, by original client ID.
+	copies := make(map[string][]string)
+	for _, node := range request.Nodes {
+		if node.Count == "" {
+			expanded.Nodes = append(expanded.Nodes, node)
+			continue
+		}
+		count, err := strconv.Atoi(node.Count)
+		if err != nil || count < 1 || count > maxCount {
+			return rspec.Rspec{}, fmt.Errorf("count of node %s must be between 1 and %d", node.ClientID, maxCount)
+		}
+		// Checked before the copies are made, so that the expansion is bounded.
+		if len(expanded.Nodes)+count > maxCount {
+			return rspec.Rspec{}, fmt.Errorf("a request cannot have more than %d nodes, copies included", maxCount)
+		}
+		for _, interface_ := range node.Interfaces {
+			if len(interface_.IPs) > 0 {
+				return rspec.Rspec{}, fmt.Errorf(
+					"interface %s of node %s cannot have an address since the node has a count",
+					interface_.ClientID,
+					node.ClientID,
+				)
+			}
+		}
+		for i := 0; i < count; i++ {
+			node_ := node
+			node_.ClientID = fmt.Sprintf("%s-%d", node.ClientID, i)
+			node_.Count = ""
+			node_.Interfaces = make([]rspec.Interface, len(node.Interfaces))
+			for j, interface_ := range node.Interfaces {
+				node_.Interfaces[j] = interface_
+				node_.Interfaces[j].ClientID = fmt.Sprintf("%s-%d", interface_.ClientID, i)
+				copies[interface_.ClientID] = append(copies[interface_.ClientID], node_.Interfaces[j].ClientID)
+			}
+			copies[node.ClientID] = append(copies[node.ClientID], node_.ClientID)
+			expanded.Nodes = append(expanded.Nodes, node_)
+		}
+	}
+	if len(expanded.Nodes) > maxCount {
+		return rspec.Rspec{}, fmt.Errorf("a request cannot have more than %d nodes, copies included", maxCount)
+	}
+	if len(copies) == 0 {
+		return request, nil
+	}
+
+	clientIDs := make(map[string]bool)
+	for _, node := range expanded.Nodes {
+		if clientIDs[node.ClientID] {
+			return rspec.Rspec{}, fmt.Errorf("duplicate node client_id %s after expanding the counts", node.ClientID)
+		}
+		clientIDs[node.ClientID] = true
+	}
+
+	expanded.Links = make([]rspec.Link, len(request.Links))
+	for i, link := range request.Links {
+		expanded.Links[i] = link
+		expanded.Links[i].InterfaceRefs = make([]rspec.InterfaceRef, 0, len(link.InterfaceRefs))
+		for _, ref := range link.InterfaceRefs {
+			if clientIDs, ok := copies[ref.ClientID]; ok {
+				for _, clientID := range clientIDs {
+					expanded.Links[i].InterfaceRefs = append(
+						expanded.Links[i].InterfaceRefs,
+						rspec.InterfaceRef{ClientID: clientID},
+					)
+				}
+			} else {
+				expanded.Links[i].InterfaceRefs = append(expanded.Links[i].InterfaceRefs, ref)
+			}
+		}
+		for _, property := range link.Properties {
+			_, source := copies[property.SourceID]
+			_, dest := copies[property.DestID]
+			if source || dest {
+				return rspec.Rspec{}, fmt.Errorf(
+					"property of link %s cannot refer to the interface of a node with a count",
+					link.ClientID,
+				)
+			}
+		}
+	}
+
+	expanded.Groups = make([]rspec.Group, len(request.Groups))
+	for i, group := range request.Groups {
+		expanded.Groups[i] = group
+		expanded.Groups[i].Members = make([]rspec.GroupMember, 0, len(group.Members))
+		for _, member := range group.Members {
+			if clientIDs, ok := copies[member.ClientID]; ok {
+				for _, clientID := range clientIDs {
+					expanded.Groups[i].Members = append(
+						expanded.Groups[i].Members,
+						rspec.GroupMember{ClientID: clientID},
+					)
+				}
+			} else {
+				expanded.Groups[i].Members = append(expanded.Groups[i].Members, member)
+			}
+		}
+	}
+	return expanded, nil
+}
@@ -41,16 +41,29 @@ func (s *Service) Delete(r *http.Request, args *DeleteArgs, reply *DeleteReply)
 		return reply.SetAndLogError(err, constants.ErrorListResources)
 	}
 
+	sliceUrns := make(map[string]bool)
 	for _, sliver := range slivers {
-		err := s.Slivers().Delete(r.Context(), sliver.Name, metav1.DeleteOptions{})
+		err := s.Slivers(sliver.Namespace).Delete(r.Context(), sliver.Name, metav1.DeleteOptions{})
 		if err != nil {
 			return reply.SetAndLogError(err, constants.ErrorDeleteResource)
 		}
-		allocationStatus, operationalStatus := s.GetSliverStatus(r.Context(), sliver.Name)
+		allocationStatus, operationalStatus := s.GetSliverStatus(
+			r.Context(),
+			sliver.Namespace,
+			sliver.Name,
+		)
 		reply.Data.Value = append(
 			reply.Data.Value,
 			NewSliver(sliver, allocationStatus, operationalStatus),
 		)
+		sliceUrns[sliver.Spec.SliceURN] = true
+	}
+
+	for sliceUrn := range sliceUrns {
+		err := deleteSliceNamespaceIfEmpty(r.Context(), *s, sliceUrn)
+		if err != nil {
+			return reply.SetAndLogError(err, constants.ErrorDeleteResource)
+		}
 	}
 
 	reply.Data.Code.Code = constants.GeniCodeSuccess
@@ -3,7 +3,6 @@ package service
 import (
 	"fmt"
 	"github.com/EdgeNet-project/fed4fire/pkg/constants"
-	"github.com/EdgeNet-project/fed4fire/pkg/rspec"
 	"k8s.io/klog/v2"
 	"net/http"
 )
@@ -42,38 +41,12 @@ func (s *Service) Describe(r *http.Request, args *DescribeArgs, reply *DescribeR
 		return reply.SetAndLogError(err, constants.ErrorListResources)
 	}
 
-	returnRspec := rspec.Rspec{Type: rspec.RspecTypeManifest}
-
 	for _, sliver := range slivers {
-		available := rspec.Available{Now: false}
-		var hardwareType *rspec.HardwareType
-		var services *rspec.Services
-		arch, host, port := s.GetSliverArchHostPort(r.Context(), sliver.Name)
-		if arch != nil && host != nil && port != nil {
-			available.Now = true
-			services = &rspec.Services{
-				Logins: []rspec.Login{{
-					Authentication: rspec.RspecLoginAuthenticationSSH,
-					Hostname:       *host,
-					Port:           *port,
-					Username:       "root",
-				}},
-			}
-		}
-		returnRspec.Nodes = append(returnRspec.Nodes, rspec.Node{
-			// TODO: Node component ID / name
-			ComponentManagerID: s.AuthorityIdentifier.URN(),
-			ClientID:           sliver.Spec.ClientID,
-			SliverID:           sliver.Spec.URN,
-			Available:          &available,
-			Exclusive:          false,
-			HardwareType:       hardwareType,
-			Services:           services,
-			SliverType: rspec.SliverType{
-				Name: "container",
-			},
-		})
-		allocationStatus, operationalStatus := s.GetSliverStatus(r.Context(), sliver.Name)
+		allocationStatus, operationalStatus := s.GetSliverStatus(
+			r.Context(),
+			sliver.Namespace,
+			sliver.Name,
+		)
 		// The spec. says that all the requested slivers belong to the same slice,
 		// so it's safe to retrieve the slice URN from any sliver.
 		reply.Data.Value.URN = sliver.Spec.SliceURN
@@ -83,6 +56,10 @@ func (s *Service) Describe(r *http.Request, args *DescribeArgs, reply *DescribeR
 		)
 	}
 
+	returnRspec, err := s.manifestRspec(r.Context(), slivers)
+	if err != nil {
+		return reply.SetAndLogError(err, constants.ErrorDeserializeRspec)
+	}
 	xml_, err := MarshalRspec(returnRspec, args.Options.Compressed)
 	if err != nil {
 		return reply.SetAndLogError(err, constants.ErrorSerializeRspec)
@@ -1,8 +1,12 @@
 package service
 
 import (
+	"fmt"
 	"github.com/EdgeNet-project/fed4fire/pkg/constants"
 	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -20,3 +24,107 @@ func TestDescribe(t *testing.T) {
 	assert.Equal(t, constants.GeniCodeSuccess, reply.Data.Code.Code)
 	assert.Len(t, reply.Data.Value.Slivers, 2)
 }
+
+func TestDescribe_Links(t *testing.T) {
+	s := testService()
+	r := testRequest()
+	allocateTestSlice(s, r, testRspecLinks)
+	args := &DescribeArgs{
+		URNs:        []string{testSliceIdentifier.URN()},
+		Credentials: []Credential{testSliceCredential},
+	}
+	reply := &DescribeReply{}
+	err := s.Describe(r, args, reply)
+	assert.Nil(t, err)
+	assert.Equal(t, constants.GeniCodeSuccess, reply.Data.Code.Code)
+	v := unmarshalTestRspec(reply.Data.Value.Rspec)
+	assert.Len(t, v.Links, 1)
+	assert.Len(t, v.Links[0].InterfaceRefs, 2)
+	assert.Len(t, v.Nodes, 2)
+	for _, node := range v.Nodes {
+		assert.Len(t, node.Interfaces, 1)
+	}
+}
+
+func TestDescribe_LinkProperties(t *testing.T) {
+	s := testService()
+	r := testRequest()
+	allocateTestSlice(s, r, fmt.Sprintf(testRspecLinkProperties, `capacity="10000" latency="20"`))
+	args := &DescribeArgs{
+		URNs:        []string{testSliceIdentifier.URN()},
+		Credentials: []Credential{testSliceCredential},
+	}
+	reply := &DescribeReply{}
+	err := s.Describe(r, args, reply)
+	assert.Nil(t, err)
+	assert.Equal(t, constants.GeniCodeSuccess, reply.Data.Code.Code)
+	v := unmarshalTestRspec(reply.Data.Value.Rspec)
+	assert.Len(t, v.Links, 1)
+	assert.Len(t, v.Links[0].Properties, 1)
+	property := v.Links[0].Properties[0]
+	assert.Equal(t, "PC1:if0", property.SourceID)
+	assert.Equal(t, "PC2:if0", property.DestID)
+	assert.Equal(t, "10000", property.Capacity)
+	assert.Equal(t, "20", property.Latency)
+	assert.Equal(t, "", property.PacketLoss)
+}
+
+func TestDescribe_Services(t *testing.T) {
+	s := testService()
+	r := testRequest()
+	allocateTestSlice(s, r, testRspecServices)
+	args := &DescribeArgs{
+		URNs:        []string{testSliceIdentifier.URN()},
+		Credentials: []Credential{testSliceCredential},
+	}
+	reply := &DescribeReply{}
+	err := s.Describe(r, args, reply)
+	assert.Nil(t, err)
+	assert.Equal(t, constants.GeniCodeSuccess, reply.Data.Code.Code)
+	v := unmarshalTestRspec(reply.Data.Value.Rspec)
+	assert.Len(t, v.Nodes[0].Services.Logins, 0)
+	assert.Len(t, v.Nodes[0].Services.Installs, 2)
+	assert.Equal(t, "echo 'Hello' > /tmp/hello", v.Nodes[0].Services.Executes[0].Command)
+}
+
+func TestDescribe_Extensions(t *testing.T) {
+	s := testService()
+	r := testRequest()
+	allocateTestSlice(s, r, testRspecExtensions)
+	args := &DescribeArgs{
+		URNs:        []string{testSliceIdentifier.URN()},
+		Credentials: []Credential{testSliceCredential},
+	}
+	reply := &DescribeReply{}
+	err := s.Describe(r, args, reply)
+	assert.Nil(t, err)
+	assert.Equal(t, constants.GeniCodeSuccess, reply.Data.Code.Code)
+	v := unmarshalTestRspec(reply.Data.Value.Rspec)
+	assert.Len(t, v.Nodes[0].Extensions, 2)
+	assert.Equal(t, "http://jfed.iminds.be/rspec/ext/jfed/1", v.Nodes[0].Extensions[1].XMLName.Space)
+	assert.Equal(t, "location", v.Nodes[0].Extensions[1].XMLName.Local)
+	assert.Contains(t, reply.Data.Value.Rspec, "<emulab:routable_control_ip>")
+}
+
+func TestDescribe_JFed(t *testing.T) {
+	b, err := ioutil.ReadFile(filepath.Join("..", "rspec", "testdata", "jfed_lan.xml"))
+	assert.Nil(t, err)
+	s := testService()
+	// jFed assigns the addresses of 192.168.0.0/24 to the first link.
+	s.LinkSubnet = "192.168.0.0/16"
+	r := testRequest()
+	allocateTestSlice(s, r, strings.ReplaceAll(string(b), "edge-net.org", "example.org"))
+	args := &DescribeArgs{
+		URNs:        []string{testSliceIdentifier.URN()},
+		Credentials: []Credential{testSliceCredential},
+	}
+	reply := &DescribeReply{}
+	err = s.Describe(r, args, reply)
+	assert.Nil(t, err)
+	assert.Equal(t, constants.GeniCodeSuccess, reply.Data.Code.Code)
+	// The namespace declarations and the link extensions of the request are echoed without the request.
+	assert.Contains(t, reply.Data.Value.Rspec, `xmlns:jfed="http://jfed.iminds.be/rspec/ext/jfed/1"`)
+	assert.Contains(t, reply.Data.Value.Rspec, `<jfed:link_layout><jfed:point x="200.0" y="120.0"></jfed:point>`)
+	assert.Equal(t, 1, strings.Count(reply.Data.Value.Rspec, "<jfed:link_layout>"))
+	assert.Equal(t, 2, strings.Count(reply.Data.Value.Rspec, "<emulab:routable_control_ip>"))
+}
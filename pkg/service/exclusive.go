@@ -0,0 +1,69 @@
+package service
+
+import (
+	"fmt"
+	v1 "github.com/EdgeNet-project/fed4fire/pkg/apis/fed4fire/v1"
+	"github.com/EdgeNet-project/fed4fire/pkg/constants"
+	"github.com/EdgeNet-project/fed4fire/pkg/identifiers"
+	"github.com/EdgeNet-project/fed4fire/pkg/rspec"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// nodeIsExclusive returns true if a node can be allocated exclusively to a sliver.
+func nodeIsExclusive(node corev1.Node, exclusiveNodeLabel string) bool {
+	return exclusiveNodeLabel != "" && node.Labels[exclusiveNodeLabel] == "true"
+}
+
+// checkExclusiveNodes returns an error if the free exclusive nodes cannot satisfy the exclusive nodes of a request RSpec.
+// A node is free if it is ready and no sliver runs on it.
+func checkExclusiveNodes(
+	s Service,
+	request rspec.Rspec,
+	groups map[string][]v1.SliverGroup,
+	nodes []corev1.Node,
+	usages map[string]nodeUsage,
+) error {
+	free := make(map[string]bool)
+	for _, node := range nodes {
+		if nodeIsExclusive(node, s.ExclusiveNodeLabel) && nodeIsReady(node) && usages[node.Name].Slivers == 0 {
+			free[node.Name] = true
+		}
+	}
+	requested := 0
+	for _, node := range request.Nodes {
+		if !node.Exclusive {
+			continue
+		}
+		requested++
+		if componentID, err := identifiers.Parse(node.ComponentID); err == nil && !free[componentID.ResourceName] {
+			return fmt.Errorf("node %s is not a free exclusive node", componentID.ResourceName)
+		}
+		for _, group := range groups[node.ClientID] {
+			if group.Policy == rspec.GroupPolicyColocate && group.Topology == rspec.GroupTopologyHostname {
+				return fmt.Errorf("exclusive node %s cannot be colocated with other nodes", node.ClientID)
+			}
+		}
+	}
+	if requested > len(free) {
+		return fmt.Errorf("%d exclusive nodes are requested but only %d are free", requested, len(free))
+	}
+	return nil
+}
+
+// exclusiveAntiAffinityTerm returns the pod anti-affinity term of an exclusive sliver, which prevents the other slivers,
+// of any slice, from being scheduled on its node. The term is symmetric: the scheduler also enforces it for the
+// slivers scheduled after the exclusive one.
+func exclusiveAntiAffinityTerm() corev1.PodAffinityTerm {
+	return corev1.PodAffinityTerm{
+		LabelSelector: &metav1.LabelSelector{
+			MatchExpressions: []metav1.LabelSelectorRequirement{{
+				Key:      constants.Fed4FireSliverName,
+				Operator: metav1.LabelSelectorOpExists,
+			}},
+		},
+		// The empty selector matches all the namespaces, when -namespacePerSlice is used.
+		NamespaceSelector: &metav1.LabelSelector{},
+		TopologyKey:       corev1.LabelHostname,
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"net/http"
 
 	"github.com/EdgeNet-project/fed4fire/pkg/constants"
+	"github.com/EdgeNet-project/fed4fire/pkg/rspec"
 )
 
 type APIVersions struct {
@@ -40,6 +41,8 @@ type GetVersionReply struct {
 			SingleAllocation int `xml:"geni_single_allocation"`
 			// Defines whether this AM allows adding slivers to slices at an AM.
 			Allocate string `xml:"geni_allocate"`
+			// Types of the links supported by this aggregate, empty if links are not supported.
+			LinkTypes []string `xml:"edgenet_link_types"`
 		} `xml:"value"`
 	}
 }
@@ -58,6 +61,9 @@ func (s *Service) GetVersion(r *http.Request, args *GetVersionArgs, reply *GetVe
 			Version:   "3",
 			Schema:    "http://www.geni.net/resources/rspec/3/request.xsd",
 			Namespace: "http://www.geni.net/resources/rspec/3",
+			Extensions: []string{
+				rspec.RspecExtensionEdgeNet,
+			},
 		},
 	}
 	reply.Data.Value.AdRspecVersions = []RspecVersion{
@@ -66,6 +72,10 @@ func (s *Service) GetVersion(r *http.Request, args *GetVersionArgs, reply *GetVe
 			Version:   "3",
 			Schema:    "http://www.geni.net/resources/rspec/3/ad.xsd",
 			Namespace: "http://www.geni.net/resources/rspec/3",
+			Extensions: []string{
+				rspec.RspecExtensionEdgeNet,
+				rspec.RspecExtensionOpstate,
+			},
 		},
 	}
 	reply.Data.Value.CredentialTypes = []CredentialType{
@@ -76,6 +86,10 @@ func (s *Service) GetVersion(r *http.Request, args *GetVersionArgs, reply *GetVe
 	}
 	reply.Data.Value.SingleAllocation = 0
 	reply.Data.Value.Allocate = constants.GeniAllocateMany
+	reply.Data.Value.LinkTypes = []string{}
+	if s.LinkCniConfig != nil {
+		reply.Data.Value.LinkTypes = append(reply.Data.Value.LinkTypes, rspec.RspecLinkTypeLAN)
+	}
 	reply.Data.Code.Code = constants.GeniCodeSuccess
 	return nil
 }
@@ -5,6 +5,7 @@ import (
 	"testing"
 
 	"github.com/EdgeNet-project/fed4fire/pkg/constants"
+	"github.com/EdgeNet-project/fed4fire/pkg/rspec"
 )
 
 func TestGetVersion(t *testing.T) {
@@ -17,4 +18,19 @@ func TestGetVersion(t *testing.T) {
 	assert.Len(t, reply.Data.Value.AdRspecVersions, 1)
 	assert.Len(t, reply.Data.Value.RequestRspecVersions, 1)
 	assert.Len(t, reply.Data.Value.CredentialTypes, 1)
+	assert.Len(t, reply.Data.Value.LinkTypes, 0)
+	assert.Contains(
+		t,
+		reply.Data.Value.RequestRspecVersions[0].Extensions,
+		rspec.RspecExtensionEdgeNet,
+	)
+}
+
+func TestGetVersion_Links(t *testing.T) {
+	s := testService()
+	args := &GetVersionArgs{}
+	reply := &GetVersionReply{}
+	err := s.GetVersion(nil, args, reply)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{rspec.RspecLinkTypeLAN}, reply.Data.Value.LinkTypes)
 }
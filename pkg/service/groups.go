@@ -0,0 +1,272 @@
+package service
+
+import (
+	"fmt"
+	v1 "github.com/EdgeNet-project/fed4fire/pkg/apis/fed4fire/v1"
+	"github.com/EdgeNet-project/fed4fire/pkg/constants"
+	"github.com/EdgeNet-project/fed4fire/pkg/identifiers"
+	"github.com/EdgeNet-project/fed4fire/pkg/naming"
+	"github.com/EdgeNet-project/fed4fire/pkg/rspec"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sort"
+)
+
+// groupTopologyKeys maps the topologies of the EdgeNet groups to the node labels defining their domains.
+var groupTopologyKeys = map[string]string{
+	rspec.GroupTopologyHostname: corev1.LabelHostname,
+	rspec.GroupTopologyCountry:  constants.EdgeNetLabelCountryISO,
+}
+
+// sliverGroupsForRspec returns the groups of the nodes of a request RSpec, by client ID.
+func sliverGroupsForRspec(request rspec.Rspec) (map[string][]v1.SliverGroup, error) {
+	clientIDs := make(map[string]bool)
+	for _, node := range request.Nodes {
+		clientIDs[node.ClientID] = true
+	}
+	groups := make(map[string][]v1.SliverGroup)
+	names := make(map[string]bool)
+	for _, group := range request.Groups {
+		if group.Name == "" {
+			return nil, fmt.Errorf("group name must not be empty")
+		}
+		if names[group.Name] {
+			return nil, fmt.Errorf("duplicate group %s", group.Name)
+		}
+		names[group.Name] = true
+		switch group.Policy {
+		case rspec.GroupPolicySpread, rspec.GroupPolicyBalance, rspec.GroupPolicyColocate:
+		default:
+			return nil, fmt.Errorf(
+				"group policy must be one of %s, %s or %s",
+				rspec.GroupPolicySpread,
+				rspec.GroupPolicyBalance,
+				rspec.GroupPolicyColocate,
+			)
+		}
+		topology := group.Topology
+		if topology == "" {
+			topology = rspec.GroupTopologyHostname
+		}
+		if _, ok := groupTopologyKeys[topology]; !ok {
+			return nil, fmt.Errorf(
+				"group topology must be %s or %s",
+				rspec.GroupTopologyHostname,
+				rspec.GroupTopologyCountry,
+			)
+		}
+		members := make(map[string]bool)
+		for _, member := range group.Members {
+			if !clientIDs[member.ClientID] {
+				return nil, fmt.Errorf("group %s member %s is not a node of the request", group.Name, member.ClientID)
+			}
+			if members[member.ClientID] {
+				return nil, fmt.Errorf("group %s member %s is duplicated", group.Name, member.ClientID)
+			}
+			members[member.ClientID] = true
+			groups[member.ClientID] = append(groups[member.ClientID], v1.SliverGroup{
+				Name:     group.Name,
+				Policy:   group.Policy,
+				Topology: topology,
+			})
+		}
+	}
+	return groups, nil
+}
+
+// checkSliverGroups returns an error if the nodes of the cluster cannot satisfy the groups of a request RSpec.
+// It only considers the ready nodes and the component IDs of the request, not the resources of the nodes.
+func checkSliverGroups(request rspec.Rspec, groups map[string][]v1.SliverGroup, nodes []corev1.Node) error {
+	// Topology domains of the ready nodes, by topology, and of the requested nodes, by topology and client ID.
+	domains := make(map[string]map[string]bool)
+	requested := make(map[string]map[string]string)
+	for topology, key := range groupTopologyKeys {
+		domains[topology] = make(map[string]bool)
+		requested[topology] = make(map[string]string)
+		for _, node := range nodes {
+			if domain := nodeTopologyDomain(node, key); domain != "" && nodeIsReady(node) {
+				domains[topology][domain] = true
+			}
+		}
+	}
+	for _, node := range request.Nodes {
+		componentID, err := identifiers.Parse(node.ComponentID)
+		if node.ComponentID == "" || err != nil {
+			continue
+		}
+		for topology, key := range groupTopologyKeys {
+			for _, kubernetesNode := range nodes {
+				if kubernetesNode.Name == componentID.ResourceName {
+					requested[topology][node.ClientID] = nodeTopologyDomain(kubernetesNode, key)
+				}
+			}
+		}
+	}
+
+	for _, group := range request.Groups {
+		topology := group.Topology
+		if topology == "" {
+			topology = rspec.GroupTopologyHostname
+		}
+		if len(domains[topology]) == 0 {
+			return fmt.Errorf("group %s: no ready node has a %s", group.Name, topology)
+		}
+		memberDomains := make(map[string]bool)
+		for _, member := range group.Members {
+			domain, ok := requested[topology][member.ClientID]
+			if !ok {
+				continue
+			}
+			if group.Policy == rspec.GroupPolicySpread && memberDomains[domain] {
+				return fmt.Errorf("group %s: several members are requested on %s %s", group.Name, topology, domain)
+			}
+			memberDomains[domain] = true
+		}
+		switch group.Policy {
+		case rspec.GroupPolicySpread:
+			if len(group.Members) > len(domains[topology]) {
+				return fmt.Errorf(
+					"group %s: %d members cannot be spread on %d ready %s domains",
+					group.Name,
+					len(group.Members),
+					len(domains[topology]),
+					topology,
+				)
+			}
+		case rspec.GroupPolicyColocate:
+			if len(memberDomains) > 1 {
+				return fmt.Errorf("group %s: members are requested on different %s domains", group.Name, topology)
+			}
+		}
+	}
+
+	// Two members cannot be both spread and colocated on the same topology.
+	for clientID, memberGroups := range groups {
+		for _, spread := range memberGroups {
+			for _, colocate := range memberGroups {
+				if spread.Policy != rspec.GroupPolicySpread || colocate.Policy != rspec.GroupPolicyColocate ||
+					spread.Topology != colocate.Topology {
+					continue
+				}
+				for otherClientID, otherGroups := range groups {
+					if otherClientID != clientID &&
+						containsSliverGroup(otherGroups, spread) &&
+						containsSliverGroup(otherGroups, colocate) {
+						return fmt.Errorf(
+							"groups %s and %s both spread and colocate %s and %s",
+							spread.Name,
+							colocate.Name,
+							clientID,
+							otherClientID,
+						)
+					}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// groupLabels returns the labels identifying the groups of a sliver, to be set on its pod.
+func groupLabels(sliver v1.Sliver) map[string]string {
+	labels := make(map[string]string)
+	for _, group := range sliver.Spec.Groups {
+		labels[groupLabelKey(sliver, group)] = "true"
+	}
+	return labels
+}
+
+// groupScheduling returns the pod affinity, the pod anti-affinity and the topology spread constraints
+// placing a sliver with respect to the other members of its groups.
+func groupScheduling(sliver v1.Sliver) (*corev1.PodAffinity, *corev1.PodAntiAffinity, []corev1.TopologySpreadConstraint) {
+	var affinity *corev1.PodAffinity
+	var antiAffinity *corev1.PodAntiAffinity
+	var constraints []corev1.TopologySpreadConstraint
+	for _, group := range sliver.Spec.Groups {
+		selector := &metav1.LabelSelector{
+			MatchLabels: map[string]string{
+				constants.Fed4FireSliceHash:  naming.SliceHash(sliver.Spec.SliceURN),
+				groupLabelKey(sliver, group): "true",
+			},
+		}
+		term := corev1.PodAffinityTerm{
+			LabelSelector: selector,
+			TopologyKey:   groupTopologyKeys[group.Topology],
+		}
+		switch group.Policy {
+		case rspec.GroupPolicySpread:
+			if antiAffinity == nil {
+				antiAffinity = &corev1.PodAntiAffinity{}
+			}
+			antiAffinity.RequiredDuringSchedulingIgnoredDuringExecution = append(
+				antiAffinity.RequiredDuringSchedulingIgnoredDuringExecution,
+				term,
+			)
+		case rspec.GroupPolicyColocate:
+			if affinity == nil {
+				affinity = &corev1.PodAffinity{}
+			}
+			affinity.RequiredDuringSchedulingIgnoredDuringExecution = append(
+				affinity.RequiredDuringSchedulingIgnoredDuringExecution,
+				term,
+			)
+		case rspec.GroupPolicyBalance:
+			constraints = append(constraints, corev1.TopologySpreadConstraint{
+				MaxSkew:           1,
+				TopologyKey:       groupTopologyKeys[group.Topology],
+				WhenUnsatisfiable: corev1.DoNotSchedule,
+				LabelSelector:     selector,
+			})
+		}
+	}
+	return affinity, antiAffinity, constraints
+}
+
+// rspecGroupsForSlivers returns the EdgeNet groups of the given slivers, sorted by name.
+func rspecGroupsForSlivers(slivers []v1.Sliver) []rspec.Group {
+	groups := make(map[string]*rspec.Group)
+	names := make([]string, 0)
+	for _, sliver := range slivers {
+		for _, group := range sliver.Spec.Groups {
+			if _, ok := groups[group.Name]; !ok {
+				groups[group.Name] = &rspec.Group{
+					Name:     group.Name,
+					Policy:   group.Policy,
+					Topology: group.Topology,
+				}
+				names = append(names, group.Name)
+			}
+			groups[group.Name].Members = append(
+				groups[group.Name].Members,
+				rspec.GroupMember{ClientID: sliver.Spec.ClientID},
+			)
+		}
+	}
+	sort.Strings(names)
+	rspecGroups := make([]rspec.Group, 0, len(names))
+	for _, name := range names {
+		rspecGroups = append(rspecGroups, *groups[name])
+	}
+	return rspecGroups
+}
+
+func groupLabelKey(sliver v1.Sliver, group v1.SliverGroup) string {
+	return constants.Fed4FireGroup + naming.GroupName(sliver.Spec.SliceURN, group.Name)
+}
+
+// nodeTopologyDomain returns the value of the label of a node defining a topology domain.
+func nodeTopologyDomain(node corev1.Node, key string) string {
+	if key == corev1.LabelHostname {
+		return node.Name
+	}
+	return node.Labels[key]
+}
+
+func containsSliverGroup(groups []v1.SliverGroup, group v1.SliverGroup) bool {
+	for _, group_ := range groups {
+		if group_ == group {
+			return true
+		}
+	}
+	return false
+}
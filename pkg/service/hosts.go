@@ -0,0 +1,113 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	v1 "github.com/EdgeNet-project/fed4fire/pkg/apis/fed4fire/v1"
+	"github.com/EdgeNet-project/fed4fire/pkg/constants"
+	"github.com/EdgeNet-project/fed4fire/pkg/naming"
+	"github.com/EdgeNet-project/fed4fire/pkg/rspec"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// checkHostNames returns an error if two nodes of a request RSpec have the same hostname in the slice,
+// or if a node has the same hostname as an existing sliver of the slice.
+func checkHostNames(request rspec.Rspec, existing []v1.Sliver) error {
+	clientIDs := make(map[string]string)
+	for _, node := range request.Nodes {
+		name := naming.HostName(node.ClientID)
+		if clientID, ok := clientIDs[name]; ok {
+			return fmt.Errorf("nodes %s and %s have the same hostname %s", clientID, node.ClientID, name)
+		}
+		clientIDs[name] = node.ClientID
+	}
+	for _, sliver := range existing {
+		name := naming.HostName(sliver.Spec.ClientID)
+		// An existing sliver of the same client ID is the one allocated again.
+		if clientID, ok := clientIDs[name]; ok && clientID != sliver.Spec.ClientID {
+			return fmt.Errorf(
+				"node %s has the same hostname %s as the existing node %s",
+				clientID,
+				name,
+				sliver.Spec.ClientID,
+			)
+		}
+	}
+	return nil
+}
+
+// sliceDomain returns the DNS domain of the slice of a sliver, under which its slivers are reachable by hostname.
+func sliceDomain(s Service, sliver v1.Sliver) string {
+	return fmt.Sprintf(
+		"%s.%s.svc.%s",
+		naming.SliceHash(sliver.Spec.SliceURN),
+		sliver.Namespace,
+		s.ClusterDomain,
+	)
+}
+
+// sliceServiceForSliver returns the headless service of the slice of a sliver, which publishes the DNS records
+// of the hostnames of the slivers under the domain of the slice, even before they are ready.
+func sliceServiceForSliver(sliver v1.Sliver) *corev1.Service {
+	sliceHash := naming.SliceHash(sliver.Spec.SliceURN)
+	return &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      sliceHash,
+			Namespace: sliver.Namespace,
+			Labels: map[string]string{
+				constants.Fed4FireSliceHash: sliceHash,
+			},
+		},
+		Spec: corev1.ServiceSpec{
+			ClusterIP: corev1.ClusterIPNone,
+			Selector: map[string]string{
+				constants.Fed4FireSliceHash: sliceHash,
+			},
+			PublishNotReadyAddresses: true,
+		},
+	}
+}
+
+// createSliceService creates the headless service of a slice, or adds the sliver to the owners of an existing one,
+// so that the service is deleted with the last sliver of the slice.
+func createSliceService(
+	ctx context.Context,
+	s Service,
+	service *corev1.Service,
+	ownerReference metav1.OwnerReference,
+) error {
+	client := s.Services(service.Namespace)
+	service.OwnerReferences = []metav1.OwnerReference{ownerReference}
+	_, err := client.Create(ctx, service, metav1.CreateOptions{})
+	if err == nil || !errors.IsAlreadyExists(err) {
+		return err
+	}
+	existing, err := client.Get(ctx, service.Name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	for _, reference := range existing.OwnerReferences {
+		if reference.Name == ownerReference.Name {
+			return nil
+		}
+	}
+	existing.OwnerReferences = append(existing.OwnerReferences, ownerReference)
+	_, err = client.Update(ctx, existing, metav1.UpdateOptions{})
+	return err
+}
+
+// rspecHostForSliver returns the hostname of a sliver in its slice, as reported in the manifests.
+// The address is only known once the pod of the sliver is scheduled.
+func rspecHostForSliver(s Service, sliver v1.Sliver, pod *corev1.Pod) *rspec.Host {
+	name := naming.HostName(sliver.Spec.ClientID)
+	host := &rspec.Host{
+		Name: name,
+		FQDN: name + "." + sliceDomain(s, sliver),
+	}
+	if pod != nil {
+		host.Address = pod.Status.PodIP
+	}
+	return host
+}
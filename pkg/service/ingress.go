@@ -0,0 +1,106 @@
+package service
+
+import (
+	"fmt"
+	v1 "github.com/EdgeNet-project/fed4fire/pkg/apis/fed4fire/v1"
+	"github.com/EdgeNet-project/fed4fire/pkg/naming"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Annotation requesting a TLS certificate for the hosts of an ingress from a cert-manager cluster issuer.
+const certManagerClusterIssuer = "cert-manager.io/cluster-issuer"
+
+// ingressHost returns the hostname of a sliver port served by an ingress.
+func ingressHost(s Service, sliver v1.Sliver, port v1.SliverPort) string {
+	return naming.HostLabel(sliver.Spec.SliceURN, sliver.Spec.ClientID, port.Port) + "." + s.IngressDomain
+}
+
+// checkIngressHosts returns an error if the ports of two slivers of a slice are served at the same hostname,
+// e.g. for the client IDs Web_1 and web-1. The requested slivers are checked against the existing ones as well.
+func checkIngressHosts(s Service, requested []*v1.Sliver, existing []v1.Sliver) error {
+	slivers := make([]v1.Sliver, 0, len(requested)+len(existing))
+	for _, sliver := range requested {
+		slivers = append(slivers, *sliver)
+	}
+	slivers = append(slivers, existing...)
+	clientIDs := make(map[string]string)
+	for _, sliver := range slivers {
+		for _, port := range sliver.Spec.Ports {
+			if !port.Ingress {
+				continue
+			}
+			host := ingressHost(s, sliver, port)
+			// An existing sliver of the same client ID is the one allocated again.
+			if clientID, ok := clientIDs[host]; ok && clientID != sliver.Spec.ClientID {
+				return fmt.Errorf("nodes %s and %s have the same ingress hostname %s", clientID, sliver.Spec.ClientID, host)
+			}
+			clientIDs[host] = sliver.Spec.ClientID
+		}
+	}
+	return nil
+}
+
+// ingressURL returns the URL of a sliver port served by an ingress.
+// It uses HTTPS when the ingresses have a TLS certificate, from -ingressTLSSecret or -ingressIssuer.
+func ingressURL(s Service, sliver v1.Sliver, port v1.SliverPort) string {
+	scheme := "http"
+	if s.IngressTLSSecret != "" || s.IngressIssuer != "" {
+		scheme = "https"
+	}
+	return fmt.Sprintf("%s://%s/", scheme, ingressHost(s, sliver, port))
+}
+
+// ingressesForSliver returns an ingress for each port of a sliver served over HTTP(S),
+// routing the hostname of the port to the service of the sliver.
+func ingressesForSliver(s Service, sliver v1.Sliver, labels map[string]string) []*networkingv1.Ingress {
+	ingresses := make([]*networkingv1.Ingress, 0)
+	for _, port := range sliver.Spec.Ports {
+		if !port.Ingress {
+			continue
+		}
+		host := ingressHost(s, sliver, port)
+		ingress := &networkingv1.Ingress{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      fmt.Sprintf("%s-%d", sliver.Name, port.Port),
+				Namespace: sliver.Namespace,
+				Labels:    labels,
+			},
+			Spec: networkingv1.IngressSpec{
+				Rules: []networkingv1.IngressRule{{
+					Host: host,
+					IngressRuleValue: networkingv1.IngressRuleValue{
+						HTTP: &networkingv1.HTTPIngressRuleValue{
+							Paths: []networkingv1.HTTPIngressPath{{
+								Path:     "/",
+								PathType: pathTypePtr(networkingv1.PathTypePrefix),
+								Backend: networkingv1.IngressBackend{
+									Service: &networkingv1.IngressServiceBackend{
+										Name: sliver.Name,
+										Port: networkingv1.ServiceBackendPort{Name: servicePortName(port)},
+									},
+								},
+							}},
+						},
+					},
+				}},
+			},
+		}
+		if s.IngressClass != "" {
+			ingress.Spec.IngressClassName = &s.IngressClass
+		}
+		if s.IngressTLSSecret != "" {
+			ingress.Spec.TLS = []networkingv1.IngressTLS{{Hosts: []string{host}, SecretName: s.IngressTLSSecret}}
+		} else if s.IngressIssuer != "" {
+			// cert-manager stores the certificate in the secret named after the ingress.
+			ingress.Annotations = map[string]string{certManagerClusterIssuer: s.IngressIssuer}
+			ingress.Spec.TLS = []networkingv1.IngressTLS{{Hosts: []string{host}, SecretName: ingress.Name + "-tls"}}
+		}
+		ingresses = append(ingresses, ingress)
+	}
+	return ingresses
+}
+
+func pathTypePtr(pathType networkingv1.PathType) *networkingv1.PathType {
+	return &pathType
+}
@@ -0,0 +1,479 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	v1 "github.com/EdgeNet-project/fed4fire/pkg/apis/fed4fire/v1"
+	"github.com/EdgeNet-project/fed4fire/pkg/constants"
+	"github.com/EdgeNet-project/fed4fire/pkg/naming"
+	"github.com/EdgeNet-project/fed4fire/pkg/rspec"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/utils/pointer"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// The links are realized as Multus networks, one per link.
+// https://github.com/k8snetworkplumbingwg/multus-cni
+var networkAttachmentDefinitionResource = schema.GroupVersionResource{
+	Group:    "k8s.cni.cncf.io",
+	Version:  "v1",
+	Resource: "network-attachment-definitions",
+}
+
+const multusNetworksAnnotation = "k8s.v1.cni.cncf.io/networks"
+
+// linkNetworkParameters are the values available in the CNI configuration template of the links.
+type linkNetworkParameters struct {
+	// Name of the network, unique for a link of a slice.
+	Name string
+	// 24-bit identifier derived from the name, e.g. for a VXLAN network identifier.
+	VNI uint32
+	// Identifier between 2 and 4094 derived from the name, e.g. for a VLAN.
+	VLAN uint32
+}
+
+// multusNetwork is an element of the Multus networks annotation.
+type multusNetwork struct {
+	Name      string           `json:"name"`
+	Interface string           `json:"interface"`
+	IPs       []string         `json:"ips"`
+	Bandwidth *multusBandwidth `json:"bandwidth,omitempty"`
+}
+
+// multusBandwidth is passed by Multus to the bandwidth CNI plugin, like the kubernetes.io/*-bandwidth
+// pod annotations for the default network. The rates and the bursts are in bits per second and in bits.
+type multusBandwidth struct {
+	IngressRate  int64 `json:"ingressRate"`
+	IngressBurst int64 `json:"ingressBurst"`
+	EgressRate   int64 `json:"egressRate"`
+	EgressBurst  int64 `json:"egressBurst"`
+}
+
+// linkInterfaceName returns the name in the container of the interface attached to the i-th link of a sliver.
+func linkInterfaceName(i int) string {
+	return fmt.Sprintf("net%d", i+1)
+}
+
+// assignInterfaceAddresses returns the interfaces attached to the links of a request RSpec, indexed by client ID.
+// The i-th link gets the i-th /24 subnet of linkSubnet. The addresses given in the request must belong to it,
+// and are reserved before the other interfaces get the remaining addresses in the order of the interface references.
+func assignInterfaceAddresses(
+	linkSubnet string,
+	requestRspec rspec.Rspec,
+) (map[string]v1.SliverInterface, error) {
+	interfaces := make(map[string]v1.SliverInterface)
+	if len(requestRspec.Links) == 0 {
+		return interfaces, nil
+	}
+	_, subnet, err := net.ParseCIDR(linkSubnet)
+	if err != nil {
+		return nil, err
+	}
+	ones, bits := subnet.Mask.Size()
+	if bits != 32 || ones > 24 {
+		return nil, fmt.Errorf("link subnet %s must be an IPv4 subnet of size /24 or larger", linkSubnet)
+	}
+	declared := make(map[string]rspec.Interface)
+	for _, node := range requestRspec.Nodes {
+		for _, interface_ := range node.Interfaces {
+			declared[interface_.ClientID] = interface_
+		}
+	}
+	for i, link := range requestRspec.Links {
+		if i >= 1<<(24-ones) {
+			return nil, fmt.Errorf("too many links for the link subnet %s", linkSubnet)
+		}
+		base := binary.BigEndian.Uint32(subnet.IP.To4()) + uint32(i)<<8
+		linkNet := &net.IPNet{IP: make(net.IP, 4), Mask: net.CIDRMask(24, 32)}
+		binary.BigEndian.PutUint32(linkNet.IP, base)
+		// The requested addresses are reserved first, so that the automatic ones do not collide with them.
+		reserved := make(map[uint32]string)
+		for _, ref := range link.InterfaceRefs {
+			interface_, ok := declared[ref.ClientID]
+			if !ok {
+				return nil, fmt.Errorf("interface %s of link %s does not exist", ref.ClientID, link.ClientID)
+			}
+			if _, ok := interfaces[ref.ClientID]; ok {
+				return nil, fmt.Errorf("interface %s belongs to more than one link", ref.ClientID)
+			}
+			if len(interface_.IPs) == 0 || interface_.IPs[0].Address == "" {
+				continue
+			}
+			host, err := requestedHost(interface_.IPs[0], linkNet)
+			if err != nil {
+				return nil, fmt.Errorf("interface %s of link %s: %s", ref.ClientID, link.ClientID, err)
+			}
+			if other, ok := reserved[host]; ok {
+				return nil, fmt.Errorf(
+					"interfaces %s and %s have the same address %s",
+					other,
+					ref.ClientID,
+					interface_.IPs[0].Address,
+				)
+			}
+			reserved[host] = ref.ClientID
+			interfaces[ref.ClientID] = v1.SliverInterface{
+				ClientID:     ref.ClientID,
+				LinkClientID: link.ClientID,
+				Address:      interfaceAddress(base + host),
+			}
+		}
+		host := uint32(0)
+		for _, ref := range link.InterfaceRefs {
+			if _, ok := interfaces[ref.ClientID]; ok {
+				continue
+			}
+			host++
+			for reserved[host] != "" {
+				host++
+			}
+			if host > 254 {
+				return nil, fmt.Errorf("too many interfaces on link %s", link.ClientID)
+			}
+			interfaces[ref.ClientID] = v1.SliverInterface{
+				ClientID:     ref.ClientID,
+				LinkClientID: link.ClientID,
+				Address:      interfaceAddress(base + host),
+			}
+		}
+	}
+	return interfaces, nil
+}
+
+// interfaceAddress formats an address of a link subnet in CIDR notation.
+func interfaceAddress(address uint32) string {
+	ip := make(net.IP, 4)
+	binary.BigEndian.PutUint32(ip, address)
+	return fmt.Sprintf("%s/24", ip)
+}
+
+// assignLinkShaping sets the shaping of the interfaces attached to the links of a request RSpec, from the link properties.
+// The traffic is shaped when it is sent, so all the properties of a source interface must be identical,
+// whatever their destination.
+func assignLinkShaping(
+	s Service,
+	requestRspec rspec.Rspec,
+	interfaces map[string]v1.SliverInterface,
+) error {
+	for _, link := range requestRspec.Links {
+		for _, property := range link.Properties {
+			for _, id := range []string{property.SourceID, property.DestID} {
+				if interface_, ok := interfaces[id]; !ok || interface_.LinkClientID != link.ClientID {
+					return fmt.Errorf("interface %s of link %s does not exist", id, link.ClientID)
+				}
+			}
+			shaping, err := s.linkShaping(property)
+			if err != nil {
+				return fmt.Errorf("invalid property of link %s: %s", link.ClientID, err)
+			}
+			if shaping == (v1.LinkShaping{}) {
+				continue
+			}
+			interface_ := interfaces[property.SourceID]
+			if interface_.Shaping != nil && *interface_.Shaping != shaping {
+				return fmt.Errorf(
+					"the properties of the traffic sent by interface %s must be identical for all destinations",
+					property.SourceID,
+				)
+			}
+			interface_.Shaping = &shaping
+			interfaces[property.SourceID] = interface_
+		}
+	}
+	return nil
+}
+
+// linkShaping parses the values of a link property and checks them against the limits of the aggregate.
+func (s Service) linkShaping(property rspec.LinkProperty) (v1.LinkShaping, error) {
+	shaping := v1.LinkShaping{}
+	if property.Capacity != "" {
+		capacity, err := strconv.ParseInt(property.Capacity, 10, 64)
+		if err != nil || capacity <= 0 {
+			return shaping, fmt.Errorf("capacity %s must be a positive number of kbps", property.Capacity)
+		}
+		if capacity > s.MaxLinkCapacity {
+			return shaping, fmt.Errorf("capacity %d kbps exceeds the maximum of %d kbps", capacity, s.MaxLinkCapacity)
+		}
+		shaping.Capacity = capacity
+	}
+	if property.Latency != "" {
+		latency, err := strconv.ParseInt(property.Latency, 10, 64)
+		if err != nil || latency < 0 {
+			return shaping, fmt.Errorf("latency %s must be a number of ms", property.Latency)
+		}
+		if latency > s.MaxLinkLatency {
+			return shaping, fmt.Errorf("latency %d ms exceeds the maximum of %d ms", latency, s.MaxLinkLatency)
+		}
+		shaping.Latency = latency
+	}
+	if property.PacketLoss != "" {
+		loss, err := strconv.ParseFloat(property.PacketLoss, 64)
+		if err != nil || loss < 0 || loss > 1 {
+			return shaping, fmt.Errorf("packet loss %s must be between 0 and 1", property.PacketLoss)
+		}
+		if loss > 0 {
+			shaping.PacketLoss = strconv.FormatFloat(loss, 'f', -1, 64)
+		}
+	}
+	return shaping, nil
+}
+
+// needsNetem returns true if the shaping of an interface requires netem, that is everything but the capacity.
+func needsNetem(shaping *v1.LinkShaping) bool {
+	return shaping != nil && (shaping.Latency > 0 || shaping.PacketLoss != "")
+}
+
+// requestedHost returns the host number in the /24 subnet of a link of an address given in a request RSpec.
+// The address must be a host address of this subnet, and the netmask, if any, must be the one of the subnet.
+func requestedHost(ip rspec.IP, subnet *net.IPNet) (uint32, error) {
+	address := net.ParseIP(ip.Address).To4()
+	if address == nil {
+		return 0, fmt.Errorf("invalid IPv4 address %s", ip.Address)
+	}
+	if ip.Netmask != "" {
+		netmask := net.ParseIP(ip.Netmask).To4()
+		if netmask == nil || !bytes.Equal(netmask, net.IP(subnet.Mask)) {
+			return 0, fmt.Errorf("netmask %s must be %s", ip.Netmask, net.IP(subnet.Mask))
+		}
+	}
+	host := binary.BigEndian.Uint32(address) - binary.BigEndian.Uint32(subnet.IP)
+	if !subnet.Contains(address) || host == 0 || host == 255 {
+		return 0, fmt.Errorf("address %s must be a host address of the link subnet %s", ip.Address, subnet)
+	}
+	return host, nil
+}
+
+// rspecInterfaceForSliverInterface converts a sliver interface to an RSpec interface.
+func rspecInterfaceForSliverInterface(interface_ v1.SliverInterface) rspec.Interface {
+	rspecInterface := rspec.Interface{ClientID: interface_.ClientID}
+	ip, subnet, err := net.ParseCIDR(interface_.Address)
+	if err == nil {
+		rspecInterface.IPs = []rspec.IP{{
+			Address: ip.String(),
+			Netmask: net.IP(subnet.Mask).String(),
+			Type:    rspec.RspecIPTypeIPv4,
+		}}
+	}
+	return rspecInterface
+}
+
+// rspecInterfacesForSliver returns the network interfaces of a sliver, as reported in the manifests.
+func rspecInterfacesForSliver(sliver v1.Sliver) []rspec.Interface {
+	interfaces := make([]rspec.Interface, 0)
+	for _, interface_ := range sliver.Spec.Interfaces {
+		interfaces = append(interfaces, rspecInterfaceForSliverInterface(interface_))
+	}
+	return interfaces
+}
+
+// rspecLinksForSlivers returns the links between the given slivers, as reported in the manifests.
+// The shaping of an interface is reported as one property towards each other interface of its link.
+func rspecLinksForSlivers(s Service, slivers []v1.Sliver) []rspec.Link {
+	links := make([]rspec.Link, 0)
+	indices := make(map[string]int)
+	interfaces := make(map[string]v1.SliverInterface)
+	for _, sliver := range slivers {
+		for _, interface_ := range sliver.Spec.Interfaces {
+			interfaces[interface_.ClientID] = interface_
+			i, ok := indices[interface_.LinkClientID]
+			if !ok {
+				i = len(links)
+				indices[interface_.LinkClientID] = i
+				links = append(links, rspec.Link{
+					ClientID: interface_.LinkClientID,
+					ComponentManagers: []rspec.ComponentManager{{
+						Name: s.AuthorityIdentifier.URN(),
+					}},
+					LinkTypes: []rspec.LinkType{{Name: rspec.RspecLinkTypeLAN}},
+				})
+			}
+			links[i].InterfaceRefs = append(links[i].InterfaceRefs, rspec.InterfaceRef{
+				ClientID: interface_.ClientID,
+			})
+		}
+	}
+	for i, link := range links {
+		for _, source := range link.InterfaceRefs {
+			shaping := interfaces[source.ClientID].Shaping
+			if shaping == nil {
+				continue
+			}
+			for _, dest := range link.InterfaceRefs {
+				if dest.ClientID == source.ClientID {
+					continue
+				}
+				property := rspec.LinkProperty{
+					SourceID:   source.ClientID,
+					DestID:     dest.ClientID,
+					PacketLoss: shaping.PacketLoss,
+				}
+				if shaping.Capacity > 0 {
+					property.Capacity = strconv.FormatInt(shaping.Capacity, 10)
+				}
+				if shaping.Latency > 0 {
+					property.Latency = strconv.FormatInt(shaping.Latency, 10)
+				}
+				links[i].Properties = append(links[i].Properties, property)
+			}
+		}
+	}
+	return links
+}
+
+// buildLinkNetworks returns the Multus networks of the links of a sliver, and the pod annotation attaching them.
+func buildLinkNetworks(
+	s Service,
+	sliver v1.Sliver,
+	labels map[string]string,
+) ([]*unstructured.Unstructured, string, error) {
+	networks := make([]*unstructured.Unstructured, 0)
+	attachments := make([]multusNetwork, 0)
+	for i, interface_ := range sliver.Spec.Interfaces {
+		if s.LinkCniConfig == nil {
+			return nil, "", fmt.Errorf("links are not supported by this aggregate")
+		}
+		name := naming.LinkName(sliver.Spec.SliceURN, interface_.LinkClientID)
+		id, err := hex.DecodeString(name[1:])
+		if err != nil {
+			return nil, "", err
+		}
+		n := binary.BigEndian.Uint64(id)
+		var config bytes.Buffer
+		err = s.LinkCniConfig.Execute(&config, linkNetworkParameters{
+			Name: name,
+			VNI:  uint32(n & 0xFFFFFF),
+			VLAN: uint32(2 + n%4093),
+		})
+		if err != nil {
+			return nil, "", err
+		}
+		network := &unstructured.Unstructured{}
+		network.SetAPIVersion(networkAttachmentDefinitionResource.GroupVersion().String())
+		network.SetKind("NetworkAttachmentDefinition")
+		network.SetName(name)
+		network.SetNamespace(sliver.Namespace)
+		network.SetLabels(map[string]string{
+			constants.Fed4FireSliceHash: labels[constants.Fed4FireSliceHash],
+		})
+		err = unstructured.SetNestedField(network.Object, config.String(), "spec", "config")
+		if err != nil {
+			return nil, "", err
+		}
+		networks = append(networks, network)
+		attachment := multusNetwork{
+			Name:      name,
+			Interface: linkInterfaceName(i),
+			IPs:       []string{interface_.Address},
+		}
+		if interface_.Shaping != nil && interface_.Shaping.Capacity > 0 {
+			rate := interface_.Shaping.Capacity * 1000
+			attachment.Bandwidth = &multusBandwidth{
+				EgressRate:  rate,
+				EgressBurst: linkBurst(rate),
+			}
+		}
+		attachments = append(attachments, attachment)
+	}
+	if len(attachments) == 0 {
+		return networks, "", nil
+	}
+	annotation, err := json.Marshal(attachments)
+	if err != nil {
+		return nil, "", err
+	}
+	return networks, string(annotation), nil
+}
+
+// linkBurst returns the burst allowed for a rate in bits per second: 100 ms of traffic,
+// but at least 10 full-size Ethernet frames.
+func linkBurst(rate int64) int64 {
+	burst := rate / 10
+	if burst < 10*1500*8 {
+		burst = 10 * 1500 * 8
+	}
+	return burst
+}
+
+// buildLinkShapingContainer returns an init container applying the latency and the packet loss
+// of the links of a sliver with netem, or nil if there is nothing to apply.
+func buildLinkShapingContainer(s Service, sliver v1.Sliver) (*corev1.Container, error) {
+	commands := make([]string, 0)
+	for i, interface_ := range sliver.Spec.Interfaces {
+		if !needsNetem(interface_.Shaping) {
+			continue
+		}
+		if s.LinkShapingImage == "" {
+			return nil, fmt.Errorf("link latency and packet loss are not supported by this aggregate")
+		}
+		command := fmt.Sprintf("tc qdisc replace dev %s root netem", linkInterfaceName(i))
+		if interface_.Shaping.Latency > 0 {
+			command += fmt.Sprintf(" delay %dms", interface_.Shaping.Latency)
+		}
+		if interface_.Shaping.PacketLoss != "" {
+			loss, err := strconv.ParseFloat(interface_.Shaping.PacketLoss, 64)
+			if err != nil {
+				return nil, err
+			}
+			command += fmt.Sprintf(" loss %s%%", strconv.FormatFloat(loss*100, 'f', -1, 64))
+		}
+		commands = append(commands, command)
+	}
+	if len(commands) == 0 {
+		return nil, nil
+	}
+	return &corev1.Container{
+		Name:    "link-shaping",
+		Image:   s.LinkShapingImage,
+		Command: []string{"sh", "-c", "set -e; " + strings.Join(commands, "; ")},
+		// tc only requires NET_ADMIN in the network namespace of the pod,
+		// whatever the security profile of the sliver.
+		SecurityContext: &corev1.SecurityContext{
+			RunAsUser:                pointer.Int64Ptr(0),
+			RunAsNonRoot:             pointer.BoolPtr(false),
+			AllowPrivilegeEscalation: pointer.BoolPtr(false),
+			Capabilities: &corev1.Capabilities{
+				Add:  []corev1.Capability{"NET_ADMIN"},
+				Drop: []corev1.Capability{"ALL"},
+			},
+		},
+	}, nil
+}
+
+// createLinkNetwork creates the network of a link, or adds the sliver to the owners of an existing one,
+// so that the network is deleted with the last sliver attached to it.
+func createLinkNetwork(
+	ctx context.Context,
+	s Service,
+	network *unstructured.Unstructured,
+	ownerReference metav1.OwnerReference,
+) error {
+	client := s.NetworkAttachmentDefinitions(network.GetNamespace())
+	network.SetOwnerReferences([]metav1.OwnerReference{ownerReference})
+	_, err := client.Create(ctx, network, metav1.CreateOptions{})
+	if err == nil || !errors.IsAlreadyExists(err) {
+		return err
+	}
+	existing, err := client.Get(ctx, network.GetName(), metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	ownerReferences := existing.GetOwnerReferences()
+	for _, reference := range ownerReferences {
+		if reference.Name == ownerReference.Name {
+			return nil
+		}
+	}
+	existing.SetOwnerReferences(append(ownerReferences, ownerReference))
+	_, err = client.Update(ctx, existing, metav1.UpdateOptions{})
+	return err
+}
@@ -1,13 +1,16 @@
 package service
 
 import (
+	"context"
 	"fmt"
+	v1 "github.com/EdgeNet-project/fed4fire/pkg/apis/fed4fire/v1"
 	"github.com/EdgeNet-project/fed4fire/pkg/constants"
 	"net/http"
 
 	"github.com/EdgeNet-project/fed4fire/pkg/identifiers"
 	"github.com/EdgeNet-project/fed4fire/pkg/rspec"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/klog/v2"
 )
@@ -60,9 +63,19 @@ func (s *Service) ListResources(
 		return reply.SetAndLogError(err, constants.ErrorListResources, constants.GeniCodeError)
 	}
 
-	v := rspec.Rspec{Type: rspec.RspecTypeAdvertisement}
+	usages, err := s.listNodeUsages(r.Context())
+	if err != nil {
+		return reply.SetAndLogError(err, constants.ErrorListResources, constants.GeniCodeError)
+	}
+
+	images, err := s.ListContainerImages(r.Context())
+	if err != nil {
+		return reply.SetAndLogError(err, constants.ErrorListResources, constants.GeniCodeError)
+	}
+
+	v := rspec.Rspec{Type: rspec.RspecTypeAdvertisement, Opstate: rspecOpstate(*s)}
 	for _, node := range nodes.Items {
-		node_ := rspecForNode(node, s.AuthorityIdentifier, s.ContainerImages)
+		node_ := rspecForNode(node, usages[node.Name], s.AuthorityIdentifier, images, s.ExclusiveNodeLabel)
 		if !(args.Options.Available && !node_.Available.Now) {
 			v.Nodes = append(v.Nodes, node_)
 		}
@@ -77,53 +90,162 @@ func (s *Service) ListResources(
 	return nil
 }
 
-// rspecForNode converts a Kubernetes node to an RSpec node.
-func rspecForNode(
-	node corev1.Node,
-	authorityIdentifier identifiers.Identifier,
-	containerImages map[string]string,
-) rspec.Node {
-	nodeArch := node.Labels[corev1.LabelArchStable]
-	nodeCountry := node.Labels[constants.EdgeNetLabelCountryISO]
-	nodeLatitude := node.Labels[constants.EdgeNetLabelLatitude]
-	nodeLongitude := node.Labels[constants.EdgeNetLabelLongitude]
+// nodeUsage holds the resources requested by the pods scheduled on a node.
+type nodeUsage struct {
+	Requests corev1.ResourceList
+	Slivers  int
+	// The node is held by an exclusive sliver.
+	Exclusive bool
+}
+
+// listNodeUsages returns the usage of each node, indexed by node name.
+func (s Service) listNodeUsages(ctx context.Context) (map[string]nodeUsage, error) {
+	pods, err := s.Pods(metav1.NamespaceAll).List(ctx, metav1.ListOptions{
+		FieldSelector: fmt.Sprintf(
+			"%s!=%s,%s!=%s",
+			"status.phase", corev1.PodSucceeded,
+			"status.phase", corev1.PodFailed,
+		),
+	})
+	if err != nil {
+		return nil, err
+	}
+	usages := make(map[string]nodeUsage)
+	for _, pod := range pods.Items {
+		if pod.Spec.NodeName == "" {
+			continue
+		}
+		usage, ok := usages[pod.Spec.NodeName]
+		if !ok {
+			usage.Requests = make(corev1.ResourceList)
+		}
+		for name, quantity := range podRequests(pod) {
+			total := usage.Requests[name]
+			total.Add(quantity)
+			usage.Requests[name] = total
+		}
+		if _, ok := pod.Labels[constants.Fed4FireSliverName]; ok {
+			usage.Slivers++
+		}
+		if pod.Labels[constants.Fed4FireExclusive] == "true" {
+			usage.Exclusive = true
+		}
+		usages[pod.Spec.NodeName] = usage
+	}
+	return usages, nil
+}
+
+// podRequests returns the resources requested by a pod, as computed by the scheduler:
+// the sum of the requests of the containers, or the largest request of the init containers if greater.
+func podRequests(pod corev1.Pod) corev1.ResourceList {
+	requests := make(corev1.ResourceList)
+	for _, container := range pod.Spec.Containers {
+		for name, quantity := range container.Resources.Requests {
+			total := requests[name]
+			total.Add(quantity)
+			requests[name] = total
+		}
+	}
+	for _, container := range pod.Spec.InitContainers {
+		for name, quantity := range container.Resources.Requests {
+			if quantity.Cmp(requests[name]) > 0 {
+				requests[name] = quantity.DeepCopy()
+			}
+		}
+	}
+	return requests
+}
+
+// canFitDefaultSliver returns true if a sliver with the default requests can be scheduled on a node.
+func canFitDefaultSliver(node corev1.Node, usage nodeUsage) bool {
+	defaultRequests := corev1.ResourceList{
+		corev1.ResourceCPU:    resource.MustParse(constants.DefaultCpuRequest),
+		corev1.ResourceMemory: resource.MustParse(constants.DefaultMemoryRequest),
+	}
+	for name, request := range defaultRequests {
+		free := node.Status.Allocatable[name]
+		free.Sub(usage.Requests[name])
+		if free.Cmp(request) < 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// rspecLocationForNode returns the location of a Kubernetes node, from the EdgeNet labels.
+func rspecLocationForNode(node corev1.Node) *rspec.Location {
+	latitude := node.Labels[constants.EdgeNetLabelLatitude]
+	longitude := node.Labels[constants.EdgeNetLabelLongitude]
 	// n39.92050 -> 39.92050
-	if len(nodeLatitude) > 1 {
-		nodeLatitude = nodeLatitude[1:]
+	if len(latitude) > 1 {
+		latitude = latitude[1:]
 	}
-	if len(nodeLongitude) > 1 {
-		nodeLongitude = nodeLongitude[1:]
+	if len(longitude) > 1 {
+		longitude = longitude[1:]
 	}
-	nodeName := node.Name
-	nodeIsReady := true
+	return &rspec.Location{
+		Country:   node.Labels[constants.EdgeNetLabelCountryISO],
+		Latitude:  latitude,
+		Longitude: longitude,
+	}
+}
+
+// nodeIsReady returns true if a node is schedulable and its Ready condition is true.
+func nodeIsReady(node corev1.Node) bool {
 	for _, condition := range node.Status.Conditions {
 		if condition.Type == "Ready" && condition.Status != "True" {
-			nodeIsReady = false
-			break
+			return false
 		}
 	}
+	return !node.Spec.Unschedulable
+}
+
+// rspecForNode converts a Kubernetes node to an RSpec node.
+func rspecForNode(
+	node corev1.Node,
+	usage nodeUsage,
+	authorityIdentifier identifiers.Identifier,
+	containerImages []v1.ContainerImage,
+	exclusiveNodeLabel string,
+) rspec.Node {
+	nodeArch := node.Labels[corev1.LabelArchStable]
+	nodeName := node.Name
+	nodeIsAvailable := nodeIsReady(node) && canFitDefaultSliver(node, usage) && !usage.Exclusive
 	diskImages := make([]rspec.DiskImage, 0)
-	for name := range containerImages {
+	for _, image := range containerImages {
+		if !supportsArch(image, nodeArch) {
+			continue
+		}
 		diskImages = append(diskImages, rspec.DiskImage{
-			Name: authorityIdentifier.Copy(identifiers.ResourceTypeImage, name).URN(),
+			Name:        authorityIdentifier.Copy(identifiers.ResourceTypeImage, image.Name).URN(),
+			OS:          image.Spec.OS,
+			Version:     image.Spec.OSVersion,
+			Description: image.Spec.Description,
 		})
 	}
 	return rspec.Node{
 		ComponentID:        authorityIdentifier.Copy(identifiers.ResourceTypeNode, nodeName).URN(),
 		ComponentManagerID: authorityIdentifier.URN(),
 		ComponentName:      nodeName,
-		Available:          &rspec.Available{Now: nodeIsReady},
-		Location: &rspec.Location{
-			Country:   nodeCountry,
-			Latitude:  nodeLatitude,
-			Longitude: nodeLongitude,
-		},
+		Exclusive:          nodeIsExclusive(node, exclusiveNodeLabel),
+		Available:          &rspec.Available{Now: nodeIsAvailable},
+		Location:           rspecLocationForNode(node),
 		HardwareType: &rspec.HardwareType{
 			Name: nodeArch,
 		},
 		SliverType: rspec.SliverType{
-			Name:       "container",
+			Name:       constants.SliverTypeContainer,
 			DiskImages: diskImages,
 		},
+		Capacity: &rspec.Capacity{
+			CPU:              node.Status.Allocatable.Cpu().String(),
+			Memory:           node.Status.Allocatable.Memory().String(),
+			EphemeralStorage: node.Status.Allocatable.StorageEphemeral().String(),
+		},
+		Usage: &rspec.Usage{
+			CPU:     usage.Requests.Cpu().String(),
+			Memory:  usage.Requests.Memory().String(),
+			Slivers: usage.Slivers,
+		},
 	}
 }
@@ -87,3 +87,180 @@ func TestListResources_NodesAvailableCompressed(t *testing.T) {
 	assert.Equal(t, rspec.RspecTypeAdvertisement, v.Type)
 	assert.Len(t, v.Nodes, 2)
 }
+
+func TestListResources_NodesUsage(t *testing.T) {
+	s := testService()
+	r := testRequest()
+	nodes := []*v1.Node{
+		testNode("node-1", true),
+		testNode("node-2", true),
+	}
+	for _, node := range nodes {
+		s.KubernetesClient.CoreV1().Nodes().Create(context.TODO(), node, metav1.CreateOptions{})
+	}
+	// node-1 is full, and node-2 runs one sliver.
+	pods := []*v1.Pod{
+		testPod("pod-1", "node-1", "2", "1Gi", false),
+		testPod("pod-2", "node-2", "500m", "1Gi", true),
+	}
+	for _, pod := range pods {
+		s.KubernetesClient.CoreV1().Pods("").Create(context.TODO(), pod, metav1.CreateOptions{})
+	}
+	args := &ListResourcesArgs{
+		Credentials: []Credential{testSliceCredential},
+		Options: Options{
+			RspecVersion: RspecVersion{
+				Type:    "geni",
+				Version: "3",
+			}}}
+	reply := &ListResourcesReply{}
+	err := s.ListResources(r, args, reply)
+	assert.Nil(t, err)
+	assert.Equal(t, constants.GeniCodeSuccess, reply.Data.Code.Code)
+	v := unmarshalTestRspec(reply.Data.Value)
+	assert.Len(t, v.Nodes, 2)
+	for _, node := range v.Nodes {
+		assert.Equal(t, "2", node.Capacity.CPU)
+		assert.Equal(t, "4Gi", node.Capacity.Memory)
+		assert.Equal(t, "10Gi", node.Capacity.EphemeralStorage)
+		switch node.ComponentName {
+		case "node-1":
+			assert.False(t, node.Available.Now)
+			assert.Equal(t, "2", node.Usage.CPU)
+			assert.Equal(t, 0, node.Usage.Slivers)
+		case "node-2":
+			assert.True(t, node.Available.Now)
+			assert.Equal(t, "500m", node.Usage.CPU)
+			assert.Equal(t, "1Gi", node.Usage.Memory)
+			assert.Equal(t, 1, node.Usage.Slivers)
+		}
+	}
+}
+
+func TestListResources_Exclusive(t *testing.T) {
+	s := testService()
+	r := testRequest()
+	nodes := []*v1.Node{
+		testNode("node-1", true),
+		testNode("node-2", true),
+		testNode("node-3", true),
+	}
+	nodes[0].Labels["example.org/exclusive"] = "true"
+	nodes[1].Labels["example.org/exclusive"] = "true"
+	for _, node := range nodes {
+		s.KubernetesClient.CoreV1().Nodes().Create(context.TODO(), node, metav1.CreateOptions{})
+	}
+	// node-2 is held by an exclusive sliver.
+	pod := testPod("pod-1", "node-2", "500m", "1Gi", true)
+	pod.Labels[constants.Fed4FireExclusive] = "true"
+	s.KubernetesClient.CoreV1().Pods("").Create(context.TODO(), pod, metav1.CreateOptions{})
+	args := &ListResourcesArgs{
+		Credentials: []Credential{testSliceCredential},
+		Options: Options{
+			RspecVersion: RspecVersion{
+				Type:    "geni",
+				Version: "3",
+			}}}
+	reply := &ListResourcesReply{}
+	err := s.ListResources(r, args, reply)
+	assert.Nil(t, err)
+	assert.Equal(t, constants.GeniCodeSuccess, reply.Data.Code.Code)
+	v := unmarshalTestRspec(reply.Data.Value)
+	assert.Len(t, v.Nodes, 3)
+	for _, node := range v.Nodes {
+		switch node.ComponentName {
+		case "node-1":
+			assert.True(t, node.Exclusive)
+			assert.True(t, node.Available.Now)
+		case "node-2":
+			assert.True(t, node.Exclusive)
+			assert.False(t, node.Available.Now)
+		case "node-3":
+			assert.False(t, node.Exclusive)
+			assert.True(t, node.Available.Now)
+		}
+	}
+}
+
+func TestListResources_ContainerImages(t *testing.T) {
+	s := testService()
+	r := testRequest()
+	nodes := []*v1.Node{
+		testNode("node-1", true),
+		testNode("node-2", true),
+	}
+	nodes[0].Labels[v1.LabelArchStable] = "amd64"
+	nodes[1].Labels[v1.LabelArchStable] = "arm64"
+	for _, node := range nodes {
+		s.KubernetesClient.CoreV1().Nodes().Create(context.TODO(), node, metav1.CreateOptions{})
+	}
+	image := testContainerImage("debian11", "docker.io/library/debian:11", []string{"amd64"}, false)
+	image.Spec.OS = "Debian"
+	image.Spec.OSVersion = "11"
+	s.ContainerImageCatalog().Create(context.TODO(), image, metav1.CreateOptions{})
+	args := &ListResourcesArgs{
+		Credentials: []Credential{testSliceCredential},
+		Options: Options{
+			RspecVersion: RspecVersion{
+				Type:    "geni",
+				Version: "3",
+			}}}
+	reply := &ListResourcesReply{}
+	err := s.ListResources(r, args, reply)
+	assert.Nil(t, err)
+	assert.Equal(t, constants.GeniCodeSuccess, reply.Data.Code.Code)
+	v := unmarshalTestRspec(reply.Data.Value)
+	assert.Len(t, v.Nodes, 2)
+	for _, node := range v.Nodes {
+		switch node.ComponentName {
+		case "node-1":
+			assert.Len(t, node.SliverType.DiskImages, 2)
+			diskImage := node.SliverType.DiskImages[0]
+			assert.Equal(t, "urn:publicid:IDN+example.org+image+debian11", diskImage.Name)
+			assert.Equal(t, "debian11", diskImage.Description)
+			assert.Equal(t, "Debian", diskImage.OS)
+			assert.Equal(t, "11", diskImage.Version)
+		case "node-2":
+			// debian11 does not support arm64.
+			assert.Len(t, node.SliverType.DiskImages, 1)
+		}
+	}
+}
+
+func TestListResources_Opstate(t *testing.T) {
+	s := testService()
+	r := testRequest()
+	args := &ListResourcesArgs{
+		Credentials: []Credential{testSliceCredential},
+		Options: Options{
+			RspecVersion: RspecVersion{
+				Type:    "geni",
+				Version: "3",
+			}}}
+	reply := &ListResourcesReply{}
+	err := s.ListResources(r, args, reply)
+	assert.Nil(t, err)
+	assert.Equal(t, constants.GeniCodeSuccess, reply.Data.Code.Code)
+	assert.Contains(t, reply.Data.Value, `<rspec_opstate xmlns="http://www.geni.net/resources/rspec/ext/opstate/1"`)
+	v := unmarshalTestRspec(reply.Data.Value)
+	assert.NotNil(t, v.Opstate)
+	assert.Equal(t, testAuthorityIdentifier.URN(), v.Opstate.AggregateManagerID)
+	assert.Equal(t, constants.GeniStateNotReady, v.Opstate.Start)
+	assert.Len(t, v.Opstate.SliverTypes, 2)
+	// Each advertised action must be accepted by PerformOperationalAction, and lead to an advertised state.
+	states := make(map[string]bool)
+	for _, state := range v.Opstate.States {
+		states[state.Name] = true
+	}
+	for _, state := range v.Opstate.States {
+		for _, action := range state.Actions {
+			next, err := nextOperationalState(state.Name, action.Name)
+			assert.Nil(t, err)
+			assert.Equal(t, action.Next, next)
+			assert.True(t, states[next], next)
+		}
+		for _, wait := range state.Waits {
+			assert.True(t, states[wait.Next], wait.Next)
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package service
+
+import (
+	"context"
+	v1 "github.com/EdgeNet-project/fed4fire/pkg/apis/fed4fire/v1"
+	"github.com/EdgeNet-project/fed4fire/pkg/constants"
+	"github.com/EdgeNet-project/fed4fire/pkg/identifiers"
+	"github.com/EdgeNet-project/fed4fire/pkg/rspec"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// manifestRspec returns the manifest RSpec of the given slivers, as returned by Allocate, Provision and Describe.
+// The namespace declarations and the unknown elements of the request RSpec, stored with the slivers, are echoed as well.
+func (s Service) manifestRspec(ctx context.Context, slivers []v1.Sliver) (rspec.Rspec, error) {
+	manifest := rspec.Rspec{Type: rspec.RspecTypeManifest}
+	for _, sliver := range slivers {
+		node, err := s.manifestNode(ctx, sliver)
+		if err != nil {
+			return rspec.Rspec{}, err
+		}
+		manifest.Nodes = append(manifest.Nodes, node)
+	}
+	manifest.Links = rspecLinksForSlivers(s, slivers)
+	manifest.Groups = rspecGroupsForSlivers(slivers)
+	for _, sliver := range slivers {
+		err := rspec.UnmarshalRspecExtensions(sliver.Spec.RspecSliceExtensions, &manifest)
+		if err != nil {
+			return rspec.Rspec{}, err
+		}
+	}
+	return manifest, nil
+}
+
+// manifestNode returns the manifest RSpec node of a sliver.
+// The component is the Kubernetes node on which the sliver is scheduled, or the requested one until then.
+func (s Service) manifestNode(ctx context.Context, sliver v1.Sliver) (rspec.Node, error) {
+	node := rspec.Node{
+		ClientID:           sliver.Spec.ClientID,
+		ComponentManagerID: s.AuthorityIdentifier.URN(),
+		SliverID:           sliver.Spec.URN,
+		Exclusive:          sliver.Spec.Exclusive,
+		Available:          &rspec.Available{Now: false},
+		SliverType:         rspecSliverTypeForSliver(sliver),
+		Interfaces:         rspecInterfacesForSliver(sliver),
+		Resources:          rspecResourcesForSliver(sliver),
+	}
+	var componentName, arch, nodeAddress string
+	if sliver.Spec.RequestedNode != nil {
+		componentName = *sliver.Spec.RequestedNode
+	}
+	if sliver.Spec.RequestedArch != nil {
+		arch = *sliver.Spec.RequestedArch
+	}
+	if kubernetesNode := s.GetSliverNode(ctx, sliver.Namespace, sliver.Name); kubernetesNode != nil {
+		componentName = kubernetesNode.Name
+		arch = kubernetesNode.Labels[corev1.LabelArchStable]
+		node.Location = rspecLocationForNode(*kubernetesNode)
+		nodeAddress = nodeInternalIP(*kubernetesNode)
+	}
+	if componentName != "" {
+		node.ComponentID = s.AuthorityIdentifier.Copy(identifiers.ResourceTypeNode, componentName).URN()
+		node.ComponentName = componentName
+	}
+	if arch != "" {
+		node.HardwareType = &rspec.HardwareType{Name: arch}
+	}
+	logins := make([]rspec.Login, 0)
+	_, host, port := s.GetSliverArchHostPort(ctx, sliver.Namespace, sliver.Name)
+	if host != nil && port != nil {
+		node.Available.Now = true
+		logins = rspecLoginsForSliver(s, sliver, *host, *port)
+	}
+	node.Services = rspecServicesForSliver(sliver, logins)
+	if len(sliver.Spec.Volumes) > 0 {
+		node.Volumes = rspecVolumesForSliver(sliver)
+	}
+	if s.ClusterDomain != "" {
+		node.Host = rspecHostForSliver(s, sliver, s.GetSliverPod(ctx, sliver.Namespace, sliver.Name))
+	}
+	if len(sliver.Spec.Ports) > 0 {
+		node.Ports = rspecPortsForSliver(s, sliver, s.GetSliverService(ctx, sliver.Namespace, sliver.Name), nodeAddress)
+	}
+	err := rspec.UnmarshalNodeExtensions(sliver.Spec.RspecExtensions, &node)
+	if err != nil {
+		return rspec.Node{}, err
+	}
+	return node, nil
+}
+
+// rspecLoginsForSliver returns an SSH login service for each user of a sliver.
+// The users share the account of the security profile, and custom images do not run sshd.
+func rspecLoginsForSliver(s Service, sliver v1.Sliver, host string, port int) []rspec.Login {
+	logins := make([]rspec.Login, 0)
+	if sliver.Spec.SliverType == constants.SliverTypeCustomContainer {
+		return logins
+	}
+	users := sliver.Spec.Users
+	// Slivers provisioned by earlier versions of the AM have no users.
+	if len(users) == 0 {
+		users = []string{sliver.Spec.UserURN}
+	}
+	for range users {
+		logins = append(logins, rspec.Login{
+			Authentication: rspec.RspecLoginAuthenticationSSH,
+			Hostname:       host,
+			Port:           port,
+			Username:       "root",
+		})
+	}
+	return logins
+}
+
+// rspecResourcesForSliver returns the resources requested for a sliver,
+// after they have been capped, or nil if none were requested.
+func rspecResourcesForSliver(sliver v1.Sliver) *rspec.Resources {
+	if sliver.Spec.CpuRequest == nil && sliver.Spec.MemoryRequest == nil {
+		return nil
+	}
+	resources := &rspec.Resources{}
+	if sliver.Spec.CpuRequest != nil {
+		resources.CPU = *sliver.Spec.CpuRequest
+	}
+	if sliver.Spec.MemoryRequest != nil {
+		resources.Memory = *sliver.Spec.MemoryRequest
+	}
+	return resources
+}
+
+// rspecSliverTypeForSliver returns the sliver type and the image of a sliver, as reported in the manifests.
+func rspecSliverTypeForSliver(sliver v1.Sliver) rspec.SliverType {
+	name := sliver.Spec.SliverType
+	// Slivers created by earlier versions of the AM have no sliver type.
+	if name == "" {
+		name = constants.SliverTypeContainer
+	}
+	return rspec.SliverType{
+		Name:       name,
+		DiskImages: []rspec.DiskImage{{Name: sliver.Spec.DiskImageName, URL: sliver.Spec.Image}},
+	}
+}
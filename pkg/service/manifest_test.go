@@ -0,0 +1,224 @@
+package service
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"github.com/EdgeNet-project/fed4fire/pkg/constants"
+	"github.com/EdgeNet-project/fed4fire/pkg/naming"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"net/http"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "update the golden files")
+
+// Line breaks of the golden files, before the start elements and after the end elements.
+var startElement = regexp.MustCompile(`><([^/])`)
+var endElement = regexp.MustCompile(`</[^>]+>`)
+
+// assertGoldenManifest compares a manifest with a golden file, with one element per line to keep the diffs readable.
+func assertGoldenManifest(t *testing.T, name string, manifest string) {
+	output := endElement.ReplaceAllString(manifest, "$0\n")
+	output = startElement.ReplaceAllString(output, ">\n<$1")
+	golden := filepath.Join("testdata", name+".golden")
+	if *update {
+		err := ioutil.WriteFile(golden, []byte(output), 0644)
+		assert.Nil(t, err)
+	}
+	expected, err := ioutil.ReadFile(golden)
+	assert.Nil(t, err)
+	assert.Equal(t, string(expected), output, name)
+}
+
+// provisionTestSliceForUsers provisions the test slice with the keys of the given users.
+func provisionTestSliceForUsers(s *Service, r *http.Request, users ...string) *ProvisionReply {
+	args := &ProvisionArgs{
+		URNs:        []string{testSliceIdentifier.URN()},
+		Credentials: []Credential{testSliceCredential},
+	}
+	for _, user := range users {
+		args.Options.Users = append(args.Options.Users, struct {
+			URN  string   `xml:"urn"`
+			Keys []string `xml:"keys"`
+		}{URN: user, Keys: []string{"ssh-ed25519 AAAA " + user}})
+	}
+	reply := &ProvisionReply{}
+	err := s.Provision(r, args, reply)
+	if err != nil {
+		panic(err)
+	}
+	return reply
+}
+
+// scheduleTestSlivers runs the pods of the test slivers on a node, and exposes their services.
+func scheduleTestSlivers(t *testing.T, s *Service) {
+	node := testNode("node-1", true)
+	node.Labels[corev1.LabelArchStable] = "amd64"
+	node.Labels[constants.EdgeNetLabelCountryISO] = "BR"
+	node.Status.Addresses = []corev1.NodeAddress{{Type: corev1.NodeInternalIP, Address: "192.0.2.1"}}
+	_, err := s.Nodes().Create(context.TODO(), node, metav1.CreateOptions{})
+	assert.Nil(t, err)
+	for i, sliver := range listTestSlivers(s) {
+		pod := testPod(sliver.Name, node.Name, "1", "1Gi", true)
+		pod.Status.PodIP = fmt.Sprintf("10.244.0.%d", 2+i)
+		_, err = s.Pods(sliver.Namespace).Create(context.TODO(), pod, metav1.CreateOptions{})
+		assert.Nil(t, err)
+		service, err := s.Services(sliver.Namespace).Get(context.TODO(), sliver.Name, metav1.GetOptions{})
+		assert.Nil(t, err)
+		for j := range service.Spec.Ports {
+			service.Spec.Ports[j].NodePort = int32(30022 + 1000*j + i)
+		}
+		_, err = s.Services(sliver.Namespace).Update(context.TODO(), service, metav1.UpdateOptions{})
+		assert.Nil(t, err)
+	}
+}
+
+func TestManifest_Allocate(t *testing.T) {
+	s := testService()
+	r := testRequest()
+	args := &AllocateArgs{
+		SliceURN:    testSliceIdentifier.URN(),
+		Credentials: []Credential{testSliceCredential},
+		Rspec:       testRspecExtensions,
+	}
+	reply := &AllocateReply{}
+	err := s.Allocate(r, args, reply)
+	assert.Nil(t, err)
+	assert.Equal(t, constants.GeniCodeSuccess, reply.Data.Code.Code)
+	assertGoldenManifest(t, "manifest_allocate", reply.Data.Value.Rspec)
+}
+
+func TestManifest_Provision(t *testing.T) {
+	s := testService()
+	r := testRequest()
+	allocateTestSlice(s, r, testRspecLinks)
+	reply := provisionTestSliceForUsers(s, r, testUserIdentifier.URN())
+	assert.Equal(t, constants.GeniCodeSuccess, reply.Data.Code.Code)
+	assertGoldenManifest(t, "manifest_provision", reply.Data.Value.Rspec)
+}
+
+func TestManifest_Describe(t *testing.T) {
+	s := testService()
+	r := testRequest()
+	allocateTestSlice(s, r, testRspecServices)
+	provisionTestSliceForUsers(
+		s,
+		r,
+		testUserIdentifier.URN(),
+		"urn:publicid:IDN+example.org+user+other",
+	)
+	scheduleTestSlivers(t, s)
+	args := &DescribeArgs{
+		URNs:        []string{testSliceIdentifier.URN()},
+		Credentials: []Credential{testSliceCredential},
+	}
+	reply := &DescribeReply{}
+	err := s.Describe(r, args, reply)
+	assert.Nil(t, err)
+	assert.Equal(t, constants.GeniCodeSuccess, reply.Data.Code.Code)
+	assertGoldenManifest(t, "manifest_describe", reply.Data.Value.Rspec)
+
+	v := unmarshalTestRspec(reply.Data.Value.Rspec)
+	node := v.Nodes[0]
+	assert.Equal(t, testAuthorityIdentifier.Copy("node", "node-1").URN(), node.ComponentID)
+	assert.Equal(t, "node-1", node.ComponentName)
+	assert.Equal(t, "amd64", node.HardwareType.Name)
+	assert.Equal(t, "BR", node.Location.Country)
+	assert.Equal(t, listTestSlivers(s)[0].Spec.URN, node.SliverID)
+	assert.Len(t, node.Services.Logins, 2)
+	assert.Equal(t, 30022, node.Services.Logins[0].Port)
+}
+
+func TestManifest_Ports(t *testing.T) {
+	s := testService()
+	r := testRequest()
+	allocateTestSlice(s, r, testRspecPorts)
+	provisionTestSlice(s, r)
+	scheduleTestSlivers(t, s)
+	args := &DescribeArgs{
+		URNs:        []string{testSliceIdentifier.URN()},
+		Credentials: []Credential{testSliceCredential},
+	}
+	reply := &DescribeReply{}
+	err := s.Describe(r, args, reply)
+	assert.Nil(t, err)
+	v := unmarshalTestRspec(reply.Data.Value.Rspec)
+	ports := v.Nodes[0].Ports
+	assert.Len(t, ports, 2)
+	assert.Equal(t, "8080", ports[0].Number)
+	assert.Equal(t, "tcp", ports[0].Protocol)
+	assert.Equal(t, "192.0.2.1", ports[0].Host)
+	assert.Equal(t, 31022, ports[0].ExternalPort)
+	assert.Equal(t, "5353", ports[1].Number)
+	assert.Equal(t, "udp", ports[1].Protocol)
+	assert.Equal(t, 32022, ports[1].ExternalPort)
+	// The SSH login must still use the SSH port.
+	assert.Equal(t, 30022, v.Nodes[0].Services.Logins[0].Port)
+}
+
+func TestManifest_Ingress(t *testing.T) {
+	s := testService()
+	s.IngressIssuer = "letsencrypt"
+	r := testRequest()
+	allocateTestSlice(s, r, strings.Replace(
+		testRspecPorts,
+		`<edgenet:port number="8080"/>`,
+		`<edgenet:port number="8080" ingress="true"/>`,
+		1,
+	))
+	provisionTestSlice(s, r)
+	scheduleTestSlivers(t, s)
+	args := &DescribeArgs{
+		URNs:        []string{testSliceIdentifier.URN()},
+		Credentials: []Credential{testSliceCredential},
+	}
+	reply := &DescribeReply{}
+	err := s.Describe(r, args, reply)
+	assert.Nil(t, err)
+	v := unmarshalTestRspec(reply.Data.Value.Rspec)
+	ports := v.Nodes[0].Ports
+	assert.Equal(
+		t,
+		"https://pc1-8080-"+naming.SliceHash(testSliceIdentifier.URN())+".slivers.example.org/",
+		ports[0].URL,
+	)
+	// The port is still reachable at the node port.
+	assert.Equal(t, 31022, ports[0].ExternalPort)
+}
+
+func TestManifest_Hosts(t *testing.T) {
+	s := testService()
+	r := testRequest()
+	allocateTestSlice(s, r, testRspecLinks)
+	provisionTestSlice(s, r)
+	scheduleTestSlivers(t, s)
+	args := &DescribeArgs{
+		URNs:        []string{testSliceIdentifier.URN()},
+		Credentials: []Credential{testSliceCredential},
+	}
+	reply := &DescribeReply{}
+	err := s.Describe(r, args, reply)
+	assert.Nil(t, err)
+	v := unmarshalTestRspec(reply.Data.Value.Rspec)
+	assert.Len(t, v.Nodes, 2)
+	addresses := make(map[string]bool)
+	for _, node := range v.Nodes {
+		host := node.Host
+		assert.Equal(t, strings.ToLower(node.ClientID), host.Name)
+		assert.Equal(
+			t,
+			host.Name+"."+naming.SliceHash(testSliceIdentifier.URN())+".default.svc.cluster.local",
+			host.FQDN,
+		)
+		assert.NotEqual(t, "", host.Address)
+		addresses[host.Address] = true
+	}
+	assert.Len(t, addresses, 2)
+}
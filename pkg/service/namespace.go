@@ -0,0 +1,177 @@
+package service
+
+import (
+	"context"
+	"github.com/EdgeNet-project/fed4fire/pkg/constants"
+	"github.com/EdgeNet-project/fed4fire/pkg/naming"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+)
+
+type sliceNamespaceResources struct {
+	Namespace     *corev1.Namespace
+	ResourceQuota *corev1.ResourceQuota
+	LimitRange    *corev1.LimitRange
+}
+
+// buildSliceNamespace builds the namespace holding the slivers of a slice,
+// as well as the quota and the default limits applied inside.
+func buildSliceNamespace(s Service, sliceUrn string) *sliceNamespaceResources {
+	name := naming.SliceHash(sliceUrn)
+	labels := map[string]string{
+		constants.Fed4FireSliceHash: name,
+	}
+
+	namespace := &corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   name,
+			Labels: labels,
+		},
+	}
+
+	resourceQuota := &corev1.ResourceQuota{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: name,
+			Labels:    labels,
+		},
+		Spec: corev1.ResourceQuotaSpec{
+			Hard: map[corev1.ResourceName]resource.Quantity{
+				corev1.ResourceLimitsCPU:    s.NamespaceCpuLimit,
+				corev1.ResourceLimitsMemory: s.NamespaceMemoryLimit,
+			},
+		},
+	}
+
+	// The users of a slice can request up to their own maximum, so the highest one applies to the namespace.
+	highest := MaxRequest{Cpu: s.MaxCpuRequest, Memory: s.MaxMemoryRequest}
+	for _, max := range s.UserMaxRequests {
+		highest.Cpu = maxQuantity(highest.Cpu, max.Cpu)
+		highest.Memory = maxQuantity(highest.Memory, max.Memory)
+	}
+
+	limitRange := &corev1.LimitRange{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: name,
+			Labels:    labels,
+		},
+		Spec: corev1.LimitRangeSpec{
+			Limits: []corev1.LimitRangeItem{{
+				Type: corev1.LimitTypeContainer,
+				// The container limits are raised up to the maximum requests when needed.
+				Max: map[corev1.ResourceName]resource.Quantity{
+					corev1.ResourceCPU:    maxQuantity(s.ContainerCpuLimit, highest.Cpu),
+					corev1.ResourceMemory: maxQuantity(s.ContainerMemoryLimit, highest.Memory),
+				},
+				Default: map[corev1.ResourceName]resource.Quantity{
+					corev1.ResourceCPU:    s.ContainerCpuLimit,
+					corev1.ResourceMemory: s.ContainerMemoryLimit,
+				},
+				DefaultRequest: map[corev1.ResourceName]resource.Quantity{
+					corev1.ResourceCPU:    resource.MustParse(constants.DefaultCpuRequest),
+					corev1.ResourceMemory: resource.MustParse(constants.DefaultMemoryRequest),
+				},
+			}},
+		},
+	}
+
+	return &sliceNamespaceResources{namespace, resourceQuota, limitRange}
+}
+
+// maxQuantity returns the largest of two quantities.
+func maxQuantity(a resource.Quantity, b resource.Quantity) resource.Quantity {
+	if b.Cmp(a) > 0 {
+		return b
+	}
+	return a
+}
+
+// createSliceNamespace creates the namespace of a slice if it does not already exist.
+// It does nothing when the service is not configured to use one namespace per slice.
+func createSliceNamespace(ctx context.Context, service Service, sliceUrn string) error {
+	if !service.NamespacePerSlice {
+		return nil
+	}
+	resources := buildSliceNamespace(service, sliceUrn)
+	_, err := service.Namespaces().Create(ctx, resources.Namespace, metav1.CreateOptions{})
+	if err != nil && !errors.IsAlreadyExists(err) {
+		return err
+	}
+	_, err = service.ResourceQuotas(resources.Namespace.Name).
+		Create(ctx, resources.ResourceQuota, metav1.CreateOptions{})
+	if err != nil && !errors.IsAlreadyExists(err) {
+		return err
+	}
+	_, err = service.LimitRanges(resources.Namespace.Name).
+		Create(ctx, resources.LimitRange, metav1.CreateOptions{})
+	if err != nil && !errors.IsAlreadyExists(err) {
+		return err
+	}
+	if service.IngressTLSSecret != "" {
+		return copyIngressTLSSecret(ctx, service, resources.Namespace.Name)
+	}
+	return nil
+}
+
+// copyIngressTLSSecret copies the TLS secret of the ingresses from the namespace of the AM to the namespace of a slice,
+// since an ingress can only use a secret of its own namespace. An existing copy is updated, e.g. after a renewal.
+func copyIngressTLSSecret(ctx context.Context, service Service, namespace string) error {
+	secret, err := service.Secrets(service.Namespace).Get(ctx, service.IngressTLSSecret, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	secretCopy := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      secret.Name,
+			Namespace: namespace,
+			Labels: map[string]string{
+				constants.Fed4FireSliceHash: namespace,
+			},
+		},
+		Type: secret.Type,
+		Data: secret.Data,
+	}
+	client := service.Secrets(namespace)
+	_, err = client.Create(ctx, secretCopy, metav1.CreateOptions{})
+	if err == nil || !errors.IsAlreadyExists(err) {
+		return err
+	}
+	existing, err := client.Get(ctx, secretCopy.Name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	existing.Data = secret.Data
+	_, err = client.Update(ctx, existing, metav1.UpdateOptions{})
+	return err
+}
+
+// deleteSliceNamespaceIfEmpty deletes the namespace of a slice once it does not contain any sliver or retained volume.
+// It does nothing when the service is not configured to use one namespace per slice.
+func deleteSliceNamespaceIfEmpty(ctx context.Context, service Service, sliceUrn string) error {
+	if !service.NamespacePerSlice {
+		return nil
+	}
+	name := service.SliceNamespace(sliceUrn)
+	slivers, err := service.Slivers(name).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+	if len(slivers.Items) > 0 {
+		return nil
+	}
+	// The retained volumes are deleted by the collector when they expire, and the namespace afterwards.
+	retained, err := hasRetainedVolumeClaims(ctx, service, name)
+	if err != nil || retained {
+		return err
+	}
+	err = service.Namespaces().Delete(ctx, name, metav1.DeleteOptions{})
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	klog.InfoS("Deleted slice namespace", "namespace", name)
+	return nil
+}
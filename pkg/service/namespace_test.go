@@ -0,0 +1,115 @@
+package service
+
+import (
+	"context"
+	"github.com/EdgeNet-project/fed4fire/pkg/constants"
+	"github.com/EdgeNet-project/fed4fire/pkg/naming"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"strings"
+	"testing"
+)
+
+func TestNamespacePerSlice(t *testing.T) {
+	s := testService()
+	s.NamespacePerSlice = true
+	r := testRequest()
+	namespace := naming.SliceHash(testSliceIdentifier.URN())
+
+	allocateTestSlice(s, r, testRspecMany)
+	_, err := s.Namespaces().Get(context.TODO(), namespace, metav1.GetOptions{})
+	assert.Nil(t, err)
+	quota, err := s.ResourceQuotas(namespace).Get(context.TODO(), namespace, metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, "8", quota.Spec.Hard.Name(corev1.ResourceLimitsCPU, "").String())
+	_, err = s.LimitRanges(namespace).Get(context.TODO(), namespace, metav1.GetOptions{})
+	assert.Nil(t, err)
+	for _, sliver := range listTestSlivers(s) {
+		assert.Equal(t, namespace, sliver.Namespace)
+	}
+
+	provisionTestSlice(s, r)
+	deployments := listTestDeployments(s)
+	assert.Len(t, deployments, 2)
+	for _, deployment := range deployments {
+		assert.Equal(t, namespace, deployment.Namespace)
+	}
+
+	// Slivers must be found across namespaces, by slice and by sliver URN.
+	slivers, err := s.AuthorizeAndListSlivers(
+		r,
+		[]string{testSliceIdentifier.URN()},
+		[]Credential{testSliceCredential},
+	)
+	assert.Nil(t, err)
+	assert.Len(t, slivers, 2)
+	slivers, err = s.AuthorizeAndListSlivers(
+		r,
+		[]string{slivers[0].Spec.URN},
+		[]Credential{testSliceCredential},
+	)
+	assert.Nil(t, err)
+	assert.Len(t, slivers, 1)
+
+	deleteArgs := &DeleteArgs{
+		URNs:        []string{testSliceIdentifier.URN()},
+		Credentials: []Credential{testSliceCredential},
+	}
+	deleteReply := &DeleteReply{}
+	err = s.Delete(r, deleteArgs, deleteReply)
+	assert.Nil(t, err)
+	assert.Equal(t, constants.GeniCodeSuccess, deleteReply.Data.Code.Code)
+	_, err = s.Namespaces().Get(context.TODO(), namespace, metav1.GetOptions{})
+	assert.NotNil(t, err)
+}
+
+func TestNamespacePerSlice_BadRequest(t *testing.T) {
+	s := testService()
+	s.NamespacePerSlice = true
+	r := testRequest()
+	args := &AllocateArgs{
+		SliceURN:    testSliceIdentifier.URN(),
+		Credentials: []Credential{testSliceCredential},
+		Rspec:       strings.Replace(testRspecSingle, `client_id="PC1"`, `client_id="PC1" component_id="invalid"`, 1),
+	}
+	reply := &AllocateReply{}
+	err := s.Allocate(r, args, reply)
+	assert.Nil(t, err)
+	assert.Equal(t, constants.GeniCodeError, reply.Data.Code.Code)
+	// The namespace must not be created for a rejected request.
+	namespaces, err := s.Namespaces().List(context.TODO(), metav1.ListOptions{})
+	assert.Nil(t, err)
+	assert.Len(t, namespaces.Items, 0)
+}
+
+func TestNamespacePerSlice_IngressTLSSecret(t *testing.T) {
+	s := testService()
+	s.NamespacePerSlice = true
+	s.IngressTLSSecret = "wildcard-tls"
+	r := testRequest()
+	namespace := naming.SliceHash(testSliceIdentifier.URN())
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "wildcard-tls", Namespace: s.Namespace},
+		Type:       corev1.SecretTypeTLS,
+		Data:       map[string][]byte{corev1.TLSCertKey: []byte("cert"), corev1.TLSPrivateKeyKey: []byte("key")},
+	}
+	_, err := s.Secrets(s.Namespace).Create(context.TODO(), secret, metav1.CreateOptions{})
+	assert.Nil(t, err)
+
+	// The ingresses of the slice use a copy of the secret in its namespace.
+	allocateTestSlice(s, r, testRspecPorts)
+	secretCopy, err := s.Secrets(namespace).Get(context.TODO(), "wildcard-tls", metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, corev1.SecretTypeTLS, secretCopy.Type)
+	assert.Equal(t, secret.Data, secretCopy.Data)
+
+	// The copy is updated when the slice is allocated again.
+	secret.Data[corev1.TLSCertKey] = []byte("renewed")
+	_, err = s.Secrets(s.Namespace).Update(context.TODO(), secret, metav1.UpdateOptions{})
+	assert.Nil(t, err)
+	allocateTestSlice(s, r, testRspecPorts)
+	secretCopy, err = s.Secrets(namespace).Get(context.TODO(), "wildcard-tls", metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("renewed"), secretCopy.Data[corev1.TLSCertKey])
+}
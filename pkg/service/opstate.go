@@ -0,0 +1,94 @@
+package service
+
+import (
+	"fmt"
+	"github.com/EdgeNet-project/fed4fire/pkg/constants"
+	"github.com/EdgeNet-project/fed4fire/pkg/rspec"
+	"strings"
+)
+
+// sliverStates is the operational state machine of the slivers, advertised in ListResources
+// and enforced by PerformOperationalAction. The first state is the initial one.
+// The containers are started when the slivers are provisioned, so geni_start has no effect.
+var sliverStates = []rspec.OpstateState{
+	{
+		Name: constants.GeniStateNotReady,
+		Actions: []rspec.OpstateAction{{
+			Name:        constants.GeniActionStart,
+			Next:        constants.GeniStateConfiguring,
+			Description: "Wait for the container to be scheduled and started.",
+		}},
+		Waits: []rspec.OpstateWait{
+			{Type: rspec.OpstateWaitSuccess, Next: constants.GeniStateReady},
+			{Type: rspec.OpstateWaitFailure, Next: constants.GeniStateFailed},
+		},
+		Description: "The container is not running yet.",
+	},
+	{
+		Name: constants.GeniStateConfiguring,
+		Waits: []rspec.OpstateWait{
+			{Type: rspec.OpstateWaitSuccess, Next: constants.GeniStateReady},
+			{Type: rspec.OpstateWaitFailure, Next: constants.GeniStateFailed},
+		},
+		Description: "The install and execute services are running.",
+	},
+	{
+		Name: constants.GeniStateReady,
+		Actions: []rspec.OpstateAction{{
+			Name:        constants.GeniActionStart,
+			Next:        constants.GeniStateReady,
+			Description: "Do nothing, the container is already running.",
+		}},
+		Description: "The container is running.",
+	},
+	{
+		Name:        constants.GeniStateFailed,
+		Description: "An install or execute service failed. The sliver must be deleted.",
+	},
+}
+
+// rspecOpstate returns the operational state machine of the slivers, as advertised in ListResources.
+func rspecOpstate(s Service) *rspec.Opstate {
+	return &rspec.Opstate{
+		AggregateManagerID: s.AuthorityIdentifier.URN(),
+		Start:              sliverStates[0].Name,
+		SliverTypes: []rspec.OpstateSliverType{
+			{Name: constants.SliverTypeContainer},
+			{Name: constants.SliverTypeCustomContainer},
+		},
+		States: sliverStates,
+	}
+}
+
+// nextOperationalState returns the state of a sliver after an action,
+// or an error if the action is not allowed in its current state.
+func nextOperationalState(state string, action string) (string, error) {
+	actions := make([]string, 0)
+	for _, state_ := range sliverStates {
+		for _, action_ := range state_.Actions {
+			if state_.Name == state && action_.Name == action {
+				return action_.Next, nil
+			}
+			actions = append(actions, action_.Name)
+		}
+	}
+	for _, action_ := range actions {
+		if action_ == action {
+			return "", fmt.Errorf("action %s is not allowed in state %s", action, state)
+		}
+	}
+	return "", fmt.Errorf("action must be one of %s", strings.Join(uniqueStrings(actions), ", "))
+}
+
+// uniqueStrings returns the distinct strings of a slice, in order.
+func uniqueStrings(values []string) []string {
+	unique := make([]string, 0)
+	seen := make(map[string]bool)
+	for _, value := range values {
+		if !seen[value] {
+			seen[value] = true
+			unique = append(unique, value)
+		}
+	}
+	return unique
+}
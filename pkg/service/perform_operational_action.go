@@ -46,17 +46,23 @@ func (s *Service) PerformOperationalAction(
 		return reply.SetAndLogError(err, constants.ErrorListResources)
 	}
 
-	if args.Action != constants.GeniActionStart {
-		return reply.SetAndLogError(
-			fmt.Errorf("action must be %s", constants.GeniActionStart),
-			constants.ErrorBadAction,
-		)
+	// The action must be allowed for all the slivers, before it is performed on any of them.
+	for _, sliver := range slivers {
+		_, operationalStatus := s.GetSliverStatus(r.Context(), sliver.Namespace, sliver.Name)
+		_, err = nextOperationalState(operationalStatus, args.Action)
+		if err != nil {
+			return reply.SetAndLogError(err, constants.ErrorBadAction, "sliver", sliver.Spec.URN)
+		}
 	}
 
 	// Do nothing, `geni_start` is a no-op for us.
 
 	for _, sliver := range slivers {
-		allocationStatus, operationalStatus := s.GetSliverStatus(r.Context(), sliver.Name)
+		allocationStatus, operationalStatus := s.GetSliverStatus(
+			r.Context(),
+			sliver.Namespace,
+			sliver.Name,
+		)
 		reply.Data.Value = append(
 			reply.Data.Value,
 			NewSliver(sliver, allocationStatus, operationalStatus),
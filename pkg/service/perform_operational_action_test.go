@@ -1,8 +1,11 @@
 package service
 
 import (
+	"context"
 	"github.com/EdgeNet-project/fed4fire/pkg/constants"
 	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"testing"
 )
 
@@ -35,3 +38,28 @@ func TestPerformOperationalAction(t *testing.T) {
 	assert.Nil(t, err)
 	assert.Equal(t, constants.GeniCodeSuccess, reply.Data.Code.Code)
 }
+
+func TestPerformOperationalAction_Failed(t *testing.T) {
+	s := testService()
+	r := testRequest()
+	allocateTestSlice(s, r, testRspecServices)
+	sliver := listTestSlivers(s)[0]
+	pod := testPod(sliver.Name, "node", "1", "1Gi", true)
+	pod.Status.InitContainerStatuses = []corev1.ContainerStatus{{
+		State: corev1.ContainerState{
+			Terminated: &corev1.ContainerStateTerminated{ExitCode: 1},
+		},
+	}}
+	_, err := s.Pods(sliver.Namespace).Create(context.TODO(), pod, metav1.CreateOptions{})
+	assert.Nil(t, err)
+	args := &PerformOperationalActionArgs{
+		URNs:        []string{testSliceIdentifier.URN()},
+		Credentials: []Credential{testSliceCredential},
+		Action:      constants.GeniActionStart,
+	}
+	reply := &PerformOperationalActionReply{}
+	err = s.PerformOperationalAction(r, args, reply)
+	assert.Nil(t, err)
+	assert.Equal(t, constants.GeniCodeError, reply.Data.Code.Code)
+	assert.Contains(t, reply.Data.Output, "not allowed in state geni_failed")
+}
@@ -0,0 +1,142 @@
+package service
+
+import (
+	"fmt"
+	v1 "github.com/EdgeNet-project/fed4fire/pkg/apis/fed4fire/v1"
+	"github.com/EdgeNet-project/fed4fire/pkg/constants"
+	"github.com/EdgeNet-project/fed4fire/pkg/rspec"
+	corev1 "k8s.io/api/core/v1"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Mean radius of the Earth in km.
+const earthRadius = 6371.0
+
+// sliverPlacementForNode returns the placement of a request RSpec node, or nil if there is none.
+// The radius around coordinates is resolved to the names of the nodes within it.
+func sliverPlacementForNode(
+	s Service,
+	node rspec.Node,
+	nodes []corev1.Node,
+) (*v1.SliverPlacement, error) {
+	if node.Placement == nil {
+		return nil, nil
+	}
+	placement := &v1.SliverPlacement{}
+	if node.Placement.Country != "" {
+		for _, country := range strings.Split(node.Placement.Country, ",") {
+			country = strings.ToUpper(strings.TrimSpace(country))
+			if len(country) != 2 {
+				return nil, fmt.Errorf("country %s must be an ISO 3166-1 alpha-2 code", country)
+			}
+			placement.Countries = append(placement.Countries, country)
+		}
+	}
+	if node.Placement.Latitude != "" || node.Placement.Longitude != "" || node.Placement.Radius != "" {
+		latitude, err1 := strconv.ParseFloat(node.Placement.Latitude, 64)
+		longitude, err2 := strconv.ParseFloat(node.Placement.Longitude, 64)
+		radius, err3 := strconv.ParseFloat(node.Placement.Radius, 64)
+		if err1 != nil || err2 != nil || err3 != nil || math.Abs(latitude) > 90 || math.Abs(longitude) > 180 {
+			return nil, fmt.Errorf("latitude, longitude and radius must be given together, in degrees and km")
+		}
+		if radius <= 0 {
+			return nil, fmt.Errorf("radius must be positive")
+		}
+		placement.Nodes = make([]string, 0)
+		for _, node := range nodes {
+			location := rspecLocationForNode(node)
+			nodeLatitude, err1 := strconv.ParseFloat(location.Latitude, 64)
+			nodeLongitude, err2 := strconv.ParseFloat(location.Longitude, 64)
+			if err1 != nil || err2 != nil {
+				continue
+			}
+			if distance(latitude, longitude, nodeLatitude, nodeLongitude) <= radius {
+				placement.Nodes = append(placement.Nodes, node.Name)
+			}
+		}
+		sort.Strings(placement.Nodes)
+	}
+	for _, label := range node.Placement.Labels {
+		allowed := false
+		for _, key := range s.PlacementLabels {
+			allowed = allowed || label.Key == key
+		}
+		if !allowed {
+			return nil, fmt.Errorf("placement label %s is not allowed", label.Key)
+		}
+		if placement.Labels == nil {
+			placement.Labels = make(map[string]string)
+		}
+		placement.Labels[label.Key] = label.Value
+	}
+	return placement, nil
+}
+
+// placementMatches returns true if a node satisfies a placement.
+func placementMatches(placement v1.SliverPlacement, node corev1.Node) bool {
+	for _, requirement := range placementNodeSelectorRequirements(placement) {
+		value, ok := node.Labels[requirement.Key]
+		if requirement.Key == corev1.LabelHostname {
+			// The hostname label of a node is its name.
+			value, ok = node.Name, true
+		}
+		if !ok || !containsString(requirement.Values, value) {
+			return false
+		}
+	}
+	return true
+}
+
+// placementNodeSelectorRequirements returns the node affinity of a sliver placement.
+func placementNodeSelectorRequirements(placement v1.SliverPlacement) []corev1.NodeSelectorRequirement {
+	requirements := make([]corev1.NodeSelectorRequirement, 0)
+	if len(placement.Countries) > 0 {
+		requirements = append(requirements, corev1.NodeSelectorRequirement{
+			Key:      constants.EdgeNetLabelCountryISO,
+			Operator: corev1.NodeSelectorOpIn,
+			Values:   placement.Countries,
+		})
+	}
+	if placement.Nodes != nil {
+		requirements = append(requirements, corev1.NodeSelectorRequirement{
+			Key:      corev1.LabelHostname,
+			Operator: corev1.NodeSelectorOpIn,
+			Values:   placement.Nodes,
+		})
+	}
+	keys := make([]string, 0, len(placement.Labels))
+	for key := range placement.Labels {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		requirements = append(requirements, corev1.NodeSelectorRequirement{
+			Key:      key,
+			Operator: corev1.NodeSelectorOpIn,
+			Values:   []string{placement.Labels[key]},
+		})
+	}
+	return requirements
+}
+
+// distance returns the great-circle distance in km between two points, with the haversine formula.
+func distance(latitude1, longitude1, latitude2, longitude2 float64) float64 {
+	radians := math.Pi / 180
+	dLatitude := (latitude2 - latitude1) * radians
+	dLongitude := (longitude2 - longitude1) * radians
+	a := math.Pow(math.Sin(dLatitude/2), 2) +
+		math.Cos(latitude1*radians)*math.Cos(latitude2*radians)*math.Pow(math.Sin(dLongitude/2), 2)
+	return 2 * earthRadius * math.Asin(math.Sqrt(a))
+}
+
+func containsString(values []string, value string) bool {
+	for _, value_ := range values {
+		if value_ == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,147 @@
+package service
+
+import (
+	"fmt"
+	v1 "github.com/EdgeNet-project/fed4fire/pkg/apis/fed4fire/v1"
+	"github.com/EdgeNet-project/fed4fire/pkg/constants"
+	"github.com/EdgeNet-project/fed4fire/pkg/rspec"
+	corev1 "k8s.io/api/core/v1"
+	"strconv"
+	"strings"
+)
+
+// Name of the SSH port in the services of the slivers.
+const sshPortName = "ssh"
+
+// sliverPortsForNode returns the ports of a request RSpec node to expose outside of the cluster.
+// Port 22 is already exposed for SSH, unless the image is a custom one.
+func sliverPortsForNode(node rspec.Node, sliverType string) ([]v1.SliverPort, error) {
+	ports := make([]v1.SliverPort, 0)
+	seen := make(map[string]bool)
+	for _, port := range node.Ports {
+		number, err := strconv.Atoi(port.Number)
+		if err != nil || number < 1 || number > 65535 {
+			return nil, fmt.Errorf("port number must be between 1 and 65535, not %s", port.Number)
+		}
+		var protocol corev1.Protocol
+		switch strings.ToLower(port.Protocol) {
+		case "", rspec.RspecPortProtocolTCP:
+			protocol = corev1.ProtocolTCP
+		case rspec.RspecPortProtocolUDP:
+			protocol = corev1.ProtocolUDP
+		default:
+			return nil, fmt.Errorf(
+				"port protocol must be %s or %s",
+				rspec.RspecPortProtocolTCP,
+				rspec.RspecPortProtocolUDP,
+			)
+		}
+		if port.Ingress && protocol != corev1.ProtocolTCP {
+			return nil, fmt.Errorf("port %d/%s cannot be served by an ingress", number, protocol)
+		}
+		sliverPort := v1.SliverPort{Port: int32(number), Protocol: string(protocol), Ingress: port.Ingress}
+		if number == 22 && protocol == corev1.ProtocolTCP && sliverType != constants.SliverTypeCustomContainer {
+			return nil, fmt.Errorf("port 22 is already exposed for SSH")
+		}
+		key := fmt.Sprintf("%d/%s", number, protocol)
+		if seen[key] {
+			return nil, fmt.Errorf("port %s is duplicated", key)
+		}
+		seen[key] = true
+		ports = append(ports, sliverPort)
+	}
+	return ports, nil
+}
+
+// servicePortName returns the name of the service port of a sliver port, e.g. tcp-8080.
+func servicePortName(port v1.SliverPort) string {
+	return fmt.Sprintf("%s-%d", strings.ToLower(port.Protocol), port.Port)
+}
+
+// servicePortsForSliver returns the ports of the service of a sliver: SSH, if the image runs sshd, and the requested ones.
+func servicePortsForSliver(sliver v1.Sliver, ssh bool) []corev1.ServicePort {
+	ports := make([]corev1.ServicePort, 0)
+	if ssh {
+		ports = append(ports, corev1.ServicePort{Name: sshPortName, Port: 22})
+	}
+	for _, port := range sliver.Spec.Ports {
+		ports = append(ports, corev1.ServicePort{
+			Name:     servicePortName(port),
+			Protocol: corev1.Protocol(port.Protocol),
+			Port:     port.Port,
+		})
+	}
+	return ports
+}
+
+// containerPortsForSliver returns the ports of the container of a sliver exposed outside of the cluster.
+func containerPortsForSliver(sliver v1.Sliver) []corev1.ContainerPort {
+	var ports []corev1.ContainerPort
+	for _, port := range sliver.Spec.Ports {
+		ports = append(ports, corev1.ContainerPort{
+			Name:          servicePortName(port),
+			ContainerPort: port.Port,
+			Protocol:      corev1.Protocol(port.Protocol),
+		})
+	}
+	return ports
+}
+
+// sshServicePort returns the SSH port of the service of a sliver, or nil if there is none.
+// The services created by earlier versions of the AM only have an unnamed SSH port.
+func sshServicePort(service corev1.Service) *corev1.ServicePort {
+	for i, port := range service.Spec.Ports {
+		if port.Name == sshPortName || (port.Name == "" && port.Port == 22) {
+			return &service.Spec.Ports[i]
+		}
+	}
+	return nil
+}
+
+// rspecPortsForSliver returns the ports of a sliver, as reported in the manifests.
+// They are reachable at the address of the load balancer, or else at the node address and the node port.
+// The host and the external port are only known once the service exists and the sliver is scheduled.
+// The ports served by an ingress are also reachable at their URL.
+func rspecPortsForSliver(s Service, sliver v1.Sliver, service *corev1.Service, nodeAddress string) []rspec.Port {
+	ports := make([]rspec.Port, 0)
+	for _, port := range sliver.Spec.Ports {
+		rspecPort := rspec.Port{
+			Number:   strconv.Itoa(int(port.Port)),
+			Protocol: strings.ToLower(port.Protocol),
+			Ingress:  port.Ingress,
+		}
+		if port.Ingress {
+			rspecPort.URL = ingressURL(s, sliver, port)
+		}
+		if service != nil {
+			for _, servicePort := range service.Spec.Ports {
+				if servicePort.Name != servicePortName(port) {
+					continue
+				}
+				ingress := service.Status.LoadBalancer.Ingress
+				if service.Spec.Type == corev1.ServiceTypeLoadBalancer && len(ingress) > 0 {
+					rspecPort.Host = ingress[0].IP
+					if rspecPort.Host == "" {
+						rspecPort.Host = ingress[0].Hostname
+					}
+					rspecPort.ExternalPort = int(servicePort.Port)
+				} else if nodeAddress != "" && servicePort.NodePort != 0 {
+					rspecPort.Host = nodeAddress
+					rspecPort.ExternalPort = int(servicePort.NodePort)
+				}
+			}
+		}
+		ports = append(ports, rspecPort)
+	}
+	return ports
+}
+
+// nodeInternalIP returns the internal IP address of a node, or an empty string if it has none.
+func nodeInternalIP(node corev1.Node) string {
+	for _, address := range node.Status.Addresses {
+		if address.Type == corev1.NodeInternalIP {
+			return address.Address
+		}
+	}
+	return ""
+}
@@ -6,12 +6,14 @@ import (
 	v1 "github.com/EdgeNet-project/fed4fire/pkg/apis/fed4fire/v1"
 	"github.com/EdgeNet-project/fed4fire/pkg/constants"
 	"github.com/EdgeNet-project/fed4fire/pkg/naming"
-	"github.com/EdgeNet-project/fed4fire/pkg/rspec"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/klog/v2"
 	"k8s.io/utils/pointer"
 	"net/http"
@@ -53,20 +55,17 @@ func (s *Service) Provision(r *http.Request, args *ProvisionArgs, reply *Provisi
 		return reply.SetAndLogError(err, constants.ErrorListResources)
 	}
 
+	users := make([]string, 0)
 	sshKeys := make([]string, 0)
 	for _, user := range args.Options.Users {
+		users = append(users, user.URN)
 		sshKeys = append(sshKeys, user.Keys...)
 	}
 
 	// Build the sliver resources
 	resources := make([]*sliverResources, len(slivers))
 	for i, sliver := range slivers {
-		resources[i], err = buildResources(
-			sliver,
-			sshKeys,
-			s.ContainerCpuLimit,
-			s.ContainerMemoryLimit,
-		)
+		resources[i], err = buildResources(*s, sliver, sshKeys)
 		if err != nil {
 			return reply.SetAndLogError(err, constants.ErrorBuildResources)
 		}
@@ -91,35 +90,40 @@ func (s *Service) Provision(r *http.Request, args *ProvisionArgs, reply *Provisi
 		return reply.SetAndLogError(createResourcesError, constants.ErrorCreateResource)
 	}
 
-	returnRspec := rspec.Rspec{Type: rspec.RspecTypeManifest}
-
+	provisioned := make([]v1.Sliver, 0)
 	for _, sliver := range slivers {
-		sliver, err := s.Slivers().Get(r.Context(), sliver.Name, metav1.GetOptions{})
+		sliver, err := s.Slivers(sliver.Namespace).
+			Get(r.Context(), sliver.Name, metav1.GetOptions{})
 		if err != nil {
 			return reply.SetAndLogError(err, constants.ErrorGetResource)
 		}
+		sliver.Spec.Users = users
 		// Update the sliver expiration time if geni_end_time is specified.
 		expirationTime, err := time.Parse(time.RFC3339, args.Options.EndTime)
 		if err == nil {
 			sliver.Spec.Expires.Time = expirationTime
-			sliver, err = s.Slivers().Update(r.Context(), sliver, metav1.UpdateOptions{})
 		}
-		allocationStatus, operationalStatus := s.GetSliverStatus(r.Context(), sliver.Name)
+		sliver, err = s.Slivers(sliver.Namespace).
+			Update(r.Context(), sliver, metav1.UpdateOptions{})
+		if err != nil {
+			return reply.SetAndLogError(err, constants.ErrorUpdateResource)
+		}
+		allocationStatus, operationalStatus := s.GetSliverStatus(
+			r.Context(),
+			sliver.Namespace,
+			sliver.Name,
+		)
 		reply.Data.Value.Slivers = append(
 			reply.Data.Value.Slivers,
 			NewSliver(*sliver, allocationStatus, operationalStatus),
 		)
-		returnRspec.Nodes = append(returnRspec.Nodes, rspec.Node{
-			ComponentManagerID: s.AuthorityIdentifier.URN(),
-			Available:          &rspec.Available{Now: false},
-			ClientID:           sliver.Spec.ClientID,
-			Exclusive:          false,
-			SliverType: rspec.SliverType{
-				Name: "container",
-			},
-		})
+		provisioned = append(provisioned, *sliver)
 	}
 
+	returnRspec, err := s.manifestRspec(r.Context(), provisioned)
+	if err != nil {
+		return reply.SetAndLogError(err, constants.ErrorDeserializeRspec)
+	}
 	xml_, err := MarshalRspec(returnRspec, args.Options.Compressed)
 	if err != nil {
 		return reply.SetAndLogError(err, constants.ErrorSerializeRspec)
@@ -130,21 +134,28 @@ func (s *Service) Provision(r *http.Request, args *ProvisionArgs, reply *Provisi
 }
 
 type sliverResources struct {
-	ConfigMap  *corev1.ConfigMap
-	Deployment *appsv1.Deployment
-	Service    *corev1.Service
+	ConfigMap     *corev1.ConfigMap
+	Deployment    *appsv1.Deployment
+	Service       *corev1.Service
+	NetworkPolicy *networkingv1.NetworkPolicy
+	LinkNetworks  []*unstructured.Unstructured
+	Ingresses     []*networkingv1.Ingress
+	SliceService  *corev1.Service
+	VolumeClaims  []*corev1.PersistentVolumeClaim
 }
 
-func buildResources(
-	sliver v1.Sliver,
-	sshKeys []string,
-	cpuLimit string,
-	memoryLimit string,
-) (*sliverResources, error) {
+func buildResources(s Service, sliver v1.Sliver, sshKeys []string) (*sliverResources, error) {
+	profile := s.SecurityProfile(sliver.Spec.SliverType)
+
 	labels := map[string]string{
 		constants.Fed4FireSliceHash:  naming.SliceHash(sliver.Spec.SliceURN),
 		constants.Fed4FireSliverName: sliver.Name,
 	}
+	objectMeta := metav1.ObjectMeta{
+		Name:      sliver.Name,
+		Namespace: sliver.Namespace,
+		Labels:    labels,
+	}
 
 	nodeSelectorRequirements := []corev1.NodeSelectorRequirement{{
 		Key:      corev1.LabelOSStable,
@@ -157,6 +168,12 @@ func buildResources(
 			Operator: corev1.NodeSelectorOpIn,
 			Values:   []string{*sliver.Spec.RequestedArch},
 		})
+	} else if len(sliver.Spec.Architectures) > 0 {
+		nodeSelectorRequirements = append(nodeSelectorRequirements, corev1.NodeSelectorRequirement{
+			Key:      corev1.LabelArchStable,
+			Operator: corev1.NodeSelectorOpIn,
+			Values:   sliver.Spec.Architectures,
+		})
 	}
 	if sliver.Spec.RequestedNode != nil {
 		nodeSelectorRequirements = append(nodeSelectorRequirements, corev1.NodeSelectorRequirement{
@@ -166,21 +183,149 @@ func buildResources(
 		})
 	}
 
+	if sliver.Spec.Placement != nil {
+		nodeSelectorRequirements = append(
+			nodeSelectorRequirements,
+			placementNodeSelectorRequirements(*sliver.Spec.Placement)...,
+		)
+	}
+
+	podAffinity, podAntiAffinity, topologySpreadConstraints := groupScheduling(sliver)
+	podLabels := groupLabels(sliver)
+	for key, value := range labels {
+		podLabels[key] = value
+	}
+	if sliver.Spec.Exclusive {
+		nodeSelectorRequirements = append(nodeSelectorRequirements, corev1.NodeSelectorRequirement{
+			Key:      s.ExclusiveNodeLabel,
+			Operator: corev1.NodeSelectorOpIn,
+			Values:   []string{"true"},
+		})
+		if podAntiAffinity == nil {
+			podAntiAffinity = &corev1.PodAntiAffinity{}
+		}
+		podAntiAffinity.RequiredDuringSchedulingIgnoredDuringExecution = append(
+			podAntiAffinity.RequiredDuringSchedulingIgnoredDuringExecution,
+			exclusiveAntiAffinityTerm(),
+		)
+		podLabels[constants.Fed4FireExclusive] = "true"
+	}
+
+	// The slivers of a slice reach each other by hostname, e.g. pc1, or pc1.<slice hash>.
+	var hostname, subdomain string
+	var dnsConfig *corev1.PodDNSConfig
+	var sliceService *corev1.Service
+	if s.ClusterDomain != "" {
+		hostname = naming.HostName(sliver.Spec.ClientID)
+		subdomain = naming.SliceHash(sliver.Spec.SliceURN)
+		dnsConfig = &corev1.PodDNSConfig{Searches: []string{sliceDomain(s, sliver)}}
+		sliceService = sliceServiceForSliver(sliver)
+	}
+
+	resourceRequirements, err := resourceRequirementsForSliver(s, sliver)
+	if err != nil {
+		return nil, err
+	}
+
+	// Custom images run their own entrypoint, and cannot be expected to run sshd.
+	ssh := sliver.Spec.SliverType != constants.SliverTypeCustomContainer
+
+	volumes := make([]corev1.Volume, 0)
+	volumeMounts := make([]corev1.VolumeMount, 0)
+	if ssh {
+		volumes = append(volumes, corev1.Volume{
+			Name: "ssh-volume",
+			VolumeSource: corev1.VolumeSource{
+				ConfigMap: &corev1.ConfigMapVolumeSource{
+					LocalObjectReference: corev1.LocalObjectReference{
+						Name: sliver.Name,
+					},
+				},
+			},
+		})
+		volumeMounts = append(volumeMounts, corev1.VolumeMount{
+			Name:      "ssh-volume",
+			ReadOnly:  true,
+			MountPath: "/root/.ssh/authorized_keys",
+			SubPath:   "authorized_keys",
+		})
+	}
+	volumeClaims, claimVolumes, claimVolumeMounts, err := volumeClaimsForSliver(s, sliver)
+	if err != nil {
+		return nil, err
+	}
+	volumes = append(volumes, claimVolumes...)
+	volumeMounts = append(volumeMounts, claimVolumeMounts...)
+	// With a read-only root filesystem, the experimenters still need a few writable directories,
+	// unless a persistent volume is mounted there.
+	if s.ReadOnlyRootFilesystem {
+		for _, dir := range []string{"/tmp", "/root"} {
+			if containsVolumeMount(claimVolumeMounts, dir) {
+				continue
+			}
+			name := strings.Trim(strings.ReplaceAll(dir, "/", "-"), "-") + "-volume"
+			volumes = append(volumes, corev1.Volume{
+				Name: name,
+				VolumeSource: corev1.VolumeSource{
+					EmptyDir: &corev1.EmptyDirVolumeSource{},
+				},
+			})
+			volumeMounts = append(volumeMounts, corev1.VolumeMount{
+				Name:      name,
+				MountPath: dir,
+			})
+		}
+	}
+
+	linkNetworks, networksAnnotation, err := buildLinkNetworks(s, sliver, labels)
+	if err != nil {
+		return nil, err
+	}
+	var podAnnotations map[string]string
+	if networksAnnotation != "" {
+		podAnnotations = map[string]string{multusNetworksAnnotation: networksAnnotation}
+	}
+	initContainers := make([]corev1.Container, 0)
+	installContainer, installVolumes, installMounts := buildInstallContainer(
+		s,
+		sliver,
+		profile.SecurityContext(s.ReadOnlyRootFilesystem),
+		volumeMounts,
+	)
+	if installContainer != nil {
+		initContainers = append(initContainers, *installContainer)
+		volumes = append(volumes, installVolumes...)
+		volumeMounts = append(volumeMounts, installMounts...)
+	}
+	linkShapingContainer, err := buildLinkShapingContainer(s, sliver)
+	if err != nil {
+		return nil, err
+	}
+	if linkShapingContainer != nil {
+		initContainers = append(initContainers, *linkShapingContainer)
+	}
+
+	// The sliver is only ready once the execute services are done and sshd listens.
+	command := buildStartupCommand(s, sliver)
+	var readinessProbe *corev1.Probe
+	if command != nil {
+		readinessProbe = &corev1.Probe{
+			Handler: corev1.Handler{
+				TCPSocket: &corev1.TCPSocketAction{Port: intstr.FromInt(22)},
+			},
+			PeriodSeconds: 5,
+		}
+	}
+
 	configMap := &corev1.ConfigMap{
-		ObjectMeta: metav1.ObjectMeta{
-			Name:   sliver.Name,
-			Labels: labels,
-		},
+		ObjectMeta: objectMeta,
 		Data: map[string]string{
 			"authorized_keys": strings.Join(sshKeys, "\n") + "\n",
 		},
 	}
 
 	deployment := &appsv1.Deployment{
-		ObjectMeta: metav1.ObjectMeta{
-			Name:   sliver.Name,
-			Labels: labels,
-		},
+		ObjectMeta: objectMeta,
 		Spec: appsv1.DeploymentSpec{
 			Replicas: pointer.Int32Ptr(1),
 			Selector: &metav1.LabelSelector{
@@ -190,9 +335,13 @@ func buildResources(
 			},
 			Template: corev1.PodTemplateSpec{
 				ObjectMeta: metav1.ObjectMeta{
-					Labels: labels,
+					Labels:      podLabels,
+					Annotations: podAnnotations,
 				},
 				Spec: corev1.PodSpec{
+					// Experimenters have no business with the Kubernetes API.
+					AutomountServiceAccountToken: pointer.BoolPtr(false),
+					SecurityContext:              profile.PodSecurityContext(),
 					Affinity: &corev1.Affinity{
 						NodeAffinity: &corev1.NodeAffinity{
 							RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{
@@ -201,73 +350,153 @@ func buildResources(
 								}},
 							},
 						},
+						PodAffinity:     podAffinity,
+						PodAntiAffinity: podAntiAffinity,
 					},
+					TopologySpreadConstraints: topologySpreadConstraints,
+					Hostname:                  hostname,
+					Subdomain:                 subdomain,
+					DNSConfig:                 dnsConfig,
+					InitContainers:            initContainers,
 					Containers: []corev1.Container{
 						{
-							Name:  sliver.Name,
-							Image: sliver.Spec.Image,
-							Resources: corev1.ResourceRequirements{
-								Limits: map[corev1.ResourceName]resource.Quantity{
-									corev1.ResourceCPU:    resource.MustParse(cpuLimit),
-									corev1.ResourceMemory: resource.MustParse(memoryLimit),
-								},
-								Requests: map[corev1.ResourceName]resource.Quantity{
-									corev1.ResourceCPU: resource.MustParse(
-										constants.DefaultCpuRequest,
-									),
-									corev1.ResourceMemory: resource.MustParse(
-										constants.DefaultMemoryRequest,
-									),
-								},
-							},
-							VolumeMounts: []corev1.VolumeMount{
-								{
-									Name:      "ssh-volume",
-									ReadOnly:  true,
-									MountPath: "/root/.ssh/authorized_keys",
-									SubPath:   "authorized_keys",
-								},
-							},
-						},
-					},
-					Volumes: []corev1.Volume{
-						{
-							Name: "ssh-volume",
-							VolumeSource: corev1.VolumeSource{
-								ConfigMap: &corev1.ConfigMapVolumeSource{
-									LocalObjectReference: corev1.LocalObjectReference{
-										Name: configMap.Name,
-									},
-								},
-							},
+							Name:            sliver.Name,
+							Image:           sliver.Spec.Image,
+							Command:         command,
+							Ports:           containerPortsForSliver(sliver),
+							ReadinessProbe:  readinessProbe,
+							Resources:       *resourceRequirements,
+							SecurityContext: profile.SecurityContext(s.ReadOnlyRootFilesystem),
+							VolumeMounts:    volumeMounts,
 						},
 					},
+					Volumes: volumes,
 				},
 			},
 		},
 	}
 
-	service := &corev1.Service{
-		ObjectMeta: metav1.ObjectMeta{
-			Name:   sliver.Name,
-			Labels: labels,
-		},
-		Spec: corev1.ServiceSpec{
-			Type: corev1.ServiceTypeNodePort,
-			Ports: []corev1.ServicePort{
-				{Port: 22},
+	var service *corev1.Service
+	if ssh || len(sliver.Spec.Ports) > 0 {
+		serviceType := corev1.ServiceTypeNodePort
+		if len(sliver.Spec.Ports) > 0 && s.PortServiceType == string(corev1.ServiceTypeLoadBalancer) {
+			serviceType = corev1.ServiceTypeLoadBalancer
+		}
+		service = &corev1.Service{
+			ObjectMeta: objectMeta,
+			Spec: corev1.ServiceSpec{
+				Type:  serviceType,
+				Ports: servicePortsForSliver(sliver, ssh),
+				Selector: map[string]string{
+					constants.Fed4FireSliverName: sliver.Name,
+				},
+			},
+		}
+	}
+
+	// Only accept traffic from the pods of the same slice, and SSH and exposed ports traffic from anywhere.
+	var networkPolicy *networkingv1.NetworkPolicy
+	if s.NetworkIsolation {
+		ingress := []networkingv1.NetworkPolicyIngressRule{
+			{
+				From: []networkingv1.NetworkPolicyPeer{{
+					PodSelector: &metav1.LabelSelector{
+						MatchLabels: map[string]string{
+							constants.Fed4FireSliceHash: labels[constants.Fed4FireSliceHash],
+						},
+					},
+				}},
 			},
-			Selector: map[string]string{
-				constants.Fed4FireSliverName: sliver.Name,
+		}
+		if ssh {
+			tcp := corev1.ProtocolTCP
+			sshPort := intstr.FromInt(22)
+			ingress = append(ingress, networkingv1.NetworkPolicyIngressRule{
+				Ports: []networkingv1.NetworkPolicyPort{{
+					Protocol: &tcp,
+					Port:     &sshPort,
+				}},
+			})
+		}
+		if len(sliver.Spec.Ports) > 0 {
+			ports := make([]networkingv1.NetworkPolicyPort, 0)
+			for _, port := range sliver.Spec.Ports {
+				protocol := corev1.Protocol(port.Protocol)
+				number := intstr.FromInt(int(port.Port))
+				ports = append(ports, networkingv1.NetworkPolicyPort{Protocol: &protocol, Port: &number})
+			}
+			ingress = append(ingress, networkingv1.NetworkPolicyIngressRule{Ports: ports})
+		}
+		networkPolicy = &networkingv1.NetworkPolicy{
+			ObjectMeta: objectMeta,
+			Spec: networkingv1.NetworkPolicySpec{
+				PodSelector: metav1.LabelSelector{
+					MatchLabels: map[string]string{
+						constants.Fed4FireSliverName: sliver.Name,
+					},
+				},
+				PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeIngress},
+				Ingress:     ingress,
 			},
-		},
+		}
 	}
 
-	return &sliverResources{configMap, deployment, service}, nil
+	ingresses := ingressesForSliver(s, sliver, labels)
+
+	return &sliverResources{
+		configMap,
+		deployment,
+		service,
+		networkPolicy,
+		linkNetworks,
+		ingresses,
+		sliceService,
+		volumeClaims,
+	}, nil
+}
+
+// resourceRequirementsForSliver returns the resources requested for a sliver container,
+// or the default ones if none were requested.
+// The limits are raised to the requests when the latter are larger.
+func resourceRequirementsForSliver(
+	s Service,
+	sliver v1.Sliver,
+) (*corev1.ResourceRequirements, error) {
+	cpuRequest := constants.DefaultCpuRequest
+	if sliver.Spec.CpuRequest != nil {
+		cpuRequest = *sliver.Spec.CpuRequest
+	}
+	memoryRequest := constants.DefaultMemoryRequest
+	if sliver.Spec.MemoryRequest != nil {
+		memoryRequest = *sliver.Spec.MemoryRequest
+	}
+	requests := make(corev1.ResourceList)
+	limits := make(corev1.ResourceList)
+	for _, r := range []struct {
+		name    corev1.ResourceName
+		request string
+		limit   resource.Quantity
+	}{
+		{corev1.ResourceCPU, cpuRequest, s.ContainerCpuLimit},
+		{corev1.ResourceMemory, memoryRequest, s.ContainerMemoryLimit},
+	} {
+		request, err := resource.ParseQuantity(r.request)
+		if err != nil {
+			return nil, err
+		}
+		limit := r.limit
+		if request.Cmp(limit) > 0 {
+			limit = request
+		}
+		requests[r.name] = request
+		limits[r.name] = limit
+	}
+	return &corev1.ResourceRequirements{Limits: limits, Requests: requests}, nil
 }
 
 func createResources(context context.Context, service Service, resources sliverResources) error {
-	sliver, err := service.Slivers().Get(context, resources.Deployment.Name, metav1.GetOptions{})
+	sliver, err := service.Slivers(resources.Deployment.Namespace).
+		Get(context, resources.Deployment.Name, metav1.GetOptions{})
 	if err != nil {
 		return err
 	}
@@ -281,7 +510,21 @@ func createResources(context context.Context, service Service, resources sliverR
 		resources.Deployment.OwnerReferences,
 		ownerReference,
 	)
-	_, err = service.Deployments().Create(context, resources.Deployment, metav1.CreateOptions{})
+	// The claims and the networks must exist before the pod is created.
+	for _, claim := range resources.VolumeClaims {
+		err = createVolumeClaim(context, service, claim, ownerReference)
+		if err != nil {
+			return err
+		}
+	}
+	for _, network := range resources.LinkNetworks {
+		err = createLinkNetwork(context, service, network, ownerReference)
+		if err != nil {
+			return err
+		}
+	}
+	_, err = service.Deployments(resources.Deployment.Namespace).
+		Create(context, resources.Deployment, metav1.CreateOptions{})
 	if err != nil && !errors.IsAlreadyExists(err) {
 		return err
 	}
@@ -289,28 +532,80 @@ func createResources(context context.Context, service Service, resources sliverR
 		resources.ConfigMap.OwnerReferences,
 		ownerReference,
 	)
-	_, err = service.ConfigMaps().Create(context, resources.ConfigMap, metav1.CreateOptions{})
+	_, err = service.ConfigMaps(resources.ConfigMap.Namespace).
+		Create(context, resources.ConfigMap, metav1.CreateOptions{})
 	if err != nil && !errors.IsAlreadyExists(err) {
 		return err
 	}
-	resources.Service.OwnerReferences = append(resources.Service.OwnerReferences, ownerReference)
-	_, err = service.Services().Create(context, resources.Service, metav1.CreateOptions{})
-	if err != nil && !errors.IsAlreadyExists(err) {
-		return err
+	if resources.Service != nil {
+		resources.Service.OwnerReferences = append(
+			resources.Service.OwnerReferences,
+			ownerReference,
+		)
+		_, err = service.Services(resources.Service.Namespace).
+			Create(context, resources.Service, metav1.CreateOptions{})
+		if err != nil && !errors.IsAlreadyExists(err) {
+			return err
+		}
+	}
+	if resources.NetworkPolicy != nil {
+		resources.NetworkPolicy.OwnerReferences = append(
+			resources.NetworkPolicy.OwnerReferences,
+			ownerReference,
+		)
+		_, err = service.NetworkPolicies(resources.NetworkPolicy.Namespace).
+			Create(context, resources.NetworkPolicy, metav1.CreateOptions{})
+		if err != nil && !errors.IsAlreadyExists(err) {
+			return err
+		}
+	}
+	if resources.SliceService != nil {
+		err = createSliceService(context, service, resources.SliceService, ownerReference)
+		if err != nil {
+			return err
+		}
+	}
+	for _, ingress := range resources.Ingresses {
+		ingress.OwnerReferences = append(ingress.OwnerReferences, ownerReference)
+		_, err = service.Ingresses(ingress.Namespace).Create(context, ingress, metav1.CreateOptions{})
+		if err != nil && !errors.IsAlreadyExists(err) {
+			return err
+		}
 	}
 	return nil
 }
 
+// deleteResources deletes the resources of a sliver.
+// The link networks, the service of the slice and the volumes are shared between slivers,
+// and are deleted with the last sliver owning them, or when they expire for the retained volumes.
 func deleteResources(context context.Context, service Service, resources sliverResources) error {
-	err := service.Services().Delete(context, resources.Service.Name, metav1.DeleteOptions{})
-	if err != nil && !errors.IsNotFound(err) {
-		return err
+	for _, ingress := range resources.Ingresses {
+		err := service.Ingresses(ingress.Namespace).Delete(context, ingress.Name, metav1.DeleteOptions{})
+		if err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+	}
+	if resources.NetworkPolicy != nil {
+		err := service.NetworkPolicies(resources.NetworkPolicy.Namespace).
+			Delete(context, resources.NetworkPolicy.Name, metav1.DeleteOptions{})
+		if err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+	}
+	if resources.Service != nil {
+		err := service.Services(resources.Service.Namespace).
+			Delete(context, resources.Service.Name, metav1.DeleteOptions{})
+		if err != nil && !errors.IsNotFound(err) {
+			return err
+		}
 	}
-	err = service.ConfigMaps().Delete(context, resources.ConfigMap.Name, metav1.DeleteOptions{})
+	err := service.ConfigMaps(resources.ConfigMap.Namespace).
+		Delete(context, resources.ConfigMap.Name, metav1.DeleteOptions{})
 	if err != nil && !errors.IsNotFound(err) {
 		return err
 	}
-	err = service.Deployments().Delete(context, resources.Deployment.Name, metav1.DeleteOptions{})
+	err = service.Deployments(resources.Deployment.Namespace).
+		Delete(context, resources.Deployment.Name, metav1.DeleteOptions{})
 	if err != nil && !errors.IsNotFound(err) {
 		return err
 	}
@@ -1,9 +1,19 @@
 package service
 
 import (
+	"context"
+	"fmt"
 	"github.com/EdgeNet-project/fed4fire/pkg/constants"
+	"github.com/EdgeNet-project/fed4fire/pkg/naming"
+	"github.com/EdgeNet-project/fed4fire/pkg/security"
 	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestProvision(t *testing.T) {
@@ -21,3 +31,617 @@ func TestProvision(t *testing.T) {
 	deployments := listTestDeployments(s)
 	assert.Len(t, deployments, 2)
 }
+
+func TestProvision_NetworkIsolation(t *testing.T) {
+	s := testService()
+	r := testRequest()
+	allocateTestSlice(s, r, testRspecMany)
+	provisionTestSlice(s, r)
+	policies, err := s.NetworkPolicies(metav1.NamespaceAll).
+		List(context.TODO(), metav1.ListOptions{})
+	assert.Nil(t, err)
+	assert.Len(t, policies.Items, 2)
+	for _, policy := range policies.Items {
+		assert.Equal(
+			t,
+			naming.SliceHash(testSliceIdentifier.URN()),
+			policy.Spec.Ingress[0].From[0].PodSelector.MatchLabels[constants.Fed4FireSliceHash],
+		)
+		assert.Equal(t, 22, policy.Spec.Ingress[1].Ports[0].Port.IntValue())
+		assert.Len(t, policy.OwnerReferences, 1)
+	}
+}
+
+func TestProvision_NoNetworkIsolation(t *testing.T) {
+	s := testService()
+	s.NetworkIsolation = false
+	r := testRequest()
+	allocateTestSlice(s, r, testRspecMany)
+	provisionTestSlice(s, r)
+	policies, err := s.NetworkPolicies(metav1.NamespaceAll).
+		List(context.TODO(), metav1.ListOptions{})
+	assert.Nil(t, err)
+	assert.Len(t, policies.Items, 0)
+}
+
+func TestProvision_SecurityProfile(t *testing.T) {
+	s := testService()
+	r := testRequest()
+	allocateTestSlice(s, r, testRspecMany)
+	provisionTestSlice(s, r)
+	for _, deployment := range listTestDeployments(s) {
+		spec := deployment.Spec.Template.Spec
+		assert.False(t, *spec.AutomountServiceAccountToken)
+		assert.Equal(
+			t,
+			corev1.SeccompProfileTypeRuntimeDefault,
+			spec.SecurityContext.SeccompProfile.Type,
+		)
+		container := spec.Containers[0]
+		assert.False(t, *container.SecurityContext.AllowPrivilegeEscalation)
+		assert.Equal(t, []corev1.Capability{"ALL"}, container.SecurityContext.Capabilities.Drop)
+		assert.Nil(t, container.SecurityContext.ReadOnlyRootFilesystem)
+		assert.Equal(t, "/root/.ssh/authorized_keys", container.VolumeMounts[0].MountPath)
+	}
+}
+
+func TestProvision_SecurityProfileRestricted(t *testing.T) {
+	s := testService()
+	s.ImagePolicy.Allowlist = []string{"docker.io/library/*"}
+	s.ReadOnlyRootFilesystem = true
+	// The restricted profile is only allowed for the custom images, which do not run sshd.
+	s.SecurityProfiles = map[string]security.Profile{
+		constants.SliverTypeCustomContainer: security.ProfileRestricted,
+	}
+	r := testRequest()
+	allocateTestSlice(s, r, strings.Replace(
+		testRspecDiskImage,
+		`name="ubuntu2004"`,
+		`name="docker.io/library/nginx:1.21"`,
+		1,
+	))
+	provisionTestSlice(s, r)
+	deployments := listTestDeployments(s)
+	assert.Len(t, deployments, 1)
+	spec := deployments[0].Spec.Template.Spec
+	assert.False(t, *spec.AutomountServiceAccountToken)
+	assert.True(t, *spec.SecurityContext.RunAsNonRoot)
+	assert.NotEqual(t, int64(0), *spec.SecurityContext.RunAsUser)
+	assert.Equal(
+		t,
+		corev1.SeccompProfileTypeRuntimeDefault,
+		spec.SecurityContext.SeccompProfile.Type,
+	)
+	container := spec.Containers[0]
+	assert.False(t, *container.SecurityContext.AllowPrivilegeEscalation)
+	assert.Equal(t, []corev1.Capability{"ALL"}, container.SecurityContext.Capabilities.Drop)
+	assert.Empty(t, container.SecurityContext.Capabilities.Add)
+	assert.True(t, *container.SecurityContext.ReadOnlyRootFilesystem)
+	// Only /tmp and /root are writable.
+	assert.Len(t, container.VolumeMounts, 2)
+	assert.Len(t, spec.Volumes, 2)
+}
+
+func TestProvision_Resources(t *testing.T) {
+	s := testService()
+	r := testRequest()
+	allocateTestSlice(s, r, testRspecResources)
+	provisionTestSlice(s, r)
+	deployments := listTestDeployments(s)
+	assert.Len(t, deployments, 1)
+	resources := deployments[0].Spec.Template.Spec.Containers[0].Resources
+	assert.Equal(t, "3", resources.Requests.Cpu().String())
+	assert.Equal(t, "4Gi", resources.Requests.Memory().String())
+	// The limits must be raised to the requests.
+	assert.Equal(t, "3", resources.Limits.Cpu().String())
+	assert.Equal(t, "4Gi", resources.Limits.Memory().String())
+}
+
+func TestProvision_DiskImageArchitectures(t *testing.T) {
+	s := testService()
+	r := testRequest()
+	image := testContainerImage("ubuntu2004", "docker.io/library/ubuntu:20.04", []string{"amd64", "arm64"}, true)
+	s.ContainerImageCatalog().Create(context.TODO(), image, metav1.CreateOptions{})
+	allocateTestSlice(s, r, testRspecSingle)
+	provisionTestSlice(s, r)
+	deployments := listTestDeployments(s)
+	assert.Len(t, deployments, 1)
+	affinity := deployments[0].Spec.Template.Spec.Affinity.NodeAffinity
+	requirements := affinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms[0].MatchExpressions
+	assert.Contains(t, requirements, corev1.NodeSelectorRequirement{
+		Key:      corev1.LabelArchStable,
+		Operator: corev1.NodeSelectorOpIn,
+		Values:   []string{"amd64", "arm64"},
+	})
+}
+
+func TestProvision_Groups(t *testing.T) {
+	s := testService()
+	r := testRequest()
+	createTestPlacementNodes(s)
+	allocateTestSlice(s, r, testRspecGroups)
+	provisionTestSlice(s, r)
+	specs := make(map[string]corev1.PodTemplateSpec)
+	for _, deployment := range listTestDeployments(s) {
+		specs[deployment.Labels[constants.Fed4FireSliverName]] = deployment.Spec.Template
+	}
+	pc1 := specs[naming.SliverName(testSliceIdentifier.URN(), "PC1")]
+	pc2 := specs[naming.SliverName(testSliceIdentifier.URN(), "PC2")]
+	pc3 := specs[naming.SliverName(testSliceIdentifier.URN(), "PC3")]
+
+	// PC1 and PC2 must be in distinct countries.
+	assert.Nil(t, pc1.Spec.Affinity.PodAffinity)
+	terms := pc1.Spec.Affinity.PodAntiAffinity.RequiredDuringSchedulingIgnoredDuringExecution
+	assert.Len(t, terms, 1)
+	assert.Equal(t, constants.EdgeNetLabelCountryISO, terms[0].TopologyKey)
+	assert.Equal(t, terms, pc2.Spec.Affinity.PodAntiAffinity.RequiredDuringSchedulingIgnoredDuringExecution)
+	selector, err := metav1.LabelSelectorAsSelector(terms[0].LabelSelector)
+	assert.Nil(t, err)
+	assert.True(t, selector.Matches(labels.Set(pc1.Labels)))
+	assert.True(t, selector.Matches(labels.Set(pc2.Labels)))
+	assert.False(t, selector.Matches(labels.Set(pc3.Labels)))
+
+	// PC2 and PC3 must be on the same node.
+	assert.Nil(t, pc3.Spec.Affinity.PodAntiAffinity)
+	terms = pc3.Spec.Affinity.PodAffinity.RequiredDuringSchedulingIgnoredDuringExecution
+	assert.Len(t, terms, 1)
+	assert.Equal(t, corev1.LabelHostname, terms[0].TopologyKey)
+	assert.Equal(t, terms, pc2.Spec.Affinity.PodAffinity.RequiredDuringSchedulingIgnoredDuringExecution)
+	selector, err = metav1.LabelSelectorAsSelector(terms[0].LabelSelector)
+	assert.Nil(t, err)
+	assert.False(t, selector.Matches(labels.Set(pc1.Labels)))
+	assert.True(t, selector.Matches(labels.Set(pc2.Labels)))
+	assert.True(t, selector.Matches(labels.Set(pc3.Labels)))
+}
+
+func TestProvision_GroupsBalance(t *testing.T) {
+	s := testService()
+	r := testRequest()
+	createTestPlacementNodes(s)
+	allocateTestSlice(s, r, strings.Replace(testRspecGroups, `policy="spread"`, `policy="balance"`, 1))
+	provisionTestSlice(s, r)
+	for _, deployment := range listTestDeployments(s) {
+		spec := deployment.Spec.Template.Spec
+		if deployment.Labels[constants.Fed4FireSliverName] != naming.SliverName(testSliceIdentifier.URN(), "PC1") {
+			continue
+		}
+		assert.Nil(t, spec.Affinity.PodAntiAffinity)
+		assert.Len(t, spec.TopologySpreadConstraints, 1)
+		assert.Equal(t, constants.EdgeNetLabelCountryISO, spec.TopologySpreadConstraints[0].TopologyKey)
+		assert.Equal(t, corev1.DoNotSchedule, spec.TopologySpreadConstraints[0].WhenUnsatisfiable)
+	}
+}
+
+func TestProvision_Exclusive(t *testing.T) {
+	s := testService()
+	r := testRequest()
+	createTestExclusiveNodes(s)
+	allocateTestSlice(s, r, strings.Replace(testRspecSingle, `exclusive="false"`, `exclusive="true"`, 1))
+	provisionTestSlice(s, r)
+	deployments := listTestDeployments(s)
+	assert.Len(t, deployments, 1)
+	template := deployments[0].Spec.Template
+	assert.Equal(t, "true", template.Labels[constants.Fed4FireExclusive])
+	requirements := template.Spec.Affinity.NodeAffinity.
+		RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms[0].MatchExpressions
+	assert.Contains(t, requirements, corev1.NodeSelectorRequirement{
+		Key:      "example.org/exclusive",
+		Operator: corev1.NodeSelectorOpIn,
+		Values:   []string{"true"},
+	})
+	// The sliver must repel the slivers of all the slices.
+	terms := template.Spec.Affinity.PodAntiAffinity.RequiredDuringSchedulingIgnoredDuringExecution
+	assert.Len(t, terms, 1)
+	assert.Equal(t, corev1.LabelHostname, terms[0].TopologyKey)
+	assert.Equal(t, &metav1.LabelSelector{}, terms[0].NamespaceSelector)
+	selector, err := metav1.LabelSelectorAsSelector(terms[0].LabelSelector)
+	assert.Nil(t, err)
+	assert.True(t, selector.Matches(labels.Set{constants.Fed4FireSliverName: "other"}))
+}
+
+func TestProvision_Ports(t *testing.T) {
+	s := testService()
+	r := testRequest()
+	allocateTestSlice(s, r, testRspecPorts)
+	provisionTestSlice(s, r)
+	// The service of the slice has no sliver name.
+	services, err := s.Services(metav1.NamespaceAll).
+		List(context.TODO(), metav1.ListOptions{LabelSelector: constants.Fed4FireSliverName})
+	assert.Nil(t, err)
+	assert.Len(t, services.Items, 1)
+	service := services.Items[0]
+	assert.Equal(t, corev1.ServiceTypeNodePort, service.Spec.Type)
+	assert.Equal(t, []corev1.ServicePort{
+		{Name: "ssh", Port: 22},
+		{Name: "tcp-8080", Protocol: corev1.ProtocolTCP, Port: 8080},
+		{Name: "udp-5353", Protocol: corev1.ProtocolUDP, Port: 5353},
+	}, service.Spec.Ports)
+	deployments := listTestDeployments(s)
+	assert.Len(t, deployments[0].Spec.Template.Spec.Containers[0].Ports, 2)
+	// The ports must be reachable from anywhere.
+	policies, err := s.NetworkPolicies(metav1.NamespaceAll).List(context.TODO(), metav1.ListOptions{})
+	assert.Nil(t, err)
+	ingress := policies.Items[0].Spec.Ingress
+	assert.Len(t, ingress, 3)
+	assert.Nil(t, ingress[2].From)
+	assert.Equal(t, 8080, ingress[2].Ports[0].Port.IntValue())
+	assert.Equal(t, corev1.ProtocolUDP, *ingress[2].Ports[1].Protocol)
+}
+
+func TestProvision_PortsLoadBalancer(t *testing.T) {
+	s := testService()
+	s.PortServiceType = "LoadBalancer"
+	s.ImagePolicy.Allowlist = []string{"docker.io/library/*"}
+	r := testRequest()
+	// Custom images do not run sshd, but their ports must be exposed.
+	allocateTestSlice(s, r, strings.Replace(
+		testRspecPorts,
+		`<sliver_type name="container"/>`,
+		`<sliver_type name="container"><disk_image name="docker.io/library/nginx:1.21"/></sliver_type>`,
+		1,
+	))
+	provisionTestSlice(s, r)
+	// The service of the slice has no sliver name.
+	services, err := s.Services(metav1.NamespaceAll).
+		List(context.TODO(), metav1.ListOptions{LabelSelector: constants.Fed4FireSliverName})
+	assert.Nil(t, err)
+	assert.Len(t, services.Items, 1)
+	service := services.Items[0]
+	assert.Equal(t, corev1.ServiceTypeLoadBalancer, service.Spec.Type)
+	assert.Len(t, service.Spec.Ports, 2)
+	assert.Equal(t, "tcp-8080", service.Spec.Ports[0].Name)
+	// The ports are reported at the address of the load balancer.
+	service.Status.LoadBalancer.Ingress = []corev1.LoadBalancerIngress{{IP: "198.51.100.1"}}
+	slivers := listTestSlivers(s)
+	ports := rspecPortsForSliver(*s, slivers[0], &service, "192.0.2.1")
+	assert.Equal(t, "198.51.100.1", ports[0].Host)
+	assert.Equal(t, 8080, ports[0].ExternalPort)
+}
+
+func TestProvision_Ingress(t *testing.T) {
+	s := testService()
+	r := testRequest()
+	allocateTestSlice(s, r, strings.Replace(
+		testRspecPorts,
+		`<edgenet:port number="8080"/>`,
+		`<edgenet:port number="8080" ingress="true"/>`,
+		1,
+	))
+	provisionTestSlice(s, r)
+	ingresses, err := s.Ingresses(metav1.NamespaceAll).List(context.TODO(), metav1.ListOptions{})
+	assert.Nil(t, err)
+	assert.Len(t, ingresses.Items, 1)
+	ingress := ingresses.Items[0]
+	sliverName := naming.SliverName(testSliceIdentifier.URN(), "PC1")
+	assert.Equal(t, sliverName+"-8080", ingress.Name)
+	assert.Equal(t, sliverName, ingress.OwnerReferences[0].Name)
+	rule := ingress.Spec.Rules[0]
+	assert.Equal(t, "pc1-8080-"+naming.SliceHash(testSliceIdentifier.URN())+".slivers.example.org", rule.Host)
+	backend := rule.HTTP.Paths[0].Backend.Service
+	assert.Equal(t, sliverName, backend.Name)
+	assert.Equal(t, "tcp-8080", backend.Port.Name)
+	assert.Nil(t, ingress.Spec.IngressClassName)
+	assert.Len(t, ingress.Spec.TLS, 0)
+}
+
+func TestProvision_IngressTLS(t *testing.T) {
+	rspec := strings.Replace(
+		testRspecPorts,
+		`<edgenet:port number="8080"/>`,
+		`<edgenet:port number="8080" ingress="true"/>`,
+		1,
+	)
+	s := testService()
+	s.IngressClass = "nginx"
+	s.IngressTLSSecret = "wildcard-tls"
+	r := testRequest()
+	allocateTestSlice(s, r, rspec)
+	provisionTestSlice(s, r)
+	ingresses, err := s.Ingresses(metav1.NamespaceAll).List(context.TODO(), metav1.ListOptions{})
+	assert.Nil(t, err)
+	ingress := ingresses.Items[0]
+	assert.Equal(t, "nginx", *ingress.Spec.IngressClassName)
+	assert.Equal(t, "wildcard-tls", ingress.Spec.TLS[0].SecretName)
+	assert.Equal(t, []string{ingress.Spec.Rules[0].Host}, ingress.Spec.TLS[0].Hosts)
+
+	s = testService()
+	s.IngressIssuer = "letsencrypt"
+	allocateTestSlice(s, r, rspec)
+	provisionTestSlice(s, r)
+	ingresses, err = s.Ingresses(metav1.NamespaceAll).List(context.TODO(), metav1.ListOptions{})
+	assert.Nil(t, err)
+	ingress = ingresses.Items[0]
+	assert.Equal(t, "letsencrypt", ingress.Annotations["cert-manager.io/cluster-issuer"])
+	assert.Equal(t, ingress.Name+"-tls", ingress.Spec.TLS[0].SecretName)
+}
+
+func TestProvision_CustomImage(t *testing.T) {
+	s := testService()
+	s.ImagePolicy.Allowlist = []string{"docker.io/library/*"}
+	r := testRequest()
+	allocateTestSlice(s, r, strings.Replace(
+		testRspecDiskImage,
+		`name="ubuntu2004"`,
+		`name="docker.io/library/nginx:1.21"`,
+		1,
+	))
+	provisionTestSlice(s, r)
+	deployments := listTestDeployments(s)
+	assert.Len(t, deployments, 1)
+	assert.Len(t, deployments[0].Spec.Template.Spec.Containers[0].VolumeMounts, 0)
+	// The service of the slice has no sliver name.
+	services, err := s.Services(metav1.NamespaceAll).
+		List(context.TODO(), metav1.ListOptions{LabelSelector: constants.Fed4FireSliverName})
+	assert.Nil(t, err)
+	assert.Len(t, services.Items, 0)
+	policies, err := s.NetworkPolicies(metav1.NamespaceAll).
+		List(context.TODO(), metav1.ListOptions{})
+	assert.Nil(t, err)
+	assert.Len(t, policies.Items, 1)
+	assert.Len(t, policies.Items[0].Spec.Ingress, 1)
+}
+
+func TestProvision_Hosts(t *testing.T) {
+	s := testService()
+	r := testRequest()
+	allocateTestSlice(s, r, testRspecLinks)
+	provisionTestSlice(s, r)
+	sliceHash := naming.SliceHash(testSliceIdentifier.URN())
+	for _, deployment := range listTestDeployments(s) {
+		spec := deployment.Spec.Template.Spec
+		assert.Contains(t, []string{"pc1", "pc2"}, spec.Hostname)
+		assert.Equal(t, sliceHash, spec.Subdomain)
+		assert.Equal(t, []string{sliceHash + ".default.svc.cluster.local"}, spec.DNSConfig.Searches)
+	}
+	// Both slivers own the headless service of the slice.
+	service, err := s.Services("default").Get(context.TODO(), sliceHash, metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, corev1.ClusterIPNone, service.Spec.ClusterIP)
+	assert.True(t, service.Spec.PublishNotReadyAddresses)
+	assert.Equal(t, map[string]string{constants.Fed4FireSliceHash: sliceHash}, service.Spec.Selector)
+	assert.Len(t, service.OwnerReferences, 2)
+
+	s = testService()
+	s.ClusterDomain = ""
+	allocateTestSlice(s, r, testRspecLinks)
+	provisionTestSlice(s, r)
+	for _, deployment := range listTestDeployments(s) {
+		assert.Equal(t, "", deployment.Spec.Template.Spec.Hostname)
+		assert.Nil(t, deployment.Spec.Template.Spec.DNSConfig)
+	}
+	_, err = s.Services("default").Get(context.TODO(), sliceHash, metav1.GetOptions{})
+	assert.NotNil(t, err)
+}
+
+func TestProvision_Volumes(t *testing.T) {
+	s := testService()
+	r := testRequest()
+	allocateTestSlice(s, r, testRspecVolumes)
+	provisionTestSlice(s, r)
+	claims, err := s.PersistentVolumeClaims(metav1.NamespaceAll).List(context.TODO(), metav1.ListOptions{})
+	assert.Nil(t, err)
+	assert.Len(t, claims.Items, 2)
+	sliverName := naming.SliverName(testSliceIdentifier.URN(), "PC1")
+	for _, claim := range claims.Items {
+		assert.Equal(t, "", claim.Labels[constants.Fed4FireRetain])
+		if claim.Name == sliverName+"-data" {
+			assert.Equal(t, "local-path", *claim.Spec.StorageClassName)
+			assert.Equal(t, []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce}, claim.Spec.AccessModes)
+			assert.Equal(t, "1Gi", claim.Spec.Resources.Requests.Storage().String())
+			assert.Len(t, claim.OwnerReferences, 1)
+			assert.Equal(t, sliverName, claim.OwnerReferences[0].Name)
+		} else {
+			// The shared volume is owned by both slivers.
+			assert.Equal(t, naming.VolumeName(testSliceIdentifier.URN(), "shared"), claim.Name)
+			assert.Equal(t, "nfs", *claim.Spec.StorageClassName)
+			assert.Equal(t, []corev1.PersistentVolumeAccessMode{corev1.ReadWriteMany}, claim.Spec.AccessModes)
+			assert.Len(t, claim.OwnerReferences, 2)
+		}
+	}
+	for _, deployment := range listTestDeployments(s) {
+		spec := deployment.Spec.Template.Spec
+		mounts := spec.Containers[0].VolumeMounts
+		if deployment.Name == sliverName {
+			assert.Contains(t, mounts, corev1.VolumeMount{Name: "pvc-data", MountPath: "/data"})
+			assert.Contains(t, mounts, corev1.VolumeMount{Name: "pvc-shared", MountPath: "/shared"})
+		} else {
+			assert.Contains(t, mounts, corev1.VolumeMount{Name: "pvc-shared", MountPath: "/mnt/shared"})
+		}
+		assert.Contains(t, spec.Volumes, corev1.Volume{
+			Name: "pvc-shared",
+			VolumeSource: corev1.VolumeSource{
+				PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
+					ClaimName: naming.VolumeName(testSliceIdentifier.URN(), "shared"),
+				},
+			},
+		})
+	}
+}
+
+func TestProvision_RetainedVolumes(t *testing.T) {
+	s := testService()
+	s.NamespacePerSlice = true
+	r := testRequest()
+	namespace := naming.SliceHash(testSliceIdentifier.URN())
+	allocateTestSlice(s, r, strings.Replace(testRspecVolumes, `path="/data/"`, `path="/data/" retain="true"`, 1))
+	provisionTestSlice(s, r)
+	sliverName := naming.SliverName(testSliceIdentifier.URN(), "PC1")
+	claim, err := s.PersistentVolumeClaims(namespace).Get(context.TODO(), sliverName+"-data", metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, "true", claim.Labels[constants.Fed4FireRetain])
+	assert.Len(t, claim.OwnerReferences, 0)
+	// The retained volumes expire with the slice, not with the sliver.
+	sliver := listTestSlivers(s)[0]
+	assert.Equal(
+		t,
+		sliver.Spec.SliceExpires.UTC().Format(time.RFC3339),
+		claim.Annotations[constants.Fed4FireExpires],
+	)
+	assert.True(t, sliver.Spec.SliceExpires.Before(&sliver.Spec.Expires))
+
+	// The expiration of the retained volumes follows the renewals of the slice.
+	renewArgs := &RenewArgs{
+		URNs:           []string{testSliceIdentifier.URN()},
+		Credentials:    []Credential{testSliceCredential},
+		ExpirationTime: "2100-01-02T15:04:05Z",
+	}
+	renewReply := &RenewReply{}
+	err = s.Renew(r, renewArgs, renewReply)
+	assert.Nil(t, err)
+	assert.Equal(t, constants.GeniCodeSuccess, renewReply.Data.Code.Code)
+	claim, err = s.PersistentVolumeClaims(namespace).Get(context.TODO(), sliverName+"-data", metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(
+		t,
+		sliver.Spec.SliceExpires.UTC().Format(time.RFC3339),
+		claim.Annotations[constants.Fed4FireExpires],
+	)
+	sliceExpires := time.Date(2100, 1, 1, 0, 0, 0, 0, time.UTC)
+	renewArgs.Credentials = []Credential{
+		createCredentialExpiring(testUserIdentifier, testSliceIdentifier, sliceExpires),
+	}
+	err = s.Renew(r, renewArgs, renewReply)
+	assert.Nil(t, err)
+	assert.Equal(t, constants.GeniCodeSuccess, renewReply.Data.Code.Code)
+	claim, err = s.PersistentVolumeClaims(namespace).Get(context.TODO(), sliverName+"-data", metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, "2100-01-01T00:00:00Z", claim.Annotations[constants.Fed4FireExpires])
+
+	// The namespace of the slice must outlive its slivers, for the retained volume.
+	deleteArgs := &DeleteArgs{
+		URNs:        []string{testSliceIdentifier.URN()},
+		Credentials: []Credential{testSliceCredential},
+	}
+	deleteReply := &DeleteReply{}
+	err = s.Delete(r, deleteArgs, deleteReply)
+	assert.Nil(t, err)
+	assert.Equal(t, constants.GeniCodeSuccess, deleteReply.Data.Code.Code)
+	_, err = s.Namespaces().Get(context.TODO(), namespace, metav1.GetOptions{})
+	assert.Nil(t, err)
+}
+
+func TestProvision_Links(t *testing.T) {
+	s := testService()
+	r := testRequest()
+	allocateTestSlice(s, r, testRspecLinks)
+	provisionTestSlice(s, r)
+	networks, err := s.NetworkAttachmentDefinitions(s.Namespace).
+		List(context.TODO(), metav1.ListOptions{})
+	assert.Nil(t, err)
+	assert.Len(t, networks.Items, 1)
+	// The network must be deleted with the last sliver attached to it.
+	assert.Len(t, networks.Items[0].GetOwnerReferences(), 2)
+	config, _, _ := unstructured.NestedString(networks.Items[0].Object, "spec", "config")
+	assert.Contains(t, config, networks.Items[0].GetName())
+	deployments := listTestDeployments(s)
+	assert.Len(t, deployments, 2)
+	for _, deployment := range deployments {
+		annotation := deployment.Spec.Template.Annotations[multusNetworksAnnotation]
+		assert.Contains(t, annotation, networks.Items[0].GetName())
+		assert.Contains(t, annotation, "net1")
+	}
+}
+
+func TestProvision_LinkProperties(t *testing.T) {
+	s := testService()
+	r := testRequest()
+	allocateTestSlice(s, r, fmt.Sprintf(
+		testRspecLinkProperties,
+		`capacity="10000" latency="20" packet_loss="0.01"`,
+	))
+	provisionTestSlice(s, r)
+	deployments := listTestDeployments(s)
+	assert.Len(t, deployments, 2)
+	for _, deployment := range deployments {
+		template := deployment.Spec.Template
+		if deployment.Name != naming.SliverName(testSliceIdentifier.URN(), "PC1") {
+			assert.Len(t, template.Spec.InitContainers, 0)
+			assert.NotContains(t, template.Annotations[multusNetworksAnnotation], "bandwidth")
+			continue
+		}
+		assert.Contains(
+			t,
+			template.Annotations[multusNetworksAnnotation],
+			`"bandwidth":{"ingressRate":0,"ingressBurst":0,"egressRate":10000000,"egressBurst":1000000}`,
+		)
+		assert.Len(t, template.Spec.InitContainers, 1)
+		assert.Equal(t, s.LinkShapingImage, template.Spec.InitContainers[0].Image)
+		assert.Contains(
+			t,
+			template.Spec.InitContainers[0].Command[2],
+			"tc qdisc replace dev net1 root netem delay 20ms loss 1%",
+		)
+	}
+}
+
+func TestProvision_Services(t *testing.T) {
+	s := testService()
+	r := testRequest()
+	allocateTestSlice(s, r, testRspecServices)
+	provisionTestSlice(s, r)
+	deployments := listTestDeployments(s)
+	assert.Len(t, deployments, 1)
+	spec := deployments[0].Spec.Template.Spec
+
+	// Both files are installed in the same volume.
+	assert.Len(t, spec.InitContainers, 1)
+	install := spec.InitContainers[0]
+	assert.Equal(t, s.InstallImage, install.Image)
+	assert.Len(t, install.VolumeMounts, 1)
+	assert.Equal(t, "/local", install.VolumeMounts[0].MountPath)
+	assert.Contains(t, install.Command[2], "tar -xzf '/local/.fed4fire-download' -C '/local'")
+	assert.Contains(t, install.Command[2], "mv '/local/.fed4fire-download' '/local/data.csv'")
+	assert.Len(t, spec.Volumes, 2)
+
+	container := spec.Containers[0]
+	assert.Contains(t, container.VolumeMounts, corev1.VolumeMount{
+		Name:      install.VolumeMounts[0].Name,
+		MountPath: "/local",
+	})
+	assert.Equal(t, "sh", container.Command[0])
+	assert.Contains(t, container.Command[2], `bash -c 'echo '"'"'Hello'"'"' > /tmp/hello'`)
+	assert.True(t, strings.HasSuffix(container.Command[2], "exec "+s.SshdCommand))
+	assert.NotNil(t, container.ReadinessProbe)
+}
+
+func TestProvision_ServicesReadOnlyRootFilesystem(t *testing.T) {
+	s := testService()
+	s.ReadOnlyRootFilesystem = true
+	r := testRequest()
+	allocateTestSlice(s, r, strings.ReplaceAll(testRspecServices, `install_path="/local"`, `install_path="/tmp"`))
+	provisionTestSlice(s, r)
+	deployments := listTestDeployments(s)
+	assert.Len(t, deployments, 1)
+	spec := deployments[0].Spec.Template.Spec
+	// The writable /tmp volume is reused.
+	assert.Len(t, spec.Volumes, 3)
+	assert.Equal(t, "tmp-volume", spec.InitContainers[0].VolumeMounts[0].Name)
+	assert.Len(t, spec.Containers[0].VolumeMounts, 3)
+}
+
+func TestProvision_Placement(t *testing.T) {
+	s := testService()
+	r := testRequest()
+	createTestPlacementNodes(s)
+	allocateTestSlice(s, r, testRspecPlacement)
+	provisionTestSlice(s, r)
+	deployments := listTestDeployments(s)
+	assert.Len(t, deployments, 1)
+	requirements := deployments[0].Spec.Template.Spec.Affinity.NodeAffinity.
+		RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms[0].MatchExpressions
+	assert.Contains(t, requirements, corev1.NodeSelectorRequirement{
+		Key:      constants.EdgeNetLabelCountryISO,
+		Operator: corev1.NodeSelectorOpIn,
+		Values:   []string{"BR"},
+	})
+	assert.Contains(t, requirements, corev1.NodeSelectorRequirement{
+		Key:      corev1.LabelHostname,
+		Operator: corev1.NodeSelectorOpIn,
+		Values:   []string{"node-1"},
+	})
+	assert.Contains(t, requirements, corev1.NodeSelectorRequirement{
+		Key:      "example.org/gpu",
+		Operator: corev1.NodeSelectorOpIn,
+		Values:   []string{"true"},
+	})
+}
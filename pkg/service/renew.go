@@ -6,7 +6,9 @@ import (
 	"net/http"
 	"time"
 
+	v1 "github.com/EdgeNet-project/fed4fire/pkg/apis/fed4fire/v1"
 	"github.com/EdgeNet-project/fed4fire/pkg/constants"
+	"github.com/EdgeNet-project/fed4fire/pkg/identifiers"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -50,7 +52,11 @@ func (s *Service) Renew(r *http.Request, args *RenewArgs, reply *RenewReply) err
 	}
 
 	for _, sliver := range slivers {
-		allocationStatus, operationalStatus := s.GetSliverStatus(r.Context(), sliver.Name)
+		allocationStatus, operationalStatus := s.GetSliverStatus(
+			r.Context(),
+			sliver.Namespace,
+			sliver.Name,
+		)
 		if time.Now().After(sliver.Spec.Expires.Time) {
 			return reply.SetAndLogError(
 				fmt.Errorf("sliver has expired"),
@@ -58,7 +64,17 @@ func (s *Service) Renew(r *http.Request, args *RenewArgs, reply *RenewReply) err
 			)
 		}
 		sliver.Spec.Expires = metav1.NewTime(expirationTime)
-		sliver, err := s.Slivers().Update(r.Context(), &sliver, metav1.UpdateOptions{})
+		// The slice may have been renewed as well, which extends its retained volumes.
+		sliceExpires, err := s.renewedSliceExpires(r, sliver, args.Credentials)
+		if err != nil {
+			return reply.SetAndLogError(err, constants.ErrorBadCredentials)
+		}
+		sliver.Spec.SliceExpires = sliceExpires
+		sliver, err := s.Slivers(sliver.Namespace).Update(r.Context(), &sliver, metav1.UpdateOptions{})
+		if err != nil {
+			return reply.SetAndLogError(err, constants.ErrorUpdateResource)
+		}
+		err = renewVolumeClaims(r.Context(), *s, *sliver)
 		if err != nil {
 			return reply.SetAndLogError(err, constants.ErrorUpdateResource)
 		}
@@ -71,3 +87,26 @@ func (s *Service) Renew(r *http.Request, args *RenewArgs, reply *RenewReply) err
 	reply.Data.Code.Code = constants.GeniCodeSuccess
 	return nil
 }
+
+// renewedSliceExpires returns the expiration of the slice of a sliver, from the slice credential if one is given.
+// It is never moved backwards.
+func (s *Service) renewedSliceExpires(r *http.Request, sliver v1.Sliver, credentials []Credential) (*metav1.Time, error) {
+	userIdentifier, err := identifiers.Parse(r.Header.Get(constants.HttpHeaderUser))
+	if err != nil {
+		return nil, err
+	}
+	sliceIdentifier, err := identifiers.Parse(sliver.Spec.SliceURN)
+	if err != nil {
+		return nil, err
+	}
+	credential, err := FindCredential(*userIdentifier, sliceIdentifier, credentials, s.TrustedCertificates)
+	if err != nil {
+		// The sliver is renewed with a sliver credential.
+		return sliver.Spec.SliceExpires, nil
+	}
+	if sliver.Spec.SliceExpires != nil && !credential.Expires.After(sliver.Spec.SliceExpires.Time) {
+		return sliver.Spec.SliceExpires, nil
+	}
+	sliceExpires := metav1.NewTime(credential.Expires)
+	return &sliceExpires, nil
+}
@@ -8,14 +8,19 @@ import (
 	v1 "github.com/EdgeNet-project/fed4fire/pkg/apis/fed4fire/v1"
 	"github.com/EdgeNet-project/fed4fire/pkg/generated/clientset/versioned"
 	fed4firev1 "github.com/EdgeNet-project/fed4fire/pkg/generated/clientset/versioned/typed/fed4fire/v1"
+	"github.com/EdgeNet-project/fed4fire/pkg/imagepolicy"
 	"github.com/EdgeNet-project/fed4fire/pkg/rspec"
+	"github.com/EdgeNet-project/fed4fire/pkg/security"
 	"html"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
 	"k8s.io/klog/v2"
 	"k8s.io/utils/pointer"
 	"net/http"
+	"strings"
+	"text/template"
 	"time"
 
 	"github.com/EdgeNet-project/fed4fire/pkg/constants"
@@ -26,54 +31,169 @@ import (
 	"github.com/EdgeNet-project/fed4fire/pkg/utils"
 	typedappsv1 "k8s.io/client-go/kubernetes/typed/apps/v1"
 	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	typednetworkingv1 "k8s.io/client-go/kubernetes/typed/networking/v1"
 
 	"github.com/EdgeNet-project/fed4fire/pkg/xmlsec1"
 
 	"github.com/EdgeNet-project/fed4fire/pkg/identifiers"
 	"github.com/EdgeNet-project/fed4fire/pkg/sfa"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 )
 
 type Service struct {
 	AbsoluteURL          string
 	AuthorityIdentifier  identifiers.Identifier
-	ContainerImages      map[string]string
-	ContainerCpuLimit    string
-	ContainerMemoryLimit string
-	NamespaceCpuLimit    string
-	NamespaceMemoryLimit string
+	ClusterDomain        string
+	ContainerImages      []v1.ContainerImage
+	ContainerCpuLimit    resource.Quantity
+	ContainerMemoryLimit resource.Quantity
+	ExclusiveNodeLabel   string
+	ImagePolicy          imagepolicy.Policy
+	IngressClass         string
+	IngressDomain        string
+	IngressIssuer        string
+	IngressTLSSecret     string
+	InstallImage         string
+	LinkCniConfig        *template.Template
+	LinkShapingImage     string
+	LinkSubnet           string
+	MaxCpuRequest        resource.Quantity
+	MaxLinkCapacity      int64
+	MaxLinkLatency       int64
+	MaxMemoryRequest     resource.Quantity
+	MaxNodeCount         int
+	MaxRspecSize         int
+	MaxVolumeSize        resource.Quantity
+	NamespaceCpuLimit    resource.Quantity
+	NamespaceMemoryLimit resource.Quantity
 	Namespace            string
-	TrustedCertificates  [][]byte
-	Fed4FireClient       versioned.Interface
-	KubernetesClient     kubernetes.Interface
+	// Create one namespace per slice instead of using Namespace for all the slivers.
+	NamespacePerSlice      bool
+	NetworkIsolation       bool
+	PortServiceType        string
+	PlacementLabels        []string
+	ReadOnlyRootFilesystem bool
+	SecurityProfiles       map[string]security.Profile
+	SharedStorageClass     string
+	SshdCommand            string
+	StrictDiskImages       bool
+	TrustedCertificates    [][]byte
+	StorageClass           string
+	// Maximum requests of specific users, keyed by user URN or authority, instead of MaxCpuRequest and MaxMemoryRequest.
+	UserMaxRequests  map[string]MaxRequest
+	DynamicClient    dynamic.Interface
+	Fed4FireClient   versioned.Interface
+	KubernetesClient kubernetes.Interface
 }
 
-func (s Service) ConfigMaps() typedcorev1.ConfigMapInterface {
-	return s.KubernetesClient.CoreV1().ConfigMaps(s.Namespace)
+func (s Service) ConfigMaps(namespace string) typedcorev1.ConfigMapInterface {
+	return s.KubernetesClient.CoreV1().ConfigMaps(namespace)
 }
 
-func (s Service) Deployments() typedappsv1.DeploymentInterface {
-	return s.KubernetesClient.AppsV1().Deployments(s.Namespace)
+// ContainerImageCatalog returns the client of the ContainerImage resources.
+// The images given on the command line are in s.ContainerImages.
+func (s Service) ContainerImageCatalog() fed4firev1.ContainerImageInterface {
+	return s.Fed4FireClient.Fed4fireV1().ContainerImages()
+}
+
+func (s Service) Deployments(namespace string) typedappsv1.DeploymentInterface {
+	return s.KubernetesClient.AppsV1().Deployments(namespace)
+}
+
+func (s Service) Ingresses(namespace string) typednetworkingv1.IngressInterface {
+	return s.KubernetesClient.NetworkingV1().Ingresses(namespace)
+}
+
+func (s Service) LimitRanges(namespace string) typedcorev1.LimitRangeInterface {
+	return s.KubernetesClient.CoreV1().LimitRanges(namespace)
+}
+
+func (s Service) Namespaces() typedcorev1.NamespaceInterface {
+	return s.KubernetesClient.CoreV1().Namespaces()
+}
+
+func (s Service) NetworkPolicies(namespace string) typednetworkingv1.NetworkPolicyInterface {
+	return s.KubernetesClient.NetworkingV1().NetworkPolicies(namespace)
+}
+
+func (s Service) NetworkAttachmentDefinitions(namespace string) dynamic.ResourceInterface {
+	return s.DynamicClient.Resource(networkAttachmentDefinitionResource).Namespace(namespace)
 }
 
 func (s Service) Nodes() typedcorev1.NodeInterface {
 	return s.KubernetesClient.CoreV1().Nodes()
 }
 
-func (s Service) Pods() typedcorev1.PodInterface {
-	return s.KubernetesClient.CoreV1().Pods(s.Namespace)
+func (s Service) PersistentVolumeClaims(namespace string) typedcorev1.PersistentVolumeClaimInterface {
+	return s.KubernetesClient.CoreV1().PersistentVolumeClaims(namespace)
+}
+
+func (s Service) Pods(namespace string) typedcorev1.PodInterface {
+	return s.KubernetesClient.CoreV1().Pods(namespace)
 }
 
-func (s Service) Services() typedcorev1.ServiceInterface {
-	return s.KubernetesClient.CoreV1().Services(s.Namespace)
+func (s Service) ResourceQuotas(namespace string) typedcorev1.ResourceQuotaInterface {
+	return s.KubernetesClient.CoreV1().ResourceQuotas(namespace)
 }
 
-func (s Service) Slivers() fed4firev1.SliverInterface {
-	return s.Fed4FireClient.Fed4fireV1().Slivers(s.Namespace)
+func (s Service) Secrets(namespace string) typedcorev1.SecretInterface {
+	return s.KubernetesClient.CoreV1().Secrets(namespace)
 }
 
-func (s Service) GetSliver(ctx context.Context, name string) *v1.Sliver {
-	sliver, err := s.Slivers().Get(ctx, name, metav1.GetOptions{})
+func (s Service) Services(namespace string) typedcorev1.ServiceInterface {
+	return s.KubernetesClient.CoreV1().Services(namespace)
+}
+
+func (s Service) Slivers(namespace string) fed4firev1.SliverInterface {
+	return s.Fed4FireClient.Fed4fireV1().Slivers(namespace)
+}
+
+// SliceNamespace returns the namespace in which the slivers of a slice are created.
+func (s Service) SliceNamespace(sliceUrn string) string {
+	if s.NamespacePerSlice {
+		return naming.SliceHash(sliceUrn)
+	}
+	return s.Namespace
+}
+
+// MaxRequest is the maximum amount of CPU and memory that a user can request for a container.
+type MaxRequest struct {
+	Cpu    resource.Quantity
+	Memory resource.Quantity
+}
+
+// MaxRequest returns the maximum requests of a user: the ones of its URN, else the ones of its authority,
+// e.g. example.org for urn:publicid:IDN+example.org+user+alice, else MaxCpuRequest and MaxMemoryRequest.
+func (s Service) MaxRequest(user identifiers.Identifier) MaxRequest {
+	if max, ok := s.UserMaxRequests[user.URN()]; ok {
+		return max
+	}
+	if max, ok := s.UserMaxRequests[strings.Join(user.Authorities, ":")]; ok {
+		return max
+	}
+	return MaxRequest{Cpu: s.MaxCpuRequest, Memory: s.MaxMemoryRequest}
+}
+
+// SecurityProfile returns the security profile of the containers of the given sliver type.
+func (s Service) SecurityProfile(sliverType string) security.Profile {
+	if profile, ok := s.SecurityProfiles[sliverType]; ok {
+		return profile
+	}
+	return security.ProfileBaseline
+}
+
+// SliversNamespace returns the namespace in which to search for slivers.
+// When using one namespace per slice, the slivers are searched in all the namespaces.
+func (s Service) SliversNamespace() string {
+	if s.NamespacePerSlice {
+		return metav1.NamespaceAll
+	}
+	return s.Namespace
+}
+
+func (s Service) GetSliver(ctx context.Context, namespace string, name string) *v1.Sliver {
+	sliver, err := s.Slivers(namespace).Get(ctx, name, metav1.GetOptions{})
 	if err != nil {
 		if !errors.IsNotFound(err) {
 			klog.ErrorS(err, "Failed to get sliver")
@@ -83,26 +203,33 @@ func (s Service) GetSliver(ctx context.Context, name string) *v1.Sliver {
 	return sliver
 }
 
-func (s Service) GetSliverArchHostPort(ctx context.Context, name string) (*string, *string, *int) {
-	service, err := s.Services().Get(ctx, name, metav1.GetOptions{})
+// nodeArch returns the architecture of a node, or an empty string if the node does not exist.
+func (s Service) nodeArch(ctx context.Context, name string) (string, error) {
+	node, err := s.Nodes().Get(ctx, name, metav1.GetOptions{})
 	if err != nil {
-		if !errors.IsNotFound(err) {
-			klog.ErrorS(err, "Failed to get service")
+		if errors.IsNotFound(err) {
+			return "", nil
 		}
+		return "", err
+	}
+	return node.Labels[corev1.LabelArchStable], nil
+}
+
+func (s Service) GetSliverArchHostPort(
+	ctx context.Context,
+	namespace string,
+	name string,
+) (*string, *string, *int) {
+	service := s.GetSliverService(ctx, namespace, name)
+	if service == nil {
 		return nil, nil, nil
 	}
-	pods, err := s.Pods().List(ctx, metav1.ListOptions{
-		FieldSelector: fmt.Sprintf("%s=%s", "status.phase", corev1.PodRunning),
-		LabelSelector: fmt.Sprintf("%s=%s", constants.Fed4FireSliverName, name),
-	})
-	if err != nil {
-		if !errors.IsNotFound(err) {
-			klog.ErrorS(err, "Failed to list pods")
-		}
+	sshPort := sshServicePort(*service)
+	if sshPort == nil {
 		return nil, nil, nil
 	}
-	if len(pods.Items) > 0 {
-		pod := pods.Items[0]
+	pod := s.GetSliverRunningPod(ctx, namespace, name)
+	if pod != nil {
 		node, err := s.Nodes().Get(ctx, pod.Spec.NodeName, metav1.GetOptions{})
 		if err != nil {
 			if !errors.IsNotFound(err) {
@@ -110,23 +237,88 @@ func (s Service) GetSliverArchHostPort(ctx context.Context, name string) (*strin
 			}
 			return nil, nil, nil
 		}
-		for _, address := range node.Status.Addresses {
-			if address.Type == corev1.NodeInternalIP {
-				return pointer.StringPtr(
-						node.Labels[corev1.LabelArchStable],
-					), pointer.StringPtr(
-						address.Address,
-					), pointer.IntPtr(
-						int(service.Spec.Ports[0].NodePort),
-					)
-			}
+		if address := nodeInternalIP(*node); address != "" {
+			return pointer.StringPtr(
+					node.Labels[corev1.LabelArchStable],
+				), pointer.StringPtr(
+					address,
+				), pointer.IntPtr(
+					int(sshPort.NodePort),
+				)
 		}
 	}
 	return nil, nil, nil
 }
 
-func (s Service) GetSliverDeployment(ctx context.Context, name string) *appsv1.Deployment {
-	deployment, err := s.Deployments().Get(ctx, name, metav1.GetOptions{})
+// GetSliverService returns the service exposing the ports of a sliver, or nil if there is none.
+func (s Service) GetSliverService(ctx context.Context, namespace string, name string) *corev1.Service {
+	service, err := s.Services(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			klog.ErrorS(err, "Failed to get service")
+		}
+		return nil
+	}
+	return service
+}
+
+// GetSliverRunningPod returns a running pod of the sliver, or nil if there is none.
+func (s Service) GetSliverRunningPod(ctx context.Context, namespace string, name string) *corev1.Pod {
+	pods, err := s.Pods(namespace).List(ctx, metav1.ListOptions{
+		FieldSelector: fmt.Sprintf("%s=%s", "status.phase", corev1.PodRunning),
+		LabelSelector: fmt.Sprintf("%s=%s", constants.Fed4FireSliverName, name),
+	})
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			klog.ErrorS(err, "Failed to list pods")
+		}
+		return nil
+	}
+	if len(pods.Items) == 0 {
+		return nil
+	}
+	return &pods.Items[0]
+}
+
+// GetSliverPod returns a pod of the sliver in any phase, or nil if there is none.
+func (s Service) GetSliverPod(ctx context.Context, namespace string, name string) *corev1.Pod {
+	pods, err := s.Pods(namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: fmt.Sprintf("%s=%s", constants.Fed4FireSliverName, name),
+	})
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			klog.ErrorS(err, "Failed to list pods")
+		}
+		return nil
+	}
+	if len(pods.Items) == 0 {
+		return nil
+	}
+	return &pods.Items[0]
+}
+
+// GetSliverNode returns the Kubernetes node on which the sliver is scheduled, or nil if there is none.
+func (s Service) GetSliverNode(ctx context.Context, namespace string, name string) *corev1.Node {
+	pod := s.GetSliverPod(ctx, namespace, name)
+	if pod == nil || pod.Spec.NodeName == "" {
+		return nil
+	}
+	node, err := s.Nodes().Get(ctx, pod.Spec.NodeName, metav1.GetOptions{})
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			klog.ErrorS(err, "Failed to get node")
+		}
+		return nil
+	}
+	return node
+}
+
+func (s Service) GetSliverDeployment(
+	ctx context.Context,
+	namespace string,
+	name string,
+) *appsv1.Deployment {
+	deployment, err := s.Deployments(namespace).Get(ctx, name, metav1.GetOptions{})
 	if err != nil {
 		if !errors.IsNotFound(err) {
 			klog.ErrorS(err, "Failed to get deployment")
@@ -136,19 +328,32 @@ func (s Service) GetSliverDeployment(ctx context.Context, name string) *appsv1.D
 	return deployment
 }
 
-func (s Service) GetSliverStatus(ctx context.Context, name string) (string, string) {
+func (s Service) GetSliverStatus(ctx context.Context, namespace string, name string) (string, string) {
 	allocationStatus := constants.GeniStateUnallocated
 	operationalStatus := constants.GeniStateNotReady
-	sliver := s.GetSliver(ctx, name)
+	sliver := s.GetSliver(ctx, namespace, name)
 	if sliver != nil {
 		allocationStatus = constants.GeniStateAllocated
-		deployment := s.GetSliverDeployment(ctx, name)
+		deployment := s.GetSliverDeployment(ctx, namespace, name)
 		if deployment != nil {
 			allocationStatus = constants.GeniStateProvisioned
 		}
-		arch, host, port := s.GetSliverArchHostPort(ctx, name)
-		if arch != nil && host != nil && port != nil {
-			operationalStatus = constants.GeniStateReady
+		if sliver.Spec.SliverType == constants.SliverTypeCustomContainer {
+			// Without SSH, the sliver is ready as soon as its container runs.
+			if s.GetSliverRunningPod(ctx, namespace, name) != nil {
+				operationalStatus = constants.GeniStateReady
+			}
+		} else {
+			arch, host, port := s.GetSliverArchHostPort(ctx, namespace, name)
+			if arch != nil && host != nil && port != nil {
+				operationalStatus = constants.GeniStateReady
+			}
+		}
+		if len(sliver.Spec.Installs) > 0 || len(sliver.Spec.Executes) > 0 {
+			pod := s.GetSliverPod(ctx, namespace, name)
+			if status := servicesOperationalStatus(pod); status != "" {
+				operationalStatus = status
+			}
 		}
 	}
 	return allocationStatus, operationalStatus
@@ -162,7 +367,7 @@ func (s Service) ListSlivers(
 	case identifiers.ResourceTypeSlice:
 		sliceHash := naming.SliceHash(identifier.URN())
 		labelSelector := fmt.Sprintf("%s=%s", constants.Fed4FireSliceHash, sliceHash)
-		slivers, err := s.Slivers().List(ctx, metav1.ListOptions{
+		slivers, err := s.Slivers(s.SliversNamespace()).List(ctx, metav1.ListOptions{
 			LabelSelector: labelSelector,
 		})
 		if err != nil {
@@ -170,11 +375,19 @@ func (s Service) ListSlivers(
 		}
 		return slivers.Items, nil
 	case identifiers.ResourceTypeSliver:
-		sliver, err := s.Slivers().Get(ctx, identifier.ResourceName, metav1.GetOptions{})
+		// The sliver name does not tell in which namespace the sliver is,
+		// so we search for it by label in all the namespaces we use.
+		labelSelector := fmt.Sprintf("%s=%s", constants.Fed4FireSliverName, identifier.ResourceName)
+		slivers, err := s.Slivers(s.SliversNamespace()).List(ctx, metav1.ListOptions{
+			LabelSelector: labelSelector,
+		})
 		if err != nil {
 			return nil, err
 		}
-		return []v1.Sliver{*sliver}, nil
+		if len(slivers.Items) == 0 {
+			return nil, errors.NewNotFound(v1.Resource("sliver"), identifier.ResourceName)
+		}
+		return slivers.Items, nil
 	default:
 		return nil, fmt.Errorf("identifier type must be slice or sliver")
 	}
@@ -300,9 +513,12 @@ func (c Credential) ValidatedSFA(trustedCertificates [][]byte) (*sfa.Credential,
 	if c.Type != constants.GeniCredentialTypeSfa {
 		return nil, fmt.Errorf("credential type is not geni_sfa")
 	}
-	val := []byte(html.UnescapeString(c.Value))
+	val, err := DecodeDocument(c.Value, maxCredentialSize)
+	if err != nil {
+		return nil, err
+	}
 	// 1. Verify the credential signature
-	err := xmlsec1.Verify(trustedCertificates, val)
+	err = xmlsec1.Verify(trustedCertificates, val)
 	if err != nil {
 		return nil, err
 	}
@@ -407,3 +623,25 @@ func MarshalRspec(rspec rspec.Rspec, compressed bool) (string, error) {
 	}
 	return string(b), nil
 }
+
+// Maximum size of a credential, once decompressed.
+const maxCredentialSize = 1 << 20
+
+// DecodeDocument returns an XML document passed as an argument, either as is, HTML-escaped,
+// or zlib-compressed and base64-encoded as geni_compressed outputs, which several AMs also accept as inputs.
+// It returns utils.ErrTooLarge if the document is larger than limit bytes once decompressed.
+func DecodeDocument(s string, limit int64) ([]byte, error) {
+	trimmed := strings.TrimSpace(s)
+	if strings.HasPrefix(trimmed, "<") || strings.HasPrefix(trimmed, "&lt;") {
+		return []byte(html.UnescapeString(s)), nil
+	}
+	// Some encoders wrap the base64 lines.
+	b, err := utils.DecompressZlibBase64Limit(strings.Join(strings.Fields(trimmed), ""), limit)
+	if err != nil {
+		if err == utils.ErrTooLarge {
+			return nil, err
+		}
+		return nil, fmt.Errorf("document is neither XML nor zlib-compressed base64: %w", err)
+	}
+	return b, nil
+}
@@ -1,6 +1,9 @@
 package service
 
 import (
+	"github.com/EdgeNet-project/fed4fire/pkg/identifiers"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/api/resource"
 	"testing"
 )
 
@@ -38,3 +41,24 @@ func TestFindMatchingCredential(t *testing.T) {
 		t.Errorf("FindCredential() = %s; want nil", err)
 	}
 }
+
+func TestMaxRequest(t *testing.T) {
+	s := testService()
+	other := identifiers.MustParse("urn:publicid:IDN+example.org+user+other")
+	foreign := identifiers.MustParse("urn:publicid:IDN+example.com+user+test")
+	s.UserMaxRequests = map[string]MaxRequest{
+		"example.org":            {Cpu: resource.MustParse("8"), Memory: resource.MustParse("16Gi")},
+		testUserIdentifier.URN(): {Cpu: resource.MustParse("16"), Memory: resource.MustParse("32Gi")},
+	}
+	// The maxima of the user URN take precedence over the ones of its authority.
+	max := s.MaxRequest(testUserIdentifier)
+	assert.Equal(t, "16", max.Cpu.String())
+	assert.Equal(t, "32Gi", max.Memory.String())
+	max = s.MaxRequest(other)
+	assert.Equal(t, "8", max.Cpu.String())
+	assert.Equal(t, "16Gi", max.Memory.String())
+	// The other users get the global maxima.
+	max = s.MaxRequest(foreign)
+	assert.Equal(t, "4", max.Cpu.String())
+	assert.Equal(t, "4Gi", max.Memory.String())
+}
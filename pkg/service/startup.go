@@ -0,0 +1,252 @@
+package service
+
+import (
+	"fmt"
+	v1 "github.com/EdgeNet-project/fed4fire/pkg/apis/fed4fire/v1"
+	"github.com/EdgeNet-project/fed4fire/pkg/constants"
+	"github.com/EdgeNet-project/fed4fire/pkg/rspec"
+	corev1 "k8s.io/api/core/v1"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// Shells that can run the execute services.
+var executeShells = []string{"sh", "bash"}
+
+// Top-level directories of the images which cannot contain an install path,
+// since the empty volume mounted there would hide the files of the image, e.g. the binaries or the libraries.
+// The directories starting with lib, e.g. /lib64, are also excluded.
+var systemDirectories = []string{"bin", "boot", "dev", "etc", "proc", "run", "sbin", "sys", "usr", "var"}
+
+// isSystemPath returns true if a clean absolute path is, or is in, a system directory of the images.
+func isSystemPath(p string) bool {
+	top := strings.SplitN(strings.TrimPrefix(p, "/"), "/", 2)[0]
+	if strings.HasPrefix(top, "lib") {
+		return true
+	}
+	for _, dir := range systemDirectories {
+		if top == dir {
+			return true
+		}
+	}
+	return false
+}
+
+// sliverServicesForNode returns the install and execute services of a request RSpec node,
+// or an error if one of them cannot be run.
+func sliverServicesForNode(
+	node rspec.Node,
+	sliverType string,
+) ([]v1.SliverInstall, []v1.SliverExecute, error) {
+	installs := make([]v1.SliverInstall, 0)
+	executes := make([]v1.SliverExecute, 0)
+	if node.Services == nil {
+		return installs, executes, nil
+	}
+	for _, install := range node.Services.Installs {
+		u, err := url.Parse(install.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, nil, fmt.Errorf("install url %s must be an HTTP(S) URL", install.URL)
+		}
+		if !path.IsAbs(install.InstallPath) || path.Clean(install.InstallPath) == "/" {
+			return nil, nil, fmt.Errorf(
+				"install path %s must be an absolute path other than /",
+				install.InstallPath,
+			)
+		}
+		if isSystemPath(path.Clean(install.InstallPath)) {
+			return nil, nil, fmt.Errorf(
+				"install path %s must not be in a system directory such as /usr or /etc",
+				install.InstallPath,
+			)
+		}
+		installs = append(installs, v1.SliverInstall{
+			URL:         install.URL,
+			InstallPath: path.Clean(install.InstallPath),
+		})
+	}
+	for _, execute := range node.Services.Executes {
+		// The startup wrapper must know the command of the image, which is only the case for sshd.
+		if sliverType == constants.SliverTypeCustomContainer {
+			return nil, nil, fmt.Errorf("execute services are not supported with custom images")
+		}
+		shell := execute.Shell
+		if shell == "" {
+			shell = executeShells[0]
+		}
+		supported := false
+		for _, shell_ := range executeShells {
+			supported = supported || shell == shell_
+		}
+		if !supported {
+			return nil, nil, fmt.Errorf("shell must be one of %s", strings.Join(executeShells, ", "))
+		}
+		executes = append(executes, v1.SliverExecute{Shell: shell, Command: execute.Command})
+	}
+	return installs, executes, nil
+}
+
+// rspecServicesForSliver returns the services of a sliver as reported in the manifests, with the given logins.
+// It returns nil if there are no services.
+func rspecServicesForSliver(sliver v1.Sliver, logins []rspec.Login) *rspec.Services {
+	if len(logins) == 0 && len(sliver.Spec.Installs) == 0 && len(sliver.Spec.Executes) == 0 {
+		return nil
+	}
+	services := &rspec.Services{Logins: logins}
+	for _, install := range sliver.Spec.Installs {
+		services.Installs = append(services.Installs, rspec.Install{
+			URL:         install.URL,
+			InstallPath: install.InstallPath,
+		})
+	}
+	for _, execute := range sliver.Spec.Executes {
+		services.Executes = append(services.Executes, rspec.Execute{
+			Shell:   execute.Shell,
+			Command: execute.Command,
+		})
+	}
+	return services
+}
+
+// shellQuote quotes a string so that it is passed as a single word to sh.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'"'"'`) + "'"
+}
+
+// installVolumeName returns the name of the volume holding the files installed in a directory.
+func installVolumeName(installPath string) string {
+	return "install" + strings.ReplaceAll(installPath, "/", "-") + "-volume"
+}
+
+// buildInstallContainer returns an init container downloading the install services of a sliver,
+// with the volumes mounted on the install paths, or nil if there is nothing to install.
+// The volumes already mounted on an install path, e.g. with a read-only root filesystem, are reused.
+func buildInstallContainer(
+	s Service,
+	sliver v1.Sliver,
+	securityContext *corev1.SecurityContext,
+	volumeMounts []corev1.VolumeMount,
+) (*corev1.Container, []corev1.Volume, []corev1.VolumeMount) {
+	if len(sliver.Spec.Installs) == 0 {
+		return nil, nil, nil
+	}
+	mounted := make(map[string]string)
+	for _, volumeMount := range volumeMounts {
+		if volumeMount.SubPath == "" {
+			mounted[volumeMount.MountPath] = volumeMount.Name
+		}
+	}
+	volumes := make([]corev1.Volume, 0)
+	containerMounts := make([]corev1.VolumeMount, 0)
+	newMounts := make([]corev1.VolumeMount, 0)
+	commands := []string{"set -e"}
+	for _, install := range sliver.Spec.Installs {
+		name, ok := mounted[install.InstallPath]
+		if !ok {
+			name = installVolumeName(install.InstallPath)
+			mounted[install.InstallPath] = name
+			volumes = append(volumes, corev1.Volume{
+				Name: name,
+				VolumeSource: corev1.VolumeSource{
+					EmptyDir: &corev1.EmptyDirVolumeSource{},
+				},
+			})
+			newMounts = append(newMounts, corev1.VolumeMount{
+				Name:      name,
+				MountPath: install.InstallPath,
+			})
+		}
+		containerMounts = appendVolumeMount(containerMounts, corev1.VolumeMount{
+			Name:      name,
+			MountPath: install.InstallPath,
+		})
+		// The file is downloaded in the install path, since the root filesystem may be read-only.
+		dir := shellQuote(install.InstallPath)
+		file := shellQuote(path.Join(install.InstallPath, ".fed4fire-download"))
+		commands = append(
+			commands,
+			fmt.Sprintf("echo Installing %s in %s", shellQuote(install.URL), dir),
+			fmt.Sprintf("wget -q -O %s %s", file, shellQuote(install.URL)),
+		)
+		switch u := strings.ToLower(install.URL); {
+		case strings.HasSuffix(u, ".tar.gz"), strings.HasSuffix(u, ".tgz"):
+			commands = append(commands, fmt.Sprintf("tar -xzf %s -C %s", file, dir), "rm "+file)
+		case strings.HasSuffix(u, ".tar"):
+			commands = append(commands, fmt.Sprintf("tar -xf %s -C %s", file, dir), "rm "+file)
+		case strings.HasSuffix(u, ".zip"):
+			commands = append(commands, fmt.Sprintf("unzip -o -q %s -d %s", file, dir), "rm "+file)
+		default:
+			target := shellQuote(path.Join(install.InstallPath, path.Base(install.URL)))
+			commands = append(commands, fmt.Sprintf("mv %s %s", file, target))
+		}
+	}
+	container := &corev1.Container{
+		Name:            "install",
+		Image:           s.InstallImage,
+		Command:         []string{"sh", "-c", strings.Join(commands, "\n")},
+		SecurityContext: securityContext,
+		VolumeMounts:    containerMounts,
+	}
+	return container, volumes, newMounts
+}
+
+// appendVolumeMount appends a volume mount, unless a volume is already mounted on the same path.
+func appendVolumeMount(volumeMounts []corev1.VolumeMount, volumeMount corev1.VolumeMount) []corev1.VolumeMount {
+	for _, volumeMount_ := range volumeMounts {
+		if volumeMount_.MountPath == volumeMount.MountPath {
+			return volumeMounts
+		}
+	}
+	return append(volumeMounts, volumeMount)
+}
+
+// buildStartupCommand returns the command of a container running the execute services of a sliver before sshd,
+// or nil if there is nothing to execute, in which case the command of the image is used.
+// The container stops if a command fails, so that the failure is reported in the operational state.
+func buildStartupCommand(s Service, sliver v1.Sliver) []string {
+	if len(sliver.Spec.Executes) == 0 {
+		return nil
+	}
+	commands := []string{"set -e"}
+	for _, execute := range sliver.Spec.Executes {
+		commands = append(
+			commands,
+			fmt.Sprintf("echo Executing %s", shellQuote(execute.Command)),
+			fmt.Sprintf("%s -c %s", execute.Shell, shellQuote(execute.Command)),
+		)
+	}
+	commands = append(commands, "exec "+s.SshdCommand)
+	return []string{"sh", "-c", strings.Join(commands, "\n")}
+}
+
+// servicesOperationalStatus returns the operational state of a sliver pod running install or execute services:
+// configuring until they are done, and failed if one of them exited with a non-zero code.
+// It returns an empty string if the services do not determine the state.
+func servicesOperationalStatus(pod *corev1.Pod) string {
+	if pod == nil {
+		return ""
+	}
+	statuses := append(
+		append([]corev1.ContainerStatus{}, pod.Status.InitContainerStatuses...),
+		pod.Status.ContainerStatuses...,
+	)
+	for _, status := range statuses {
+		for _, state := range []corev1.ContainerState{status.State, status.LastTerminationState} {
+			if state.Terminated != nil && state.Terminated.ExitCode != 0 {
+				return constants.GeniStateFailed
+			}
+		}
+	}
+	for _, status := range pod.Status.InitContainerStatuses {
+		if status.State.Terminated == nil {
+			return constants.GeniStateConfiguring
+		}
+	}
+	for _, status := range pod.Status.ContainerStatuses {
+		if status.State.Running != nil && !status.Ready {
+			return constants.GeniStateConfiguring
+		}
+	}
+	return ""
+}
@@ -43,7 +43,11 @@ func (s *Service) Status(r *http.Request, args *StatusArgs, reply *StatusReply)
 	}
 
 	for _, sliver := range slivers {
-		allocationStatus, operationalStatus := s.GetSliverStatus(r.Context(), sliver.Name)
+		allocationStatus, operationalStatus := s.GetSliverStatus(
+			r.Context(),
+			sliver.Namespace,
+			sliver.Name,
+		)
 		// The spec. says that all the requested slivers belong to the same slice,
 		// so it's safe to retrieve the slice URN from any sliver.
 		reply.Data.Value.URN = sliver.Spec.SliceURN
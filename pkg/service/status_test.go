@@ -1,8 +1,11 @@
 package service
 
 import (
+	"context"
 	"github.com/EdgeNet-project/fed4fire/pkg/constants"
 	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"testing"
 )
 
@@ -20,3 +23,60 @@ func TestStatus(t *testing.T) {
 	assert.Equal(t, constants.GeniCodeSuccess, reply.Data.Code.Code)
 	assert.Len(t, reply.Data.Value.Slivers, 2)
 }
+
+func TestStatus_Services(t *testing.T) {
+	tests := []struct {
+		status corev1.PodStatus
+		state  string
+	}{
+		{
+			corev1.PodStatus{
+				InitContainerStatuses: []corev1.ContainerStatus{{
+					State: corev1.ContainerState{Running: &corev1.ContainerStateRunning{}},
+				}},
+			},
+			constants.GeniStateConfiguring,
+		},
+		{
+			corev1.PodStatus{
+				InitContainerStatuses: []corev1.ContainerStatus{{
+					State: corev1.ContainerState{
+						Terminated: &corev1.ContainerStateTerminated{ExitCode: 1},
+					},
+				}},
+			},
+			constants.GeniStateFailed,
+		},
+		{
+			corev1.PodStatus{
+				ContainerStatuses: []corev1.ContainerStatus{{
+					State: corev1.ContainerState{Running: &corev1.ContainerStateRunning{}},
+				}},
+			},
+			constants.GeniStateConfiguring,
+		},
+		{
+			corev1.PodStatus{
+				ContainerStatuses: []corev1.ContainerStatus{{
+					State: corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{}},
+					LastTerminationState: corev1.ContainerState{
+						Terminated: &corev1.ContainerStateTerminated{ExitCode: 127},
+					},
+				}},
+			},
+			constants.GeniStateFailed,
+		},
+	}
+	for _, test := range tests {
+		s := testService()
+		r := testRequest()
+		allocateTestSlice(s, r, testRspecServices)
+		sliver := listTestSlivers(s)[0]
+		pod := testPod(sliver.Name, "node", "1", "1Gi", true)
+		pod.Status = test.status
+		_, err := s.Pods(sliver.Namespace).Create(context.TODO(), pod, metav1.CreateOptions{})
+		assert.Nil(t, err)
+		_, state := s.GetSliverStatus(context.TODO(), sliver.Namespace, sliver.Name)
+		assert.Equal(t, test.state, state)
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"github.com/EdgeNet-project/fed4fire/pkg/rspec"
 	appsv1 "k8s.io/api/apps/v1"
 	"net/http"
+	"text/template"
 	"time"
 
 	"github.com/EdgeNet-project/fed4fire/pkg/constants"
@@ -21,9 +22,14 @@ import (
 	"github.com/EdgeNet-project/fed4fire/pkg/utils"
 
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	f4ftestclient "github.com/EdgeNet-project/fed4fire/pkg/generated/clientset/versioned/fake"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	dynamictestclient "k8s.io/client-go/dynamic/fake"
 	"k8s.io/client-go/kubernetes"
 	kubetestclient "k8s.io/client-go/kubernetes/fake"
 )
@@ -63,28 +69,199 @@ const testRspecMany = `<rspec type="request" generated="2013-01-16T14:20:39Z" xs
   </node>
   <node client_id="PC2" component_id="urn:publicid:IDN+example.org+node+node-1" component_manager_id="urn:publicid:IDN+example.org+authority+am" exclusive="false">
     <sliver_type name="container"/>
-    <hardware_type>
-      <name>amd64</name>
-	</hardware_type>
+    <hardware_type name="amd64"/>
   </node>
 </rspec>
 `
 
+const testRspecResources = `<rspec type="request" xmlns="http://www.geni.net/resources/rspec/3" xmlns:edgenet="http://www.edge-net.org/resources/rspec/ext/1">
+  <node client_id="PC1" component_manager_id="urn:publicid:IDN+example.org+authority+am" exclusive="false">
+    <sliver_type name="container"/>
+    <edgenet:resources cpu="3" memory="16Gi"/>
+  </node>
+</rspec>`
+
+const testRspecLinks = `<rspec type="request" xmlns="http://www.geni.net/resources/rspec/3">
+  <node client_id="PC1" component_manager_id="urn:publicid:IDN+example.org+authority+am" exclusive="false">
+    <sliver_type name="container"/>
+    <interface client_id="PC1:if0"/>
+  </node>
+  <node client_id="PC2" component_manager_id="urn:publicid:IDN+example.org+authority+am" exclusive="false">
+    <sliver_type name="container"/>
+    <interface client_id="PC2:if0">
+      <ip address="10.128.0.1" netmask="255.255.255.0" type="ipv4"/>
+    </interface>
+  </node>
+  <link client_id="link0">
+    <component_manager name="urn:publicid:IDN+example.org+authority+am"/>
+    <interface_ref client_id="PC1:if0"/>
+    <interface_ref client_id="PC2:if0"/>
+    <link_type name="lan"/>
+  </link>
+</rspec>`
+
+// testRspecLinkProperties must be formatted with the attributes of the link property.
+const testRspecLinkProperties = `<rspec type="request" xmlns="http://www.geni.net/resources/rspec/3">
+  <node client_id="PC1" component_manager_id="urn:publicid:IDN+example.org+authority+am" exclusive="false">
+    <sliver_type name="container"/>
+    <interface client_id="PC1:if0"/>
+  </node>
+  <node client_id="PC2" component_manager_id="urn:publicid:IDN+example.org+authority+am" exclusive="false">
+    <sliver_type name="container"/>
+    <interface client_id="PC2:if0"/>
+  </node>
+  <link client_id="link0">
+    <interface_ref client_id="PC1:if0"/>
+    <interface_ref client_id="PC2:if0"/>
+    <property source_id="PC1:if0" dest_id="PC2:if0" %s/>
+  </link>
+</rspec>`
+
+const testRspecServices = `<rspec type="request" xmlns="http://www.geni.net/resources/rspec/3">
+  <node client_id="PC1" component_manager_id="urn:publicid:IDN+example.org+authority+am" exclusive="false">
+    <sliver_type name="container"/>
+    <services>
+      <install url="https://example.org/tools.tar.gz" install_path="/local"/>
+      <install url="https://example.org/data.csv" install_path="/local"/>
+      <execute shell="bash" command="echo 'Hello' > /tmp/hello"/>
+    </services>
+  </node>
+</rspec>`
+
+const testRspecExtensions = `<rspec type="request" xmlns="http://www.geni.net/resources/rspec/3" xmlns:jfed="http://jfed.iminds.be/rspec/ext/jfed/1" xmlns:emulab="http://www.protogeni.net/resources/rspec/ext/emulab/1">
+  <node client_id="PC1" component_manager_id="urn:publicid:IDN+example.org+authority+am" exclusive="false">
+    <sliver_type name="container"/>
+    <emulab:routable_control_ip/>
+    <location xmlns="http://jfed.iminds.be/rspec/ext/jfed/1" x="107.5" y="91.5"/>
+  </node>
+</rspec>`
+
+const testRspecDiskImage = `<rspec type="request" xmlns="http://www.geni.net/resources/rspec/3">
+  <node client_id="PC1" component_manager_id="urn:publicid:IDN+example.org+authority+am" exclusive="false">
+    <sliver_type name="container">
+      <disk_image name="ubuntu2004"/>
+    </sliver_type>
+  </node>
+</rspec>`
+
+const testRspecPlacement = `<rspec type="request" xmlns="http://www.geni.net/resources/rspec/3" xmlns:edgenet="http://www.edge-net.org/resources/rspec/ext/1">
+  <node client_id="PC1" component_manager_id="urn:publicid:IDN+example.org+authority+am" exclusive="false">
+    <sliver_type name="container"/>
+    <edgenet:placement country="br" latitude="-23.5" longitude="-46.6" radius="100">
+      <edgenet:label key="example.org/gpu" value="true"/>
+    </edgenet:placement>
+  </node>
+</rspec>`
+
+const testRspecGroups = `<rspec type="request" xmlns="http://www.geni.net/resources/rspec/3" xmlns:edgenet="http://www.edge-net.org/resources/rspec/ext/1">
+  <node client_id="PC1" component_manager_id="urn:publicid:IDN+example.org+authority+am" exclusive="false">
+    <sliver_type name="container"/>
+  </node>
+  <node client_id="PC2" component_manager_id="urn:publicid:IDN+example.org+authority+am" exclusive="false">
+    <sliver_type name="container"/>
+  </node>
+  <node client_id="PC3" component_manager_id="urn:publicid:IDN+example.org+authority+am" exclusive="false">
+    <sliver_type name="container"/>
+  </node>
+  <edgenet:group name="servers" policy="spread" topology="country">
+    <edgenet:member client_id="PC1"/>
+    <edgenet:member client_id="PC2"/>
+  </edgenet:group>
+  <edgenet:group name="clients" policy="colocate">
+    <edgenet:member client_id="PC2"/>
+    <edgenet:member client_id="PC3"/>
+  </edgenet:group>
+</rspec>`
+
+const testRspecCount = `<rspec type="request" xmlns="http://www.geni.net/resources/rspec/3" xmlns:edgenet="http://www.edge-net.org/resources/rspec/ext/1">
+  <node client_id="server" component_manager_id="urn:publicid:IDN+example.org+authority+am" exclusive="false">
+    <sliver_type name="container"/>
+    <interface client_id="server:if0"/>
+  </node>
+  <node client_id="client" component_manager_id="urn:publicid:IDN+example.org+authority+am" exclusive="false" edgenet:count="3">
+    <sliver_type name="container"/>
+    <interface client_id="client:if0"/>
+  </node>
+  <link client_id="link0">
+    <interface_ref client_id="server:if0"/>
+    <interface_ref client_id="client:if0"/>
+  </link>
+  <edgenet:group name="clients" policy="balance">
+    <edgenet:member client_id="client"/>
+  </edgenet:group>
+</rspec>`
+
+const testRspecPorts = `<rspec type="request" xmlns="http://www.geni.net/resources/rspec/3" xmlns:edgenet="http://www.edge-net.org/resources/rspec/ext/1">
+  <node client_id="PC1" component_manager_id="urn:publicid:IDN+example.org+authority+am" exclusive="false">
+    <sliver_type name="container"/>
+    <edgenet:port number="8080"/>
+    <edgenet:port number="5353" protocol="udp"/>
+  </node>
+</rspec>`
+
+const testRspecVolumes = `<rspec type="request" xmlns="http://www.geni.net/resources/rspec/3" xmlns:edgenet="http://www.edge-net.org/resources/rspec/ext/1">
+  <node client_id="PC1" component_manager_id="urn:publicid:IDN+example.org+authority+am" exclusive="false">
+    <sliver_type name="container"/>
+    <edgenet:volume name="data" size="1024Mi" path="/data/"/>
+    <edgenet:volume name="shared" size="5Gi" path="/shared" shared="true"/>
+  </node>
+  <node client_id="PC2" component_manager_id="urn:publicid:IDN+example.org+authority+am" exclusive="false">
+    <sliver_type name="container"/>
+    <edgenet:volume name="shared" size="5Gi" path="/mnt/shared" shared="true"/>
+  </node>
+</rspec>`
+
+const testLinkCniConfig = `{"cniVersion": "0.3.1", "name": "{{.Name}}", "type": "vxlan", "vni": {{.VNI}}, "ipam": {"type": "static"}}`
+
 func testService() *Service {
+	var dynamicClient dynamic.Interface = dynamictestclient.NewSimpleDynamicClientWithCustomListKinds(
+		runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{
+			networkAttachmentDefinitionResource: "NetworkAttachmentDefinitionList",
+		},
+	)
 	var fed4fireClient versioned.Interface = f4ftestclient.NewSimpleClientset()
 	var kubernetesClient kubernetes.Interface = kubetestclient.NewSimpleClientset()
 	return &Service{
 		AuthorityIdentifier: testAuthorityIdentifier,
-		ContainerImages: map[string]string{
-			"ubuntu2004": "docker.io/library/ubuntu:20.04",
+		ContainerImages: []v1.ContainerImage{
+			{
+				ObjectMeta: metav1.ObjectMeta{Name: "ubuntu2004"},
+				Spec: v1.ContainerImageSpec{
+					Image:   "docker.io/library/ubuntu:20.04",
+					Default: true,
+				},
+			},
 		},
-		ContainerCpuLimit:    "2",
-		ContainerMemoryLimit: "2Gi",
-		NamespaceCpuLimit:    "8",
-		NamespaceMemoryLimit: "8Gi",
+		ClusterDomain:        "cluster.local",
+		ContainerCpuLimit:    resource.MustParse("2"),
+		InstallImage:         "docker.io/library/busybox:1.34",
+		ContainerMemoryLimit: resource.MustParse("2Gi"),
+		ExclusiveNodeLabel:   "example.org/exclusive",
+		IngressDomain:        "slivers.example.org",
+		MaxCpuRequest:        resource.MustParse("4"),
+		MaxLinkCapacity:      100000,
+		MaxLinkLatency:       1000,
+		MaxMemoryRequest:     resource.MustParse("4Gi"),
+		MaxNodeCount:         10,
+		MaxRspecSize:         65536,
+		MaxVolumeSize:        resource.MustParse("10Gi"),
+		Namespace:            "default",
+		NamespaceCpuLimit:    resource.MustParse("8"),
+		NamespaceMemoryLimit: resource.MustParse("8Gi"),
+		NetworkIsolation:     true,
+		PortServiceType:      "NodePort",
+		PlacementLabels:      []string{"example.org/gpu"},
+		SharedStorageClass:   "nfs",
+		SshdCommand:          "/usr/sbin/sshd -D -e",
+		LinkCniConfig:        template.Must(template.New("").Parse(testLinkCniConfig)),
+		LinkShapingImage:     "docker.io/nicolaka/netshoot:v0.4",
+		LinkSubnet:           "10.128.0.0/16",
+		DynamicClient:        dynamicClient,
 		Fed4FireClient:       fed4fireClient,
 		KubernetesClient:     kubernetesClient,
 		TrustedCertificates:  [][]byte{authorityCert},
+		StorageClass:         "local-path",
 	}
 }
 
@@ -108,6 +285,11 @@ func testNode(name string, ready bool) *corev1.Node {
 			},
 		},
 		Status: corev1.NodeStatus{
+			Allocatable: corev1.ResourceList{
+				corev1.ResourceCPU:              resource.MustParse("2"),
+				corev1.ResourceMemory:           resource.MustParse("4Gi"),
+				corev1.ResourceEphemeralStorage: resource.MustParse("10Gi"),
+			},
 			Conditions: []corev1.NodeCondition{
 				{
 					Type:   "Ready",
@@ -118,6 +300,46 @@ func testNode(name string, ready bool) *corev1.Node {
 	}
 }
 
+func testPod(name string, nodeName string, cpu string, memory string, sliver bool) *corev1.Pod {
+	labels := map[string]string{}
+	if sliver {
+		labels[constants.Fed4FireSliverName] = name
+	}
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   name,
+			Labels: labels,
+		},
+		Spec: corev1.PodSpec{
+			NodeName: nodeName,
+			Containers: []corev1.Container{{
+				Name: name,
+				Resources: corev1.ResourceRequirements{
+					Requests: corev1.ResourceList{
+						corev1.ResourceCPU:    resource.MustParse(cpu),
+						corev1.ResourceMemory: resource.MustParse(memory),
+					},
+				},
+			}},
+		},
+		Status: corev1.PodStatus{
+			Phase: corev1.PodRunning,
+		},
+	}
+}
+
+func testContainerImage(name string, image string, architectures []string, default_ bool) *v1.ContainerImage {
+	return &v1.ContainerImage{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec: v1.ContainerImageSpec{
+			Image:         image,
+			Architectures: architectures,
+			Description:   name,
+			Default:       default_,
+		},
+	}
+}
+
 func allocateTestSlice(service *Service, request *http.Request, rspec string) {
 	args := &AllocateArgs{
 		SliceURN:    testSliceIdentifier.URN(),
@@ -140,13 +362,13 @@ func provisionTestSlice(service *Service, request *http.Request) {
 }
 
 func listTestDeployments(service *Service) []appsv1.Deployment {
-	deployments, err := service.Deployments().List(context.TODO(), metav1.ListOptions{})
+	deployments, err := service.Deployments(metav1.NamespaceAll).List(context.TODO(), metav1.ListOptions{})
 	utils.Check(err)
 	return deployments.Items
 }
 
 func listTestSlivers(service *Service) []v1.Sliver {
-	slivers, err := service.Slivers().List(context.TODO(), metav1.ListOptions{})
+	slivers, err := service.Slivers(metav1.NamespaceAll).List(context.TODO(), metav1.ListOptions{})
 	utils.Check(err)
 	return slivers.Items
 }
@@ -155,13 +377,19 @@ func unmarshalTestRspec(s string) rspec.Rspec {
 	v := rspec.Rspec{}
 	err := xml.Unmarshal([]byte(s), &v)
 	if err != nil {
-		err := xml.Unmarshal(utils.DecompressZlibBase64(s), &v)
+		b, err := utils.DecompressZlibBase64(s)
+		utils.Check(err)
+		err = xml.Unmarshal(b, &v)
 		utils.Check(err)
 	}
 	return v
 }
 
 func createCredential(owner identifiers.Identifier, target identifiers.Identifier) Credential {
+	return createCredentialExpiring(owner, target, time.Now().Add(1*time.Hour))
+}
+
+func createCredentialExpiring(owner identifiers.Identifier, target identifiers.Identifier, expires time.Time) Credential {
 	ownerCert, _ := utils.CreateCertificate(
 		owner.URN(),
 		"",
@@ -192,7 +420,7 @@ func createCredential(owner identifiers.Identifier, target identifiers.Identifie
 		OwnerURN:  owner.URN(),
 		TargetGID: string(targetGid),
 		TargetURN: target.URN(),
-		Expires:   time.Now().Add(1 * time.Hour),
+		Expires:   expires,
 	}
 	unsignedCredential := sfa.SignedCredential{
 		Credential: credential,
@@ -0,0 +1,259 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	v1 "github.com/EdgeNet-project/fed4fire/pkg/apis/fed4fire/v1"
+	"github.com/EdgeNet-project/fed4fire/pkg/constants"
+	"github.com/EdgeNet-project/fed4fire/pkg/naming"
+	"github.com/EdgeNet-project/fed4fire/pkg/rspec"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"path"
+	"time"
+)
+
+// Maximum length of the name of a volume, which is appended to the name of the sliver in its claim.
+const maxVolumeNameLength = 32
+
+// sliverVolumesForRspec returns the persistent volumes of the nodes of a request RSpec, by client ID.
+// The shared volumes of the same name must have the same size and retention on all the nodes.
+func sliverVolumesForRspec(s Service, request rspec.Rspec) (map[string][]v1.SliverVolume, error) {
+	volumes := make(map[string][]v1.SliverVolume)
+	shared := make(map[string]v1.SliverVolume)
+	for _, node := range request.Nodes {
+		names := make(map[string]bool)
+		paths := make(map[string]bool)
+		for _, volume := range node.Volumes {
+			if len(validation.IsDNS1123Label(volume.Name)) > 0 || len(volume.Name) > maxVolumeNameLength {
+				return nil, fmt.Errorf(
+					"volume name %s must be a DNS label of at most %d characters",
+					volume.Name,
+					maxVolumeNameLength,
+				)
+			}
+			if names[volume.Name] {
+				return nil, fmt.Errorf("volume %s of node %s is duplicated", volume.Name, node.ClientID)
+			}
+			names[volume.Name] = true
+			size, err := resource.ParseQuantity(volume.Size)
+			if err != nil || size.Sign() <= 0 || size.Cmp(s.MaxVolumeSize) > 0 {
+				return nil, fmt.Errorf("size of volume %s must be between 0 and %s", volume.Name, s.MaxVolumeSize.String())
+			}
+			mountPath := path.Clean(volume.Path)
+			if !path.IsAbs(mountPath) || mountPath == "/" {
+				return nil, fmt.Errorf("path of volume %s must be an absolute path other than /", volume.Name)
+			}
+			if paths[mountPath] {
+				return nil, fmt.Errorf("several volumes of node %s are mounted at %s", node.ClientID, mountPath)
+			}
+			paths[mountPath] = true
+			sliverVolume := v1.SliverVolume{
+				Name:   volume.Name,
+				Size:   size.String(),
+				Path:   mountPath,
+				Shared: volume.Shared,
+				Retain: volume.Retain,
+			}
+			if volume.Shared {
+				other, ok := shared[volume.Name]
+				if ok && (other.Size != sliverVolume.Size || other.Retain != sliverVolume.Retain) {
+					return nil, fmt.Errorf(
+						"shared volume %s must have the same size and retention on all the nodes",
+						volume.Name,
+					)
+				}
+				shared[volume.Name] = sliverVolume
+			}
+			volumes[node.ClientID] = append(volumes[node.ClientID], sliverVolume)
+		}
+	}
+	return volumes, nil
+}
+
+// volumeClaimName returns the name of the persistent volume claim of a sliver volume.
+// The shared volumes are named after the slice, so that its slivers mount the same claim.
+func volumeClaimName(sliver v1.Sliver, volume v1.SliverVolume) string {
+	if volume.Shared {
+		return naming.VolumeName(sliver.Spec.SliceURN, volume.Name)
+	}
+	return sliver.Name + "-" + volume.Name
+}
+
+// volumeClaimsForSliver returns the persistent volume claims of the volumes of a sliver, and the pod volumes mounting them.
+// The retained claims are labelled, and annotated with the expiration of the slice.
+func volumeClaimsForSliver(
+	s Service,
+	sliver v1.Sliver,
+) ([]*corev1.PersistentVolumeClaim, []corev1.Volume, []corev1.VolumeMount, error) {
+	claims := make([]*corev1.PersistentVolumeClaim, 0)
+	volumes := make([]corev1.Volume, 0)
+	volumeMounts := make([]corev1.VolumeMount, 0)
+	for _, volume := range sliver.Spec.Volumes {
+		size, err := resource.ParseQuantity(volume.Size)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		accessMode := corev1.ReadWriteOnce
+		storageClass := s.StorageClass
+		if volume.Shared {
+			accessMode = corev1.ReadWriteMany
+			storageClass = s.SharedStorageClass
+		}
+		claim := &corev1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      volumeClaimName(sliver, volume),
+				Namespace: sliver.Namespace,
+				Labels: map[string]string{
+					constants.Fed4FireSliceHash: naming.SliceHash(sliver.Spec.SliceURN),
+				},
+			},
+			Spec: corev1.PersistentVolumeClaimSpec{
+				AccessModes:      []corev1.PersistentVolumeAccessMode{accessMode},
+				StorageClassName: &storageClass,
+				Resources: corev1.ResourceRequirements{
+					Requests: corev1.ResourceList{corev1.ResourceStorage: size},
+				},
+			},
+		}
+		if volume.Retain {
+			claim.Labels[constants.Fed4FireRetain] = "true"
+			extendVolumeClaim(claim, volumeClaimExpires(sliver))
+		}
+		claims = append(claims, claim)
+		name := "pvc-" + volume.Name
+		volumes = append(volumes, corev1.Volume{
+			Name: name,
+			VolumeSource: corev1.VolumeSource{
+				PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: claim.Name},
+			},
+		})
+		volumeMounts = append(volumeMounts, corev1.VolumeMount{Name: name, MountPath: volume.Path})
+	}
+	return claims, volumes, volumeMounts, nil
+}
+
+func containsVolumeMount(volumeMounts []corev1.VolumeMount, mountPath string) bool {
+	for _, volumeMount := range volumeMounts {
+		if volumeMount.MountPath == mountPath {
+			return true
+		}
+	}
+	return false
+}
+
+// createVolumeClaim creates the persistent volume claim of a sliver volume, or updates an existing one.
+// The claims which are not retained are owned by the slivers mounting them, and deleted with the last one.
+// The retained claims are not owned, and their expiration is extended to the one of the slice.
+func createVolumeClaim(
+	ctx context.Context,
+	s Service,
+	claim *corev1.PersistentVolumeClaim,
+	ownerReference metav1.OwnerReference,
+) error {
+	client := s.PersistentVolumeClaims(claim.Namespace)
+	retain := claim.Labels[constants.Fed4FireRetain] == "true"
+	if !retain {
+		claim.OwnerReferences = []metav1.OwnerReference{ownerReference}
+	}
+	_, err := client.Create(ctx, claim, metav1.CreateOptions{})
+	if err == nil || !errors.IsAlreadyExists(err) {
+		return err
+	}
+	existing, err := client.Get(ctx, claim.Name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	if retain {
+		expires, err := time.Parse(time.RFC3339, claim.Annotations[constants.Fed4FireExpires])
+		if err != nil || !extendVolumeClaim(existing, expires) {
+			return err
+		}
+	} else {
+		for _, reference := range existing.OwnerReferences {
+			if reference.Name == ownerReference.Name {
+				return nil
+			}
+		}
+		existing.OwnerReferences = append(existing.OwnerReferences, ownerReference)
+	}
+	_, err = client.Update(ctx, existing, metav1.UpdateOptions{})
+	return err
+}
+
+// renewVolumeClaims extends the expiration of the retained volumes of a sliver to the one of its slice.
+// The claims which do not exist yet, before the sliver is provisioned, are ignored.
+func renewVolumeClaims(ctx context.Context, s Service, sliver v1.Sliver) error {
+	for _, volume := range sliver.Spec.Volumes {
+		if !volume.Retain {
+			continue
+		}
+		client := s.PersistentVolumeClaims(sliver.Namespace)
+		claim, err := client.Get(ctx, volumeClaimName(sliver, volume), metav1.GetOptions{})
+		if errors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if extendVolumeClaim(claim, volumeClaimExpires(sliver)) {
+			_, err = client.Update(ctx, claim, metav1.UpdateOptions{})
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// volumeClaimExpires returns the expiration of the retained volumes of a sliver, the one of its slice.
+// The slivers allocated before it was stored fall back to their own expiration.
+func volumeClaimExpires(sliver v1.Sliver) time.Time {
+	if sliver.Spec.SliceExpires != nil {
+		return sliver.Spec.SliceExpires.Time
+	}
+	return sliver.Spec.Expires.Time
+}
+
+// extendVolumeClaim sets the expiration annotation of a retained claim, if it is later than the current one.
+// It returns true if the annotation changed.
+func extendVolumeClaim(claim *corev1.PersistentVolumeClaim, expires time.Time) bool {
+	current, err := time.Parse(time.RFC3339, claim.Annotations[constants.Fed4FireExpires])
+	if err == nil && !expires.After(current) {
+		return false
+	}
+	if claim.Annotations == nil {
+		claim.Annotations = make(map[string]string)
+	}
+	claim.Annotations[constants.Fed4FireExpires] = expires.UTC().Format(time.RFC3339)
+	return true
+}
+
+// hasRetainedVolumeClaims returns true if a namespace contains retained volumes, which must outlive its slivers.
+func hasRetainedVolumeClaims(ctx context.Context, s Service, namespace string) (bool, error) {
+	claims, err := s.PersistentVolumeClaims(namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: constants.Fed4FireRetain,
+	})
+	if err != nil {
+		return false, err
+	}
+	return len(claims.Items) > 0, nil
+}
+
+// rspecVolumesForSliver returns the EdgeNet volumes of a sliver, as reported in the manifests.
+func rspecVolumesForSliver(sliver v1.Sliver) []rspec.Volume {
+	volumes := make([]rspec.Volume, 0, len(sliver.Spec.Volumes))
+	for _, volume := range sliver.Spec.Volumes {
+		volumes = append(volumes, rspec.Volume{
+			Name:   volume.Name,
+			Size:   volume.Size,
+			Path:   volume.Path,
+			Shared: volume.Shared,
+			Retain: volume.Retain,
+		})
+	}
+	return volumes
+}
@@ -2,11 +2,20 @@ package utils
 
 import (
 	"bytes"
+	"compress/gzip"
 	"compress/zlib"
 	"encoding/base64"
+	"errors"
+	"io"
 	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
 )
 
+// ErrTooLarge is returned when decompressed data exceeds the given limit.
+var ErrTooLarge = errors.New("decompressed data is too large")
+
 func CompressZlibBase64(data []byte) string {
 	var b bytes.Buffer
 	w := zlib.NewWriter(&b)
@@ -21,22 +30,107 @@ func CompressZlibBase64(data []byte) string {
 	return base64.StdEncoding.EncodeToString(b.Bytes())
 }
 
-func DecompressZlibBase64(s string) []byte {
+func DecompressZlibBase64(s string) ([]byte, error) {
+	return DecompressZlibBase64Limit(s, -1)
+}
+
+// DecompressZlibBase64Limit is like DecompressZlibBase64, but returns ErrTooLarge
+// if the data is larger than limit bytes once decompressed. A negative limit means no limit.
+func DecompressZlibBase64Limit(s string, limit int64) ([]byte, error) {
 	c, err := base64.StdEncoding.DecodeString(s)
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
 	r, err := zlib.NewReader(bytes.NewReader(c))
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
-	b, err := ioutil.ReadAll(r)
-	if err != nil {
-		panic(err)
+	defer r.Close()
+	var reader io.Reader = r
+	if limit >= 0 {
+		reader = io.LimitReader(r, limit+1)
 	}
-	err = r.Close()
+	b, err := ioutil.ReadAll(reader)
 	if err != nil {
-		panic(err)
+		return nil, err
+	}
+	if limit >= 0 && int64(len(b)) > limit {
+		return nil, ErrTooLarge
+	}
+	return b, nil
+}
+
+// GzipHandler decompresses the gzip-encoded request bodies, and compresses the responses
+// of at least minSize bytes for the clients accepting gzip.
+// The request bodies are limited to maxBodySize bytes once decompressed.
+func GzipHandler(next http.Handler, maxBodySize int64, minSize int) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.EqualFold(r.Header.Get("Content-Encoding"), "gzip") {
+			body, err := gzip.NewReader(r.Body)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			defer body.Close()
+			r.Body = body
+			r.ContentLength = -1
+			r.Header.Del("Content-Encoding")
+			r.Header.Del("Content-Length")
+		}
+		r.Body = http.MaxBytesReader(w, r.Body, maxBodySize)
+		if !acceptsGzip(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		response := &bufferedResponseWriter{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(response, r)
+		w.Header().Add("Vary", "Accept-Encoding")
+		if response.body.Len() < minSize {
+			w.WriteHeader(response.status)
+			_, _ = w.Write(response.body.Bytes())
+			return
+		}
+		w.Header().Set("Content-Encoding", "gzip")
+		w.Header().Del("Content-Length")
+		w.WriteHeader(response.status)
+		gz := gzip.NewWriter(w)
+		_, _ = gz.Write(response.body.Bytes())
+		_ = gz.Close()
+	})
+}
+
+// acceptsGzip returns true if the Accept-Encoding header of a request allows gzip.
+func acceptsGzip(r *http.Request) bool {
+	for _, value := range r.Header.Values("Accept-Encoding") {
+		for _, encoding := range strings.Split(value, ",") {
+			parts := strings.Split(encoding, ";")
+			if strings.TrimSpace(parts[0]) != "gzip" {
+				continue
+			}
+			for _, parameter := range parts[1:] {
+				parameter = strings.TrimSpace(parameter)
+				if strings.HasPrefix(parameter, "q=") {
+					q, err := strconv.ParseFloat(parameter[2:], 64)
+					return err == nil && q > 0
+				}
+			}
+			return true
+		}
 	}
-	return b
+	return false
+}
+
+// bufferedResponseWriter holds a response until it is known whether it should be compressed.
+type bufferedResponseWriter struct {
+	http.ResponseWriter
+	body   bytes.Buffer
+	status int
+}
+
+func (w *bufferedResponseWriter) Write(b []byte) (int, error) {
+	return w.body.Write(b)
+}
+
+func (w *bufferedResponseWriter) WriteHeader(status int) {
+	w.status = status
 }
@@ -1,12 +1,97 @@
 package utils
 
 import (
+	"bytes"
+	"compress/gzip"
 	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 )
 
 func TestZlibBase64(t *testing.T) {
 	want := []byte("Hello World")
-	got := DecompressZlibBase64(CompressZlibBase64(want))
+	got, err := DecompressZlibBase64(CompressZlibBase64(want))
+	assert.Nil(t, err)
 	assert.Equal(t, want, got)
 }
+
+func TestZlibBase64_Invalid(t *testing.T) {
+	_, err := DecompressZlibBase64("not base64!")
+	assert.NotNil(t, err)
+	_, err = DecompressZlibBase64("SGVsbG8gV29ybGQ=")
+	assert.NotNil(t, err)
+	_, err = DecompressZlibBase64Limit(CompressZlibBase64([]byte("Hello World")), 5)
+	assert.Equal(t, ErrTooLarge, err)
+	got, err := DecompressZlibBase64Limit(CompressZlibBase64([]byte("Hello")), 5)
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("Hello"), got)
+}
+
+func TestGzipHandler(t *testing.T) {
+	echo := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			w.WriteHeader(http.StatusRequestEntityTooLarge)
+			return
+		}
+		w.Header().Set("Content-Type", "text/xml")
+		_, _ = w.Write(b)
+	})
+	handler := GzipHandler(echo, 1024, 100)
+	gzipped := func(s string) *bytes.Buffer {
+		var b bytes.Buffer
+		w := gzip.NewWriter(&b)
+		_, _ = w.Write([]byte(s))
+		_ = w.Close()
+		return &b
+	}
+	large := strings.Repeat("a", 200)
+
+	// Compressed request, uncompressed response
+	r := httptest.NewRequest(http.MethodPost, "/", gzipped("Hello World"))
+	r.Header.Set("Content-Encoding", "gzip")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "Hello World", w.Body.String())
+
+	// Compressed request larger than the limit once decompressed
+	r = httptest.NewRequest(http.MethodPost, "/", gzipped(strings.Repeat("a", 2048)))
+	r.Header.Set("Content-Encoding", "gzip")
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+
+	// Large response compressed for a client accepting gzip
+	r = httptest.NewRequest(http.MethodPost, "/", strings.NewReader(large))
+	r.Header.Set("Accept-Encoding", "deflate, gzip;q=0.5")
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	assert.Equal(t, "gzip", w.Header().Get("Content-Encoding"))
+	assert.Equal(t, "text/xml", w.Header().Get("Content-Type"))
+	body, err := gzip.NewReader(w.Body)
+	assert.Nil(t, err)
+	b, err := ioutil.ReadAll(body)
+	assert.Nil(t, err)
+	assert.Equal(t, large, string(b))
+
+	// Small response, and client refusing gzip
+	for _, test := range []struct {
+		body           string
+		acceptEncoding string
+	}{
+		{"Hello World", "gzip"},
+		{large, "gzip;q=0"},
+		{large, ""},
+	} {
+		r = httptest.NewRequest(http.MethodPost, "/", strings.NewReader(test.body))
+		r.Header.Set("Accept-Encoding", test.acceptEncoding)
+		w = httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		assert.Equal(t, "", w.Header().Get("Content-Encoding"))
+		assert.Equal(t, test.body, w.Body.String())
+	}
+}